@@ -0,0 +1,79 @@
+// Package stream provides transport-independent helpers shared by the sse
+// and websocket packages.
+//
+// Broadcaster lets applications fan out messages to Server-Sent Events and
+// WebSocket clients through one abstraction:
+//
+//	sseHub := sse.NewHub[string]()
+//	wsHub := websocket.NewHub()
+//
+//	all := stream.NewMultiBroadcaster(sseHub, wsHub)
+//	err := all.Publish("Server restarting in 5 minutes")
+package stream
+
+import "errors"
+
+// Broadcaster sends messages to all clients of a hub.
+//
+// It's implemented by *sse.Hub[T] and *websocket.Hub, and by
+// *MultiBroadcaster.
+//
+// Publish converts msg to the transport's wire format:
+//   - string: sent as-is
+//   - []byte: sent as-is by WebSocket; base64-encoded by SSE, which can't
+//     carry binary data (see sse.Event.WithBinary)
+//   - fmt.Stringer: String() method called
+//   - other types: JSON-encoded, except that SSE base64-encodes the
+//     MarshalBinary output of an encoding.BinaryMarshaler
+//
+// A typed sse.Hub[T] sends values of type T as Broadcast does.
+type Broadcaster interface {
+	// Publish sends msg to all connected clients.
+	Publish(msg any) error
+
+	// Clients returns the number of connected clients.
+	Clients() int
+}
+
+// MultiBroadcaster publishes each message to several Broadcasters.
+//
+// Use it to reach clients on mixed transports with a single call.
+//
+// Example:
+//
+//	all := stream.NewMultiBroadcaster(sseHub, wsHub)
+//	log.Printf("notifying %d clients", all.Clients())
+//	_ = all.Publish(Notification{Text: "Deploy finished"})
+type MultiBroadcaster struct {
+	broadcasters []Broadcaster
+}
+
+// NewMultiBroadcaster creates a MultiBroadcaster that publishes to each of
+// broadcasters, in order.
+func NewMultiBroadcaster(broadcasters ...Broadcaster) *MultiBroadcaster {
+	return &MultiBroadcaster{broadcasters: broadcasters}
+}
+
+// Publish sends msg through every Broadcaster.
+//
+// A failing Broadcaster doesn't stop delivery through the others. The
+// returned error joins all errors (see errors.Join), or is nil if every
+// Publish succeeded.
+func (m *MultiBroadcaster) Publish(msg any) error {
+	var errs []error
+	for _, b := range m.broadcasters {
+		if err := b.Publish(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Clients returns the total number of clients across all Broadcasters.
+func (m *MultiBroadcaster) Clients() int {
+	total := 0
+	for _, b := range m.broadcasters {
+		total += b.Clients()
+	}
+	return total
+}
@@ -0,0 +1,77 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coregx/stream/sse"
+	"github.com/coregx/stream/websocket"
+)
+
+// Both hubs must satisfy Broadcaster.
+var (
+	_ Broadcaster = (*sse.Hub[string])(nil)
+	_ Broadcaster = (*websocket.Hub)(nil)
+	_ Broadcaster = (*MultiBroadcaster)(nil)
+)
+
+// fakeBroadcaster records published messages.
+type fakeBroadcaster struct {
+	clients int
+	err     error
+	got     []any
+}
+
+func (f *fakeBroadcaster) Publish(msg any) error {
+	f.got = append(f.got, msg)
+	return f.err
+}
+
+func (f *fakeBroadcaster) Clients() int {
+	return f.clients
+}
+
+// TestMultiBroadcaster tests fan-out, client totals, and error joining.
+func TestMultiBroadcaster(t *testing.T) {
+	errFailed := errors.New("publish failed")
+	a := &fakeBroadcaster{clients: 2}
+	b := &fakeBroadcaster{clients: 3, err: errFailed}
+	c := &fakeBroadcaster{clients: 1}
+
+	m := NewMultiBroadcaster(a, b, c)
+
+	if got := m.Clients(); got != 6 {
+		t.Errorf("Clients() = %d, want 6", got)
+	}
+
+	err := m.Publish("hello")
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Publish() error = %v, want %v", err, errFailed)
+	}
+	for i, f := range []*fakeBroadcaster{a, b, c} {
+		if len(f.got) != 1 || f.got[0] != "hello" {
+			t.Errorf("broadcaster %d received %v, want [hello]", i, f.got)
+		}
+	}
+
+	if err := NewMultiBroadcaster(a, c).Publish("ok"); err != nil {
+		t.Errorf("Publish() error = %v, want nil", err)
+	}
+}
+
+// TestMultiBroadcaster_ClosedHubs tests that closed hubs of both transports
+// report ErrHubClosed.
+func TestMultiBroadcaster_ClosedHubs(t *testing.T) {
+	sseHub := sse.NewHub[string]()
+	wsHub := websocket.NewHub()
+	_ = sseHub.Close()
+	_ = wsHub.Close()
+
+	err := NewMultiBroadcaster(sseHub, wsHub).Publish("hello")
+	if !errors.Is(err, sse.ErrHubClosed) {
+		t.Errorf("Publish() error = %v, want sse.ErrHubClosed", err)
+	}
+	if !errors.Is(err, websocket.ErrHubClosed) {
+		t.Errorf("Publish() error = %v, want websocket.ErrHubClosed", err)
+	}
+}
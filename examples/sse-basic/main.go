@@ -20,6 +20,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/coregx/stream"
 	"github.com/coregx/stream/sse"
 )
 
@@ -44,16 +45,13 @@ func main() {
 	}()
 
 	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
 
 	// Graceful shutdown
 	slog.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
+	if err := stream.GracefulShutdown(ctx, server, 5*time.Second); err != nil {
 		slog.Error("Server shutdown error", "error", err)
 		os.Exit(1)
 	}
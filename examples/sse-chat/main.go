@@ -20,6 +20,7 @@ package main
 import (
 	"context"
 	"encoding/json/v2"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -29,6 +30,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/coregx/stream"
 	"github.com/coregx/stream/sse"
 )
 
@@ -39,13 +41,6 @@ type Message struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
-// String implements fmt.Stringer for Message.
-// This allows Hub[Message] to broadcast messages as strings.
-func (m Message) String() string {
-	data, _ := json.Marshal(m)
-	return string(data)
-}
-
 // ChatServer manages the chat room using SSE Hub.
 type ChatServer struct {
 	hub    *sse.Hub[Message]
@@ -87,17 +82,6 @@ func (cs *ChatServer) Start() error {
 	return cs.server.ListenAndServe()
 }
 
-// Shutdown gracefully shuts down the server.
-func (cs *ChatServer) Shutdown(ctx context.Context) error {
-	// Close hub first (disconnects clients)
-	if err := cs.hub.Close(); err != nil {
-		slog.Error("Failed to close hub", "error", err)
-	}
-
-	// Shutdown HTTP server
-	return cs.server.Shutdown(ctx)
-}
-
 // handleIndex serves a simple HTML page for browser testing.
 func (cs *ChatServer) handleIndex(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
@@ -219,7 +203,8 @@ func (cs *ChatServer) handleEvents(w http.ResponseWriter, r *http.Request) {
 	<-conn.Done()
 
 	clientCount = cs.hub.Clients()
-	slog.Info("Client disconnected", "remote", r.RemoteAddr, "remaining", clientCount)
+	slog.Info("Client disconnected", "remote", r.RemoteAddr,
+		"reason", conn.CloseReason(), "remaining", clientCount)
 }
 
 // handleMessages handles POST requests to send messages to all clients.
@@ -256,7 +241,12 @@ func (cs *ChatServer) handleMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Broadcast to all clients
-	if err := cs.hub.Broadcast(msg); err != nil {
+	if err := cs.hub.BroadcastJSON(msg); err != nil {
+		if errors.Is(err, sse.ErrHubBusy) {
+			// Hub is overloaded; let the client retry
+			http.Error(w, "Server busy, try again", http.StatusServiceUnavailable)
+			return
+		}
 		slog.Error("Failed to broadcast message", "error", err)
 		http.Error(w, "Failed to broadcast", http.StatusInternalServerError)
 		return
@@ -283,16 +273,13 @@ func main() {
 	}()
 
 	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
 
-	// Graceful shutdown
+	// Graceful shutdown: disconnect hub clients, then stop the server
 	slog.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
+	if err := stream.GracefulShutdown(ctx, server.server, 5*time.Second, server.hub); err != nil {
 		slog.Error("Server shutdown error", "error", err)
 		os.Exit(1)
 	}
@@ -35,7 +35,11 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	log.Printf("User %s connected from %s", username, r.RemoteAddr)
 
 	// Register client with hub
-	hub.Register(conn)
+	if err := hub.Register(conn); err != nil {
+		log.Printf("Rejecting %s: %v", username, err)
+		_ = conn.CloseWithCode(websocket.CloseTryAgainLater, "server full")
+		return
+	}
 	defer func() {
 		hub.Unregister(conn)
 		log.Printf("User %s disconnected", username)
@@ -57,7 +61,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		var msg Message
 		if err := conn.ReadJSON(&msg); err != nil {
 			if websocket.IsCloseError(err) {
-				// Clean disconnect - broadcast leave notification
+				// Disconnect (clean or dropped) - broadcast leave notification
 				leaveMsg := Message{
 					Type:      "leave",
 					Username:  username,
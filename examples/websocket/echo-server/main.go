@@ -20,26 +20,22 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Client connected from %s", r.RemoteAddr)
 
-	// Echo loop: read message and write it back
-	for {
-		// Read next message (text or binary)
-		msgType, data, err := conn.Read()
-		if err != nil {
-			if websocket.IsCloseError(err) {
-				log.Printf("Client disconnected: %v", err)
-			} else {
-				log.Printf("Read error: %v", err)
-			}
-			break
-		}
-
-		log.Printf("Received %s message: %s", msgType, string(data))
-
-		// Echo message back to client
-		if err := conn.Write(msgType, data); err != nil {
-			log.Printf("Write error: %v", err)
-			break
-		}
+	// Echo loop: dispatch each message and write it back
+	err = conn.Serve(websocket.MessageHandler{
+		OnText: func(text string) error {
+			log.Printf("Received text message: %s", text)
+			return conn.WriteText(text)
+		},
+		OnBinary: func(data []byte) error {
+			log.Printf("Received binary message: %d bytes", len(data))
+			return conn.Write(websocket.BinaryMessage, data)
+		},
+		OnClose: func(code websocket.CloseCode, reason string) {
+			log.Printf("Client disconnected: %d (%s) %s", code, code, reason)
+		},
+	})
+	if err != nil {
+		log.Printf("Connection error: %v", err)
 	}
 }
 
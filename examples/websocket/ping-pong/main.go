@@ -51,27 +51,21 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	// Main goroutine: Read messages (Pong handled automatically)
-	for {
-		msgType, data, err := conn.Read()
-		if err != nil {
-			if websocket.IsCloseError(err) {
-				log.Printf("Client disconnected cleanly: %v", err)
-			} else {
-				log.Printf("Read error: %v", err)
-			}
-			close(done)
-			break
-		}
-
+	for msgType, data := range conn.Messages() {
 		log.Printf("Received %s message: %s", msgType, string(data))
 
 		// Echo back
 		if err := conn.Write(msgType, data); err != nil {
 			log.Printf("Write error: %v", err)
-			close(done)
 			break
 		}
 	}
+	if err := conn.Err(); err != nil {
+		log.Printf("Read error: %v", err)
+	} else {
+		log.Printf("Client disconnected")
+	}
+	close(done)
 
 	log.Printf("Connection closed: %s", r.RemoteAddr)
 }
@@ -0,0 +1,218 @@
+// Package metrics collects connection and broadcast metrics from the sse
+// and websocket hubs.
+//
+// Hubs report events to a MetricsSink. Collector is a ready-made sink that
+// keeps counters and gauges per transport and exposes them in a form that
+// maps directly onto a Prometheus collector, without depending on the
+// Prometheus client library.
+//
+// Example:
+//
+//	collector := metrics.NewCollector()
+//
+//	sseHub := sse.NewHubWithOptions[string](&sse.HubOptions{Metrics: collector})
+//	wsHub := websocket.NewHubWithOptions(&websocket.HubOptions{Metrics: collector})
+//
+//	for _, m := range collector.Metrics() {
+//	    log.Printf("%s{transport=%q} %v", m.Name, m.Transport, m.Value)
+//	}
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Transport names reported by the hubs.
+const (
+	TransportSSE       = "sse"
+	TransportWebSocket = "websocket"
+)
+
+// MetricsSink receives events from a hub.
+//
+// Hubs call it from their event loops and writer goroutines, so
+// implementations must be safe for concurrent use and should return
+// quickly. transport identifies the reporting hub (TransportSSE or
+// TransportWebSocket).
+type MetricsSink interface {
+	// ClientConnected is called when a client joins a hub.
+	ClientConnected(transport string)
+
+	// ClientDisconnected is called when a client leaves a hub, including
+	// when the hub is closed.
+	ClientDisconnected(transport string)
+
+	// Broadcast is called once per message broadcast to clients.
+	Broadcast(transport string)
+
+	// BytesWritten is called after n bytes are written to a client.
+	BytesWritten(transport string, n int)
+
+	// MessageDropped is called when a message is dropped for a slow client.
+	MessageDropped(transport string)
+}
+
+// Discard is a MetricsSink that ignores all events.
+var Discard MetricsSink = discard{}
+
+type discard struct{}
+
+func (discard) ClientConnected(string)    {}
+func (discard) ClientDisconnected(string) {}
+func (discard) Broadcast(string)          {}
+func (discard) BytesWritten(string, int)  {}
+func (discard) MessageDropped(string)     {}
+
+// Type is the kind of a Metric.
+type Type int
+
+const (
+	// Counter is a value that only increases.
+	Counter Type = iota
+
+	// Gauge is a value that can go up and down.
+	Gauge
+)
+
+// Metric is a single value exported by a Collector.
+//
+// Name and Help map onto a Prometheus metric descriptor with a single
+// "transport" label; Type selects prometheus.CounterValue or
+// prometheus.GaugeValue.
+type Metric struct {
+	Name      string
+	Help      string
+	Type      Type
+	Transport string
+	Value     float64
+}
+
+// Snapshot holds a transport's metric values at a point in time.
+type Snapshot struct {
+	Connected    uint64 // Clients connected (total)
+	Disconnected uint64 // Clients disconnected (total)
+	Active       int64  // Clients currently connected
+	Broadcasts   uint64 // Messages broadcast (total)
+	BytesWritten uint64 // Bytes written to clients (total)
+	Dropped      uint64 // Messages dropped for slow clients (total)
+}
+
+// counters holds one transport's metrics.
+type counters struct {
+	connected    atomic.Uint64
+	disconnected atomic.Uint64
+	active       atomic.Int64
+	broadcasts   atomic.Uint64
+	bytesWritten atomic.Uint64
+	dropped      atomic.Uint64
+}
+
+// Collector is a MetricsSink that keeps counters per transport.
+//
+// A single Collector may be shared by several hubs. The zero value is not
+// usable; create one with NewCollector.
+type Collector struct {
+	mu         sync.RWMutex
+	transports map[string]*counters
+}
+
+// NewCollector creates an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{transports: make(map[string]*counters)}
+}
+
+// counters returns the counters for transport, creating them on first use.
+func (c *Collector) counters(transport string) *counters {
+	c.mu.RLock()
+	t, ok := c.transports[transport]
+	c.mu.RUnlock()
+	if ok {
+		return t
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok = c.transports[transport]; !ok {
+		t = &counters{}
+		c.transports[transport] = t
+	}
+	return t
+}
+
+// ClientConnected implements MetricsSink.
+func (c *Collector) ClientConnected(transport string) {
+	t := c.counters(transport)
+	t.connected.Add(1)
+	t.active.Add(1)
+}
+
+// ClientDisconnected implements MetricsSink.
+func (c *Collector) ClientDisconnected(transport string) {
+	t := c.counters(transport)
+	t.disconnected.Add(1)
+	t.active.Add(-1)
+}
+
+// Broadcast implements MetricsSink.
+func (c *Collector) Broadcast(transport string) {
+	c.counters(transport).broadcasts.Add(1)
+}
+
+// BytesWritten implements MetricsSink.
+func (c *Collector) BytesWritten(transport string, n int) {
+	c.counters(transport).bytesWritten.Add(uint64(n))
+}
+
+// MessageDropped implements MetricsSink.
+func (c *Collector) MessageDropped(transport string) {
+	c.counters(transport).dropped.Add(1)
+}
+
+// Snapshot returns the current values for transport.
+// Unknown transports return a zero Snapshot.
+func (c *Collector) Snapshot(transport string) Snapshot {
+	c.mu.RLock()
+	t, ok := c.transports[transport]
+	c.mu.RUnlock()
+	if !ok {
+		return Snapshot{}
+	}
+
+	return Snapshot{
+		Connected:    t.connected.Load(),
+		Disconnected: t.disconnected.Load(),
+		Active:       t.active.Load(),
+		Broadcasts:   t.broadcasts.Load(),
+		BytesWritten: t.bytesWritten.Load(),
+		Dropped:      t.dropped.Load(),
+	}
+}
+
+// Metrics returns all current values, one Metric per metric and transport.
+//
+// It's intended for adapting a Collector to a monitoring system. For
+// Prometheus, a prometheus.Collector's Collect method can forward each
+// Metric with prometheus.MustNewConstMetric.
+func (c *Collector) Metrics() []Metric {
+	c.mu.RLock()
+	transports := make([]string, 0, len(c.transports))
+	for transport := range c.transports {
+		transports = append(transports, transport)
+	}
+	c.mu.RUnlock()
+
+	metrics := make([]Metric, 0, len(transports)*6)
+	for _, transport := range transports {
+		s := c.Snapshot(transport)
+		metrics = append(metrics,
+			Metric{"stream_clients_connected_total", "Total clients connected.", Counter, transport, float64(s.Connected)},
+			Metric{"stream_clients_disconnected_total", "Total clients disconnected.", Counter, transport, float64(s.Disconnected)},
+			Metric{"stream_clients_active", "Clients currently connected.", Gauge, transport, float64(s.Active)},
+			Metric{"stream_broadcasts_total", "Total messages broadcast.", Counter, transport, float64(s.Broadcasts)},
+			Metric{"stream_bytes_written_total", "Total bytes written to clients.", Counter, transport, float64(s.BytesWritten)},
+			Metric{"stream_messages_dropped_total", "Total messages dropped for slow clients.", Counter, transport, float64(s.Dropped)},
+		)
+	}
+	return metrics
+}
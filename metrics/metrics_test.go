@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestCollector tests that events update per-transport counters.
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+
+	c.ClientConnected(TransportSSE)
+	c.ClientConnected(TransportSSE)
+	c.ClientDisconnected(TransportSSE)
+	c.Broadcast(TransportSSE)
+	c.BytesWritten(TransportSSE, 100)
+	c.BytesWritten(TransportSSE, 20)
+	c.MessageDropped(TransportSSE)
+	c.ClientConnected(TransportWebSocket)
+
+	want := Snapshot{Connected: 2, Disconnected: 1, Active: 1, Broadcasts: 1, BytesWritten: 120, Dropped: 1}
+	if got := c.Snapshot(TransportSSE); got != want {
+		t.Errorf("Snapshot(sse) = %+v, want %+v", got, want)
+	}
+	if got := c.Snapshot(TransportWebSocket); got.Active != 1 || got.Broadcasts != 0 {
+		t.Errorf("Snapshot(websocket) = %+v, want 1 active, 0 broadcasts", got)
+	}
+	if got := c.Snapshot("unknown"); got != (Snapshot{}) {
+		t.Errorf("Snapshot(unknown) = %+v, want zero", got)
+	}
+}
+
+// TestCollector_Metrics tests the exported metric list.
+func TestCollector_Metrics(t *testing.T) {
+	c := NewCollector()
+	c.ClientConnected(TransportSSE)
+	c.BytesWritten(TransportSSE, 42)
+
+	got := make(map[string]Metric)
+	for _, m := range c.Metrics() {
+		if m.Transport != TransportSSE {
+			t.Errorf("metric %s has transport %q, want %q", m.Name, m.Transport, TransportSSE)
+		}
+		got[m.Name] = m
+	}
+
+	tests := []struct {
+		name  string
+		typ   Type
+		value float64
+	}{
+		{"stream_clients_connected_total", Counter, 1},
+		{"stream_clients_disconnected_total", Counter, 0},
+		{"stream_clients_active", Gauge, 1},
+		{"stream_broadcasts_total", Counter, 0},
+		{"stream_bytes_written_total", Counter, 42},
+		{"stream_messages_dropped_total", Counter, 0},
+	}
+	if len(got) != len(tests) {
+		t.Errorf("Metrics() returned %d metrics, want %d", len(got), len(tests))
+	}
+	for _, tt := range tests {
+		m, ok := got[tt.name]
+		if !ok {
+			t.Errorf("missing metric %s", tt.name)
+			continue
+		}
+		if m.Type != tt.typ || m.Value != tt.value || m.Help == "" {
+			t.Errorf("%s = %+v, want type %d value %v with help", tt.name, m, tt.typ, tt.value)
+		}
+	}
+}
+
+// TestCollector_Concurrent tests concurrent updates from several hubs.
+func TestCollector_Concurrent(t *testing.T) {
+	c := NewCollector()
+
+	var wg sync.WaitGroup
+	for _, transport := range []string{TransportSSE, TransportWebSocket} {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					c.Broadcast(transport)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	for _, transport := range []string{TransportSSE, TransportWebSocket} {
+		if got := c.Snapshot(transport).Broadcasts; got != 1000 {
+			t.Errorf("Broadcasts(%s) = %d, want 1000", transport, got)
+		}
+	}
+}
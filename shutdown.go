@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Closer is a hub that disconnects all of its clients when closed.
+//
+// It's implemented by *sse.Hub[T] and *websocket.Hub.
+type Closer interface {
+	Close() error
+}
+
+// shutdowner is a Closer that can instead tell its clients the server is
+// going away, like websocket.Hub.Shutdown.
+type shutdowner interface {
+	Shutdown(reason string) error
+}
+
+// shutdownReason is the close reason sent to clients by GracefulShutdown.
+const shutdownReason = "server shutting down"
+
+// GracefulShutdown waits for ctx to be done, then closes hubs and shuts
+// server down.
+//
+// Streaming handlers only return once their connection closes, so
+// http.Server.Shutdown alone would wait on them until timeout. Closing the
+// hubs first notifies and disconnects their clients, letting the handlers
+// return; Shutdown then waits up to timeout for the remaining requests.
+// Hubs are closed in order, before Shutdown is called. WebSocket hubs are
+// closed with Shutdown, so their clients receive 1001 (Going Away); SSE
+// hubs end their clients' event streams.
+//
+// The returned error joins any hub Close errors and the Shutdown error
+// (see errors.Join), or is nil if all succeeded.
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//
+//	go server.ListenAndServe()
+//	if err := stream.GracefulShutdown(ctx, server, 5*time.Second, sseHub, wsHub); err != nil {
+//	    slog.Error("Shutdown error", "error", err)
+//	}
+func GracefulShutdown(ctx context.Context, server *http.Server, timeout time.Duration, hubs ...Closer) error {
+	<-ctx.Done()
+
+	var errs []error
+	for _, hub := range hubs {
+		closeHub := hub.Close
+		if s, ok := hub.(shutdowner); ok {
+			closeHub = func() error { return s.Shutdown(shutdownReason) }
+		}
+		if err := closeHub(); err != nil {
+			errs = append(errs, fmt.Errorf("stream: close hub: %w", err))
+		}
+	}
+
+	// ctx is already done; the grace period starts now
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("stream: shutdown server: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coregx/stream/sse"
+	"github.com/coregx/stream/websocket"
+)
+
+// Both hubs must satisfy Closer.
+var (
+	_ Closer = (*sse.Hub[string])(nil)
+	_ Closer = (*websocket.Hub)(nil)
+)
+
+// fakeHub records how it was closed. done is closed by Close.
+type fakeHub struct {
+	done   chan struct{}
+	once   sync.Once
+	reason string
+	err    error
+}
+
+func newFakeHub(err error) *fakeHub {
+	return &fakeHub{done: make(chan struct{}), err: err}
+}
+
+func (f *fakeHub) Close() error {
+	f.once.Do(func() { close(f.done) })
+	return f.err
+}
+
+// fakeShutdownHub is a fakeHub that also supports Shutdown.
+type fakeShutdownHub struct {
+	*fakeHub
+}
+
+func (f fakeShutdownHub) Shutdown(reason string) error {
+	f.reason = reason
+	return f.Close()
+}
+
+// TestGracefulShutdown tests that hubs are closed once the context is done
+// and before the server shuts down, so streaming handlers don't hold up
+// the shutdown.
+func TestGracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	errFailed := errors.New("close failed")
+	plain := newFakeHub(errFailed)
+	ws := fakeShutdownHub{newFakeHub(nil)}
+
+	// A streaming handler that runs until its hub disconnects it
+	started := make(chan struct{})
+	server := &http.Server{
+		ReadHeaderTimeout: time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_ = http.NewResponseController(w).Flush()
+			close(started)
+			<-ws.done
+		}),
+	}
+	go func() { _ = server.Serve(ln) }()
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- GracefulShutdown(ctx, server, 5*time.Second, plain, ws) }()
+
+	// Nothing happens until ctx is done
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-plain.done:
+		t.Fatal("hubs closed before the context was done")
+	default:
+	}
+
+	cancel()
+	select {
+	case err = <-errc:
+	case <-time.After(time.Second):
+		t.Fatal("GracefulShutdown() waited on the streaming handler; hubs not closed first")
+	}
+
+	if !errors.Is(err, errFailed) {
+		t.Errorf("GracefulShutdown() error = %v, want the hub's Close error", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GracefulShutdown() error = %v, want no shutdown timeout", err)
+	}
+	if ws.reason != shutdownReason {
+		t.Errorf("Shutdown reason = %q, want %q", ws.reason, shutdownReason)
+	}
+}
@@ -0,0 +1,599 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Common errors returned by Client.
+var (
+	// ErrClientConnected is returned by Connect when the client is already connected.
+	ErrClientConnected = errors.New("sse: client already connected")
+
+	// ErrInvalidResponse is returned by Connect when the server doesn't
+	// respond with a 200 OK text/event-stream.
+	ErrInvalidResponse = errors.New("sse: invalid response")
+)
+
+// Client defaults.
+const (
+	defaultEventType         = "message"
+	defaultClientEventBuffer = 64
+	defaultMinBackoff        = time.Second
+	defaultMaxBackoff        = 30 * time.Second
+)
+
+// ReconnectPolicy controls how a Client reconnects after the stream drops.
+//
+// All fields are optional. Zero values use sensible defaults.
+type ReconnectPolicy struct {
+	// MinBackoff is the delay before the first reconnect attempt when the
+	// server hasn't sent a retry field (default: 1s).
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts (default: 30s).
+	MaxBackoff time.Duration
+
+	// MaxAttempts is the number of consecutive failed attempts before the
+	// client gives up (default: 0, unlimited). The count resets after each
+	// successful reconnect.
+	MaxAttempts int
+}
+
+// Client reads a text/event-stream from a server.
+//
+// Client is the receiving side of the protocol: it parses the data, event,
+// id, and retry fields into Event values, joins multi-line data, and skips
+// comments (including keep-alives). Events without an event field have
+// Type "message", as in the browser EventSource API.
+//
+// Example:
+//
+//	client := sse.NewClient("https://example.com/events")
+//	if err := client.Connect(ctx); err != nil {
+//	    return err
+//	}
+//	defer client.Close()
+//
+//	for event := range client.Events() {
+//	    fmt.Printf("%s: %s\n", event.Type, event.Data)
+//	}
+//	if err := client.Err(); err != nil {
+//	    log.Printf("stream ended: %v", err)
+//	}
+type Client struct {
+	url        string
+	httpClient *http.Client
+	header     http.Header
+	bufferSize int
+
+	// reconnect is nil if reconnection is disabled.
+	reconnect *ReconnectPolicy
+
+	events chan Event
+	done   chan struct{}
+
+	mu        sync.Mutex
+	connected bool
+	cancel    context.CancelFunc
+	err       error
+
+	// lastEventID is the ID of the last event received.
+	lastEventID string
+
+	// handlers maps event types to callbacks registered with On.
+	handlers map[string][]func(Event)
+
+	// parser state persists across reconnects (last event ID and retry).
+	// Only used by the reader goroutine.
+	parser parser
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http.Client used to connect.
+//
+// The default client has no timeout, since event streams are long-lived.
+// Use the Connect context to bound the connection instead.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		if hc != nil {
+			c.httpClient = hc
+		}
+	}
+}
+
+// WithHeader adds a request header sent when connecting
+// (e.g. Authorization).
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithEventBuffer sets the capacity of the Events channel (default: 64).
+func WithEventBuffer(n int) ClientOption {
+	return func(c *Client) {
+		if n >= 0 {
+			c.bufferSize = n
+		}
+	}
+}
+
+// WithReconnect enables automatic reconnection.
+//
+// When the stream ends or fails, the client waits and reconnects, sending
+// the last seen event ID in the Last-Event-ID header so the server can
+// resume. The delay is the server's retry field if it sent one, otherwise
+// policy.MinBackoff, doubling after each failed attempt up to
+// policy.MaxBackoff. A nil policy uses the defaults.
+//
+// Events keeps delivering across reconnects. The stream ends for good when
+// the context is canceled, Close is called, MaxAttempts is exceeded, or the
+// server responds with a non-200 status or wrong Content-Type (per the
+// specification, e.g. 204 No Content tells clients to stop).
+//
+// Example:
+//
+//	client := sse.NewClient(url, sse.WithReconnect(&sse.ReconnectPolicy{
+//	    MaxBackoff:  time.Minute,
+//	    MaxAttempts: 10,
+//	}))
+func WithReconnect(policy *ReconnectPolicy) ClientOption {
+	return func(c *Client) {
+		p := ReconnectPolicy{}
+		if policy != nil {
+			p = *policy
+		}
+		if p.MinBackoff <= 0 {
+			p.MinBackoff = defaultMinBackoff
+		}
+		if p.MaxBackoff < p.MinBackoff {
+			p.MaxBackoff = max(defaultMaxBackoff, p.MinBackoff)
+		}
+		c.reconnect = &p
+	}
+}
+
+// NewClient creates a Client for the event stream at url.
+//
+// The client doesn't connect until Connect is called.
+//
+// Example:
+//
+//	client := sse.NewClient(url,
+//	    sse.WithHeader("Authorization", "Bearer "+token),
+//	)
+func NewClient(url string, opts ...ClientOption) *Client {
+	c := &Client{
+		url:        url,
+		httpClient: &http.Client{},
+		header:     make(http.Header),
+		bufferSize: defaultClientEventBuffer,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.events = make(chan Event, c.bufferSize)
+	c.done = make(chan struct{})
+	return c
+}
+
+// Connect opens the event stream and starts reading events in the background.
+//
+// Connect returns once the server has responded. Events are delivered on
+// the Events channel until the stream ends (see WithReconnect), ctx is
+// canceled, or Close is called. A Client can only be connected once.
+//
+// Returns ErrInvalidResponse if the server doesn't respond with 200 OK and
+// a text/event-stream Content-Type, or ErrClientConnected if Connect was
+// already called.
+func (c *Client) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	if c.connected {
+		c.mu.Unlock()
+		return ErrClientConnected
+	}
+	c.connected = true
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.mu.Unlock()
+
+	body, err := c.open(ctx)
+	if err != nil {
+		c.finish(err)
+		return err
+	}
+
+	go func() {
+		c.finish(c.run(ctx, body))
+	}()
+	return nil
+}
+
+// run reads the stream, reconnecting when it drops if enabled.
+func (c *Client) run(ctx context.Context, body io.ReadCloser) error {
+	for {
+		err := c.read(ctx, body)
+		if c.reconnect == nil || ctx.Err() != nil {
+			return err
+		}
+
+		body, err = c.reopen(ctx, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// reopen reconnects with backoff. cause is the error that ended the
+// previous stream (nil for a clean end). Returns nil, nil if ctx is
+// canceled while waiting.
+func (c *Client) reopen(ctx context.Context, cause error) (io.ReadCloser, error) {
+	for attempt := 1; ; attempt++ {
+		if c.reconnect.MaxAttempts > 0 && attempt > c.reconnect.MaxAttempts {
+			return nil, fmt.Errorf("sse: gave up after %d reconnect attempts: %w", c.reconnect.MaxAttempts, cause)
+		}
+
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil
+		}
+
+		body, err := c.open(ctx)
+		switch {
+		case err == nil:
+			return body, nil
+		case ctx.Err() != nil:
+			return nil, nil
+		case errors.Is(err, ErrInvalidResponse):
+			// The server doesn't want us back
+			return nil, err
+		}
+		cause = err
+	}
+}
+
+// backoff returns the delay before the given reconnect attempt (from 1).
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.reconnect.MinBackoff
+	if c.parser.reconnectMs > 0 {
+		delay = time.Duration(c.parser.reconnectMs) * time.Millisecond
+	}
+
+	for i := 1; i < attempt && delay < c.reconnect.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, c.reconnect.MaxBackoff)
+}
+
+// open sends the request and validates the response.
+func (c *Client) open(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("sse: failed to create request: %w", err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if id := c.LastEventID(); id != "" {
+		req.Header.Set("Last-Event-ID", id)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sse: failed to connect: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: status %d", ErrInvalidResponse, resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: content type %q", ErrInvalidResponse, resp.Header.Get("Content-Type"))
+	}
+
+	return resp.Body, nil
+}
+
+// read parses events from body until it ends or ctx is canceled.
+// Returns nil on a clean end of stream.
+func (c *Client) read(ctx context.Context, body io.ReadCloser) error {
+	defer body.Close()
+
+	// Unblock the scanner when the context is canceled
+	stop := context.AfterFunc(ctx, func() { _ = body.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	scanner.Split(scanLines)
+
+	// A new connection starts a new event, but keeps the last event ID
+	c.parser.reset()
+	for scanner.Scan() {
+		event, ok := c.parser.parseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		c.mu.Lock()
+		c.lastEventID = event.ID
+		handlers := c.handlers[event.Type]
+		c.mu.Unlock()
+
+		if len(handlers) > 0 {
+			for _, fn := range handlers {
+				fn(event)
+			}
+			continue
+		}
+
+		select {
+		case c.events <- event:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if ctx.Err() != nil {
+		// Closed or canceled by the caller
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("sse: failed to read stream: %w", err)
+	}
+	return nil
+}
+
+// finish records the error that ended the stream and closes Events.
+func (c *Client) finish(err error) {
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+
+	close(c.events)
+	close(c.done)
+}
+
+// Events returns the channel of received events.
+//
+// The channel is closed when the stream ends; check Err to tell a clean
+// end of stream from a failure.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Stream returns an iterator over received events, for use with range.
+//
+// The iteration connects the client if Connect hasn't been called, then
+// yields events as Events delivers them until the stream ends; Err then
+// reports why. If connecting fails, it yields nothing and Err returns the
+// error. Breaking out of the loop closes the client.
+//
+// Example:
+//
+//	client := sse.NewClient(url, sse.WithReconnect(nil))
+//	for event := range client.Stream(ctx) {
+//	    fmt.Printf("%s: %s\n", event.Type, event.Data)
+//	}
+//	if err := client.Err(); err != nil {
+//	    log.Printf("stream ended: %v", err)
+//	}
+func (c *Client) Stream(ctx context.Context) iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		if err := c.Connect(ctx); err != nil && !errors.Is(err, ErrClientConnected) {
+			return // Recorded for Err
+		}
+
+		for event := range c.events {
+			if !yield(event) {
+				_ = c.Close()
+				return
+			}
+		}
+	}
+}
+
+// On registers fn to be called for every event of the given type.
+//
+// Use "message" for events sent without an event field. Handlers run on
+// the reader goroutine in the order they were registered, so a slow
+// handler delays the events after it. Events with at least one handler
+// are not delivered on the Events channel.
+//
+// On may be called before or after Connect.
+//
+// Thread-safe: Can be called concurrently.
+//
+// Example:
+//
+//	client.On("time", func(e sse.Event) {
+//	    fmt.Println("server time:", e.Data)
+//	})
+func (c *Client) On(eventType string, fn func(Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.handlers == nil {
+		c.handlers = make(map[string][]func(Event))
+	}
+	// Copy so the reader can call a snapshot without holding mu
+	handlers := make([]func(Event), 0, len(c.handlers[eventType])+1)
+	handlers = append(handlers, c.handlers[eventType]...)
+	c.handlers[eventType] = append(handlers, fn)
+}
+
+// Err returns the error that ended the stream, or nil if the stream ended
+// cleanly, was closed, or is still running.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// LastEventID returns the ID of the last event received.
+func (c *Client) LastEventID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastEventID
+}
+
+// Close disconnects from the server and waits for the reader to stop.
+//
+// It's safe to call Close multiple times, and before Connect.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-c.done
+	return nil
+}
+
+// parser accumulates text/event-stream lines into events.
+type parser struct {
+	eventType   string
+	data        strings.Builder
+	hasData     bool
+	retry       int
+	lastEventID string
+
+	// reconnectMs is the latest retry value, kept across events (0 if none).
+	reconnectMs int
+}
+
+// parseLine processes a single line. It returns the completed event and
+// true when line is the blank line that dispatches an event with data.
+func (p *parser) parseLine(line string) (Event, bool) {
+	if line == "" {
+		return p.dispatch()
+	}
+
+	// Comment (keep-alive)
+	if strings.HasPrefix(line, ":") {
+		return Event{}, false
+	}
+
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+
+	switch field {
+	case "data":
+		if p.hasData {
+			p.data.WriteByte('\n')
+		}
+		p.data.WriteString(value)
+		p.hasData = true
+	case "event":
+		p.eventType = value
+	case "id":
+		// IDs containing NUL are ignored per the specification
+		if !strings.ContainsRune(value, 0) {
+			p.lastEventID = value
+		}
+	case "retry":
+		if isDigits(value) {
+			if ms, err := strconv.Atoi(value); err == nil {
+				p.retry = ms
+				p.reconnectMs = ms
+			}
+		}
+	}
+	// Unknown fields are ignored
+
+	return Event{}, false
+}
+
+// dispatch completes the current event. Events without data aren't
+// dispatched, but still reset the type and retry. The type defaults to
+// "message" per the specification.
+func (p *parser) dispatch() (Event, bool) {
+	eventType := p.eventType
+	if eventType == "" {
+		eventType = defaultEventType
+	}
+	event := Event{
+		Type:  eventType,
+		ID:    p.lastEventID,
+		Data:  p.data.String(),
+		Retry: p.retry,
+	}
+	ok := p.hasData
+
+	p.reset()
+	return event, ok
+}
+
+// reset discards the event in progress.
+func (p *parser) reset() {
+	p.eventType = ""
+	p.data.Reset()
+	p.hasData = false
+	p.retry = 0
+}
+
+// isDigits reports whether s consists only of ASCII digits.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// scanLines is a bufio.SplitFunc for text/event-stream lines, which may end
+// in "\r\n", "\n", or a lone "\r".
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// "\r": need the next byte to tell "\r\n" from a lone "\r"
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+
+	if atEOF {
+		// Incomplete final line: discarded, like an unterminated event
+		return len(data), nil, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,475 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestClient_ReceivesEvents tests parsing events from a real Upgrade server.
+func TestClient_ReceivesEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_ = conn.SendComment("keep-alive")
+		_ = conn.Send(NewEvent("hello").WithType("greeting").WithID("1"))
+		_ = conn.Send(NewEvent("line1\nline2").WithID("2").WithRetry(5000))
+		_ = conn.SendData("no id")
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	want := []Event{
+		{Type: "greeting", ID: "1", Data: "hello"},
+		{Type: "message", ID: "2", Data: "line1\nline2", Retry: 5000},
+		{Type: "message", ID: "2", Data: "no id"}, // ID persists until the server changes it
+	}
+
+	var got []Event
+	for event := range client.Events() {
+		got = append(got, event)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("received %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if err := client.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil after clean end of stream", err)
+	}
+	if got := client.LastEventID(); got != "2" {
+		t.Errorf("LastEventID() = %q, want %q", got, "2")
+	}
+}
+
+// TestClient_InvalidResponse tests that non-SSE responses are rejected.
+func TestClient_InvalidResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "nope", http.StatusUnauthorized)
+			},
+		},
+		{
+			name: "content type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte("{}"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			err := client.Connect(context.Background())
+			if !errors.Is(err, ErrInvalidResponse) {
+				t.Fatalf("Connect() error = %v, want ErrInvalidResponse", err)
+			}
+			if _, ok := <-client.Events(); ok {
+				t.Error("Events channel should be closed after failed Connect")
+			}
+		})
+	}
+}
+
+// TestClient_Close tests that Close stops an open stream.
+func TestClient_Close(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		<-conn.Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithHeader("X-Test", "1"))
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := client.Connect(context.Background()); !errors.Is(err, ErrClientConnected) {
+		t.Errorf("second Connect() error = %v, want ErrClientConnected", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = client.Close()
+		_ = client.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close() did not return")
+	}
+	if err := client.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil after Close", err)
+	}
+}
+
+// TestClient_Stream tests ranging over events from a live server, and that
+// breaking out of the loop closes the client.
+func TestClient_Stream(t *testing.T) {
+	disconnected := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer close(disconnected)
+
+		for i := 1; ; i++ {
+			if err := conn.Send(NewEvent("tick").WithID(strconv.Itoa(i))); err != nil {
+				return
+			}
+			select {
+			case <-conn.Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var ids []string
+	for event := range client.Stream(context.Background()) {
+		ids = append(ids, event.ID)
+		if len(ids) == 3 {
+			break
+		}
+	}
+
+	if !slices.Equal(ids, []string{"1", "2", "3"}) {
+		t.Errorf("event IDs = %q, want [1 2 3]", ids)
+	}
+	if err := client.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil after break", err)
+	}
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server still streaming after break")
+	}
+}
+
+// TestClient_StreamError tests that a failed connect ends the iteration
+// with the error recorded for Err.
+func TestClient_StreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	for event := range client.Stream(context.Background()) {
+		t.Errorf("received %+v, want no events", event)
+	}
+	if err := client.Err(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("Err() = %v, want ErrInvalidResponse", err)
+	}
+}
+
+// TestClient_On tests dispatching events to handlers by type.
+func TestClient_On(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_ = conn.Send(NewEvent("12:00").WithType("time"))
+		_ = conn.SendData("hello")
+		_ = conn.Send(NewEvent("12:01").WithType("time"))
+		_ = conn.Send(NewEvent("ignored").WithType("other"))
+		_ = conn.SendData("world")
+	}))
+	defer server.Close()
+
+	var times, messages []string
+	client := NewClient(server.URL)
+	client.On("time", func(e Event) {
+		if e.Type != "time" {
+			t.Errorf("time handler got type %q", e.Type)
+		}
+		times = append(times, e.Data)
+	})
+	client.On("message", func(e Event) {
+		if e.Type != "message" {
+			t.Errorf("message handler got type %q", e.Type)
+		}
+		messages = append(messages, e.Data)
+	})
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	// Events without a handler still go to the channel
+	var unhandled []Event
+	for event := range client.Events() {
+		unhandled = append(unhandled, event)
+	}
+
+	if got := strings.Join(times, ","); got != "12:00,12:01" {
+		t.Errorf("time handler got %q, want %q", got, "12:00,12:01")
+	}
+	if got := strings.Join(messages, ","); got != "hello,world" {
+		t.Errorf("message handler got %q, want %q", got, "hello,world")
+	}
+	if len(unhandled) != 1 || unhandled[0].Type != "other" {
+		t.Errorf("Events() delivered %+v, want only the %q event", unhandled, "other")
+	}
+}
+
+// TestClient_Reconnect tests resuming with Last-Event-ID after the server
+// closes the stream.
+func TestClient_Reconnect(t *testing.T) {
+	var requests atomic.Int32
+	lastEventIDs := make(chan string, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		lastEventIDs <- r.Header.Get("Last-Event-ID")
+
+		if requests.Add(1) == 1 {
+			// Drop the stream mid-way
+			_ = conn.Send(NewEvent("one").WithID("1").WithRetry(10))
+			_ = conn.Send(NewEvent("two").WithID("2"))
+			return
+		}
+		_ = conn.Send(NewEvent("three").WithID("3"))
+		<-conn.Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithReconnect(nil))
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	for _, want := range []string{"one", "two", "three"} {
+		select {
+		case event := <-client.Events():
+			if event.Data != want {
+				t.Errorf("event data = %q, want %q", event.Data, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %q", want)
+		}
+	}
+
+	if got := <-lastEventIDs; got != "" {
+		t.Errorf("first request Last-Event-ID = %q, want empty", got)
+	}
+	if got := <-lastEventIDs; got != "2" {
+		t.Errorf("reconnect Last-Event-ID = %q, want %q", got, "2")
+	}
+}
+
+// TestClient_Reconnect_MaxAttempts tests giving up when the server is gone.
+func TestClient_Reconnect_MaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = Upgrade(w, r)
+	}))
+
+	client := NewClient(server.URL, WithReconnect(&ReconnectPolicy{
+		MinBackoff:  5 * time.Millisecond,
+		MaxBackoff:  20 * time.Millisecond,
+		MaxAttempts: 3,
+	}))
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	server.Close()
+
+	select {
+	case <-client.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not give up")
+	}
+	if err := client.Err(); err == nil || !strings.Contains(err.Error(), "3 reconnect attempts") {
+		t.Errorf("Err() = %v, want give-up error", err)
+	}
+}
+
+// TestClient_Reconnect_StopStatus tests that a non-200 reconnect response
+// ends the stream.
+func TestClient_Reconnect_StopStatus(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = Upgrade(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithReconnect(&ReconnectPolicy{MinBackoff: 5 * time.Millisecond}))
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	select {
+	case <-client.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client kept reconnecting after 204")
+	}
+	if err := client.Err(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("Err() = %v, want ErrInvalidResponse", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+// TestClient_Backoff tests reconnect delay calculation.
+func TestClient_Backoff(t *testing.T) {
+	client := NewClient("http://example.com", WithReconnect(&ReconnectPolicy{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+	}))
+
+	tests := []struct {
+		attempt int
+		retryMs int
+		want    time.Duration
+	}{
+		{1, 0, 100 * time.Millisecond},
+		{2, 0, 200 * time.Millisecond},
+		{4, 0, 800 * time.Millisecond},
+		{5, 0, time.Second},
+		{1, 50, 50 * time.Millisecond},
+		{3, 50, 200 * time.Millisecond},
+		{10, 5000, time.Second},
+	}
+
+	for _, tt := range tests {
+		client.parser.reconnectMs = tt.retryMs
+		if got := client.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) with retry %dms = %v, want %v", tt.attempt, tt.retryMs, got, tt.want)
+		}
+	}
+}
+
+// TestParser tests text/event-stream parsing edge cases.
+func TestParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []Event
+	}{
+		{
+			name:   "event type",
+			stream: "event: time\ndata: x\n\ndata: y\n\n",
+			want:   []Event{{Type: "time", Data: "x"}, {Type: "message", Data: "y"}},
+		},
+		{
+			name:   "no space after colon",
+			stream: "data:hello\n\n",
+			want:   []Event{{Type: "message", Data: "hello"}},
+		},
+		{
+			name:   "only first space stripped",
+			stream: "data:  two\n\n",
+			want:   []Event{{Type: "message", Data: " two"}},
+		},
+		{
+			name:   "empty data line",
+			stream: "data\ndata\n\n",
+			want:   []Event{{Type: "message", Data: "\n"}},
+		},
+		{
+			name:   "CRLF and CR line endings",
+			stream: "data: a\r\ndata: b\r\r\ndata: c\r\r",
+			want:   []Event{{Type: "message", Data: "a\nb"}, {Type: "message", Data: "c"}},
+		},
+		{
+			name:   "event without data is not dispatched",
+			stream: "event: ping\n\ndata: x\n\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+		{
+			name:   "comments and unknown fields ignored",
+			stream: ": comment\nfoo: bar\ndata: x\n\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+		{
+			name:   "invalid retry ignored",
+			stream: "retry: 1s\ndata: x\n\nretry: -5\ndata: y\n\n",
+			want:   []Event{{Type: "message", Data: "x"}, {Type: "message", Data: "y"}},
+		},
+		{
+			name:   "empty id resets last event ID",
+			stream: "id: 1\ndata: x\n\nid\ndata: y\n\n",
+			want:   []Event{{Type: "message", ID: "1", Data: "x"}, {Type: "message", Data: "y"}},
+		},
+		{
+			name:   "unterminated event discarded",
+			stream: "data: x\n\ndata: partial\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(tt.stream))
+			scanner.Split(scanLines)
+
+			var p parser
+			var got []Event
+			for scanner.Scan() {
+				if event, ok := p.parseLine(scanner.Text()); ok {
+					got = append(got, event)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1,13 +1,23 @@
 package sse
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json/v2"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Common errors returned by Conn.
@@ -18,8 +28,50 @@ var (
 	// ErrNoFlusher is returned when http.ResponseWriter doesn't support flushing.
 	// This usually indicates an incompatible HTTP server or proxy.
 	ErrNoFlusher = errors.New("sse: ResponseWriter does not support flushing")
+
+	// ErrWriteTimeout is returned when a write doesn't complete within the
+	// connection's write timeout. The connection is closed, and the error
+	// also wraps ErrConnectionClosed.
+	ErrWriteTimeout = errors.New("sse: write timeout")
 )
 
+// CloseReason reports why a Conn was closed.
+type CloseReason int32
+
+const (
+	// NotClosed means the connection is still open.
+	NotClosed CloseReason = iota
+
+	// ClientDisconnected means the client went away: the request's
+	// context was canceled (the client closed the connection), or a write
+	// to the client timed out.
+	ClientDisconnected
+
+	// ContextCanceled means the context passed to UpgradeWithContext was
+	// canceled or timed out while the client was still connected.
+	ContextCanceled
+
+	// ServerClosed means the server closed the connection with Close,
+	// directly or through a Hub.
+	ServerClosed
+)
+
+// String returns the reason in lowercase words, e.g. "client disconnected".
+func (r CloseReason) String() string {
+	switch r {
+	case NotClosed:
+		return "not closed"
+	case ClientDisconnected:
+		return "client disconnected"
+	case ContextCanceled:
+		return "context canceled"
+	case ServerClosed:
+		return "server closed"
+	default:
+		return "unknown"
+	}
+}
+
 // Conn represents an active SSE connection to a client.
 //
 // Conn manages the lifecycle of a Server-Sent Events connection, handling
@@ -40,13 +92,70 @@ var (
 //	    conn.SendJSON(map[string]string{"status": "connected"})
 //	}
 type Conn struct {
-	w       http.ResponseWriter
-	flusher http.Flusher
-	ctx     context.Context
-	cancel  context.CancelFunc
-	done    chan struct{}
-	closed  bool
-	mu      sync.Mutex
+	w      http.ResponseWriter
+	bw     *bufio.Writer // Buffers writes to w (nil if unbuffered)
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+
+	// closed is set by Close. It's atomic so Close never waits on mu,
+	// which a stuck write may hold indefinitely.
+	closed atomic.Bool
+
+	// closeReason is the CloseReason, set once by the first close.
+	closeReason atomic.Int32
+
+	// reqCtx is the request's context (nil if there's no request), used
+	// to tell a client disconnect from a canceled UpgradeWithContext ctx.
+	reqCtx context.Context
+
+	// closeDone closes done exactly once: from Close, or from the write
+	// that was in progress when Close ran.
+	closeDone sync.Once
+
+	// writeTimeout bounds each write (0 = no limit). Stored as a
+	// time.Duration; atomic so it can be changed during a stuck write.
+	writeTimeout atomic.Int64
+
+	// lastEventID is the Last-Event-ID sent by a reconnecting client.
+	lastEventID string
+
+	// clientIP is the client's IP address (see UpgradeOptions).
+	clientIP string
+
+	// stopKeepAlive stops the running keep-alive goroutine (nil if none).
+	// Protected by mu.
+	stopKeepAlive chan struct{}
+
+	// keepAliveComment is the pre-formatted keep-alive comment, sent every
+	// keep-alive interval plus up to keepAliveJitter (see UpgradeOptions).
+	keepAliveComment string
+	keepAliveJitter  time.Duration
+
+	// stopAutoFlush stops the running auto-flush goroutine (nil if none).
+	// Protected by mu.
+	stopAutoFlush chan struct{}
+
+	// buffered holds events queued by SendNoFlush, written by the next
+	// Flush or Send. Protected by mu.
+	buffered []byte
+
+	// autoID is set by EnableAutoID; lastAutoID is the last ID assigned
+	// (protected by mu).
+	autoID     atomic.Bool
+	lastAutoID uint64
+
+	// onWriteError is called when a write fails (nil if none).
+	onWriteError atomic.Pointer[func(error)]
+
+	// logger receives internal errors (nil = discard, see log).
+	logger Logger
+
+	// metadata holds application values set with SetMetadata.
+	// Protected by metaMu.
+	metaMu   sync.RWMutex
+	metadata map[string]any
 }
 
 // Upgrade upgrades an HTTP connection to SSE with the request's context.
@@ -54,7 +163,14 @@ type Conn struct {
 // It sets the necessary SSE headers, validates that the ResponseWriter supports
 // flushing, and sends an initial connection comment.
 //
+// Upgrade works over HTTP/1.1 and HTTP/2. The connection is never hijacked:
+// every event is written to the ResponseWriter and flushed, which on
+// HTTP/2 sends it as a DATA frame on the request's stream. The
+// Connection: keep-alive header is only set for HTTP/1.x, since HTTP/2
+// forbids connection-specific headers.
+//
 // The connection uses r.Context() for cancellation tracking.
+// The client's Last-Event-ID (if any) is available via Conn.LastEventID.
 //
 // Returns ErrNoFlusher if the ResponseWriter doesn't implement http.Flusher.
 //
@@ -67,7 +183,74 @@ type Conn struct {
 //	}
 //	defer conn.Close()
 func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
-	return UpgradeWithContext(r.Context(), w, r)
+	return upgrade(r.Context(), w, r, nil)
+}
+
+// UpgradeOptions configures SSE upgrade behavior.
+//
+// All fields are optional. Zero values use sensible defaults.
+type UpgradeOptions struct {
+	// TrustedProxyHeader names the header a reverse proxy sets to the
+	// client's address, e.g. "X-Forwarded-For" or "X-Real-IP".
+	// Empty = use r.RemoteAddr (default).
+	//
+	// Only set this when every request passes through a proxy that sets
+	// the header, or clients can spoof their address. For X-Forwarded-For
+	// the last address is used: the one added by the nearest proxy.
+	// See Conn.ClientIP.
+	TrustedProxyHeader string
+
+	// KeepAliveComment is the comment text StartKeepAlive sends, e.g.
+	// "ping" for ": ping\n\n", for proxies that expect a specific
+	// keep-alive. Empty = an empty comment line (":\n\n", default).
+	KeepAliveComment string
+
+	// KeepAliveJitter adds a random delay in [0, KeepAliveJitter) to each
+	// keep-alive interval, so thousands of connections started together
+	// don't all flush at the same moment (default: 0, no jitter).
+	KeepAliveJitter time.Duration
+
+	// WriteBufferSize, if positive, wraps the ResponseWriter in a
+	// bufio.Writer of this size for high-throughput streams. Writes go
+	// through the buffer, which is flushed along with the response after
+	// every Send, so events are never held back (default: 0, write
+	// directly).
+	WriteBufferSize int
+
+	// Charset, if set, is appended to the Content-Type, e.g. "utf-8" for
+	// "text/event-stream; charset=utf-8", for clients that require it.
+	// SSE is always UTF-8 (default: "", bare "text/event-stream").
+	Charset string
+
+	// Header holds extra response headers, e.g. Access-Control-Allow-Origin
+	// for EventSource connections from another origin. They're set before
+	// the SSE headers, which take precedence.
+	Header http.Header
+
+	// Logger receives errors the connection handles internally, such as
+	// a failed keep-alive (default: discard).
+	Logger Logger
+}
+
+// UpgradeWithOptions upgrades an HTTP connection to SSE like Upgrade,
+// configured by opts. A nil opts is equivalent to Upgrade.
+//
+// Example:
+//
+//	conn, err := sse.UpgradeWithOptions(w, r, &sse.UpgradeOptions{
+//	    TrustedProxyHeader: "X-Forwarded-For",
+//	    Charset:            "utf-8",
+//	    Header: http.Header{
+//	        "Access-Control-Allow-Origin": {"https://app.example.com"},
+//	    },
+//	})
+//	if err != nil {
+//	    http.Error(w, err.Error(), http.StatusInternalServerError)
+//	    return
+//	}
+//	defer conn.Close()
+func UpgradeWithOptions(w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Conn, error) {
+	return upgrade(r.Context(), w, r, opts)
 }
 
 // UpgradeWithContext upgrades an HTTP connection to SSE with a custom context.
@@ -82,17 +265,37 @@ func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
 //	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 //	defer cancel()
 //	conn, err := sse.UpgradeWithContext(ctx, w, r)
-func UpgradeWithContext(ctx context.Context, w http.ResponseWriter, _ *http.Request) (*Conn, error) {
+func UpgradeWithContext(ctx context.Context, w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	return upgrade(ctx, w, r, nil)
+}
+
+// upgrade implements Upgrade, UpgradeWithOptions, and UpgradeWithContext.
+func upgrade(ctx context.Context, w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Conn, error) {
+	if opts == nil {
+		opts = &UpgradeOptions{}
+	}
+
 	// Verify ResponseWriter supports flushing
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		return nil, ErrNoFlusher
 	}
 
-	// Set SSE headers
-	w.Header().Set("Content-Type", "text/event-stream")
+	for name, values := range opts.Header {
+		w.Header()[http.CanonicalHeaderKey(name)] = values
+	}
+
+	// Set SSE headers. Connection is a hop-by-hop header, which HTTP/2
+	// forbids (RFC 9113 Section 8.2.2); HTTP/2 streams stay open anyway.
+	contentType := "text/event-stream"
+	if opts.Charset != "" {
+		contentType += "; charset=" + opts.Charset
+	}
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+	if !isHTTP2(r) {
+		w.Header().Set("Connection", "keep-alive")
+	}
 	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
 
 	// Send initial connection comment
@@ -105,12 +308,27 @@ func UpgradeWithContext(ctx context.Context, w http.ResponseWriter, _ *http.Requ
 	// Create connection with context
 	connCtx, cancel := context.WithCancel(ctx)
 	conn := &Conn{
-		w:       w,
-		flusher: flusher,
-		ctx:     connCtx,
-		cancel:  cancel,
-		done:    make(chan struct{}),
-		closed:  false,
+		w:      w,
+		ctx:    connCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+
+		lastEventID: lastEventID(r),
+		clientIP:    clientIP(r, opts.TrustedProxyHeader),
+
+		keepAliveComment: ":\n\n",
+		keepAliveJitter:  max(opts.KeepAliveJitter, 0),
+
+		logger: opts.Logger,
+	}
+	if opts.KeepAliveComment != "" {
+		conn.keepAliveComment = Comment(opts.KeepAliveComment)
+	}
+	if opts.WriteBufferSize > 0 {
+		conn.bw = bufio.NewWriterSize(w, opts.WriteBufferSize)
+	}
+	if r != nil {
+		conn.reqCtx = r.Context()
 	}
 
 	// Watch for context cancellation
@@ -119,15 +337,65 @@ func UpgradeWithContext(ctx context.Context, w http.ResponseWriter, _ *http.Requ
 	return conn, nil
 }
 
+// isHTTP2 reports whether r arrived over HTTP/2 or later.
+func isHTTP2(r *http.Request) bool {
+	return r != nil && r.ProtoMajor >= 2
+}
+
+// lastEventID extracts the client's last seen event ID from the request.
+//
+// Browsers send the Last-Event-ID header when EventSource reconnects.
+// The lastEventId query parameter is accepted as a fallback for clients
+// that can't set custom headers (e.g. polyfills).
+func lastEventID(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+// clientIP returns the client's IP address for r.
+//
+// If header is set and holds a valid IP address, it's used (the last one
+// of a comma-separated list); otherwise the host of r.RemoteAddr.
+func clientIP(r *http.Request, header string) string {
+	if r == nil {
+		return ""
+	}
+	if header != "" {
+		values := strings.Split(r.Header.Get(header), ",")
+		if addr, err := netip.ParseAddr(strings.TrimSpace(values[len(values)-1])); err == nil {
+			return addr.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // watchContext monitors the context and closes the connection when canceled.
 func (c *Conn) watchContext() {
 	<-c.ctx.Done()
-	_ = c.Close()
+
+	reason := ContextCanceled
+	if c.reqCtx != nil && c.reqCtx.Err() != nil {
+		reason = ClientDisconnected
+	}
+	_ = c.close(reason)
 }
 
 // Send sends an Event to the client.
 //
-// Returns ErrConnectionClosed if the connection is already closed.
+// Returns ErrConnectionClosed if the connection is already closed. If the
+// write or flush fails (the client went away, or http.Server's
+// WriteTimeout expired), the connection is closed, so Done unblocks, and
+// the returned error wraps both ErrConnectionClosed and the cause.
 //
 // Example:
 //
@@ -136,22 +404,96 @@ func (c *Conn) watchContext() {
 //	    WithID("evt-123")
 //	err := conn.Send(event)
 func (c *Conn) Send(event *Event) error {
+	if event.ID == "" && c.autoID.Load() {
+		return c.sendAutoID(event)
+	}
+
+	// Serialize outside the lock; written and flushed immediately
+	return c.sendEncoded(event.Bytes())
+}
+
+// SendContext sends an Event to the client like Send, aborting the write
+// if ctx is canceled or its deadline passes first.
+//
+// Use it to bound a send to a slow client by a request-scoped context
+// rather than the connection-wide write timeout. If ctx ends while the
+// write or flush is blocked, the write is interrupted, the connection is
+// closed (the stream can't be resumed after a partial write), and
+// SendContext returns ctx.Err(). If ctx is already done, nothing is sent.
+//
+// Returns ErrConnectionClosed if the connection is already closed.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+//	defer cancel()
+//	err := conn.SendContext(ctx, sse.NewEvent("update"))
+func (c *Conn) SendContext(ctx context.Context, event *Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 
-	if c.closed {
+	if c.closed.Load() {
 		return ErrConnectionClosed
 	}
 
-	// Write event to response
-	_, err := io.WriteString(c.w, event.String())
-	if err != nil {
-		return fmt.Errorf("sse: failed to write event: %w", err)
+	if event.ID == "" && c.autoID.Load() {
+		event = c.withAutoID(event)
 	}
+	return c.writeContext(ctx, event.Bytes(), "event")
+}
 
-	// Flush immediately to send to client
-	c.flusher.Flush()
-	return nil
+// writeContext writes p like writeBuffered, interrupting the write by
+// expiring the write deadline when ctx ends. Caller must hold c.mu.
+func (c *Conn) writeContext(ctx context.Context, p []byte, what string) error {
+	if ctx.Done() == nil {
+		return c.writeBuffered(p, what)
+	}
+
+	rc := http.NewResponseController(c.w)
+	fired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(fired)
+		_ = rc.SetWriteDeadline(time.Now())
+	})
+
+	err := c.writeBuffered(p, what)
+	if !stop() {
+		// Don't leave the expired deadline behind for later writes
+		<-fired
+		_ = rc.SetWriteDeadline(time.Time{})
+	}
+
+	if err != nil && ctx.Err() != nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		// write already closed the connection
+		return ctx.Err()
+	}
+	return err
+}
+
+// sendAutoID sends event with the next auto-assigned ID.
+// The ID is assigned under the lock so IDs go out in order.
+func (c *Conn) sendAutoID(event *Event) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.closed.Load() {
+		return ErrConnectionClosed
+	}
+
+	return c.writeBuffered(c.withAutoID(event).Bytes(), "event")
+}
+
+// withAutoID returns a copy of event with the next auto-assigned ID.
+// The caller's event is left untouched. Caller must hold mu.
+func (c *Conn) withAutoID(event *Event) *Event {
+	c.lastAutoID++
+	e := *event
+	e.ID = strconv.FormatUint(c.lastAutoID, 10)
+	return &e
 }
 
 // SendData sends a simple data-only event to the client.
@@ -167,6 +509,162 @@ func (c *Conn) SendData(data string) error {
 	return c.Send(NewEvent(data))
 }
 
+// SendBytes sends a data-only event with a pre-encoded payload.
+//
+// It's equivalent to SendData(string(data)) without the string conversion.
+// Multi-line data becomes multiple "data:" lines, same as SendData.
+//
+// Returns ErrConnectionClosed if the connection is already closed.
+//
+// Example:
+//
+//	payload, _ := json.Marshal(update)
+//	err := conn.SendBytes(payload)
+func (c *Conn) SendBytes(data []byte) error {
+	if c.autoID.Load() {
+		return c.sendAutoID(&Event{Data: string(data)})
+	}
+
+	buf := appendLines(make([]byte, 0, len(data)+8), "data: ", data)
+	return c.sendEncoded(append(buf, '\n'))
+}
+
+// SendBinary sends b as a base64-encoded event of type BinaryEventType.
+//
+// It's equivalent to Send(NewEvent("").WithBinary(b)); see Event.WithBinary
+// for decoding on the client.
+//
+// Returns ErrConnectionClosed if the connection is already closed.
+//
+// Example:
+//
+//	err := conn.SendBinary(thumbnail)
+func (c *Conn) SendBinary(b []byte) error {
+	return c.Send(NewEvent("").WithBinary(b))
+}
+
+// sendEncoded writes a pre-serialized event.
+func (c *Conn) sendEncoded(p []byte) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.closed.Load() {
+		return ErrConnectionClosed
+	}
+
+	return c.writeBuffered(p, "event")
+}
+
+// SendNoFlush queues an Event without writing it to the client.
+//
+// Queued events are written together, in one write and flush, by the next
+// Flush or by any Send, SendComment, or keep-alive on the connection (which
+// keeps events in order). Batching cuts the per-event write and flush cost
+// when sending at high rates. Use StartAutoFlush to bound how long events
+// may stay queued.
+//
+// Events still queued when the connection closes are discarded.
+//
+// Returns ErrConnectionClosed if the connection is already closed.
+//
+// Example:
+//
+//	for _, update := range updates {
+//	    _ = conn.SendNoFlush(sse.NewEvent(update))
+//	}
+//	err := conn.Flush()
+func (c *Conn) SendNoFlush(event *Event) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.closed.Load() {
+		return ErrConnectionClosed
+	}
+
+	if event.ID == "" && c.autoID.Load() {
+		event = c.withAutoID(event)
+	}
+	c.buffered = event.appendTo(c.buffered)
+	return nil
+}
+
+// Flush writes events queued by SendNoFlush to the client.
+//
+// It's a no-op if nothing is queued.
+//
+// Returns ErrConnectionClosed if the connection is already closed.
+//
+// Example:
+//
+//	err := conn.Flush()
+func (c *Conn) Flush() error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.closed.Load() {
+		return ErrConnectionClosed
+	}
+	if len(c.buffered) == 0 {
+		return nil
+	}
+
+	return c.writeBuffered(nil, "events")
+}
+
+// StartAutoFlush flushes queued events at the given interval.
+//
+// It bounds the latency added by SendNoFlush: queued events are written at
+// most interval after they're queued. Intervals with nothing queued don't
+// write anything.
+//
+// The auto-flush goroutine stops automatically when the connection is
+// closed. Calling StartAutoFlush again replaces the running auto-flush with
+// the new interval. Non-positive intervals are ignored.
+//
+// Example:
+//
+//	conn.StartAutoFlush(50 * time.Millisecond)
+//	for update := range updates {
+//	    _ = conn.SendNoFlush(sse.NewEvent(update))
+//	}
+func (c *Conn) StartAutoFlush(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.closed.Load() {
+		return
+	}
+	if c.stopAutoFlush != nil {
+		close(c.stopAutoFlush)
+	}
+	c.stopAutoFlush = make(chan struct{})
+	go c.autoFlush(interval, c.stopAutoFlush)
+}
+
+// autoFlush flushes queued events every interval until the connection
+// closes or stop is closed.
+func (c *Conn) autoFlush(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(); err != nil {
+				return
+			}
+		case <-stop:
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
+
 // SendJSON sends a JSON-encoded event to the client.
 //
 // The value is marshaled to JSON using encoding/json/v2. If marshaling fails,
@@ -174,16 +672,352 @@ func (c *Conn) SendData(data string) error {
 //
 // Returns ErrConnectionClosed if the connection is already closed.
 //
+// Encoding buffers are pooled and reused across calls, so repeated sends
+// don't allocate fresh buffers each time.
+//
 // Example:
 //
 //	user := map[string]string{"name": "Alice", "status": "online"}
 //	err := conn.SendJSON(user)
 func (c *Conn) SendJSON(v any) error {
-	data, err := json.Marshal(v)
-	if err != nil {
+	buf := jsonBuffers.Get().(*jsonBuffer)
+	defer putJSONBuffer(buf)
+
+	buf.data.Reset()
+	if err := json.MarshalWrite(&buf.data, v); err != nil {
 		return fmt.Errorf("sse: failed to marshal JSON: %w", err)
 	}
-	return c.SendData(string(data))
+
+	if c.autoID.Load() {
+		return c.sendAutoID(&Event{Data: buf.data.String()})
+	}
+
+	// Same encoding as SendBytes. The write completes before sendEncoded
+	// returns, so the buffers can be reused afterwards.
+	buf.event = appendLines(buf.event[:0], "data: ", buf.data.Bytes())
+	buf.event = append(buf.event, '\n')
+	return c.sendEncoded(buf.event)
+}
+
+// maxPooledJSONBuffer is the largest buffer SendJSON returns to the pool,
+// so an occasional huge event doesn't pin its memory.
+const maxPooledJSONBuffer = 64 << 10
+
+// jsonBuffer holds SendJSON's encoded value and serialized event.
+type jsonBuffer struct {
+	data  bytes.Buffer
+	event []byte
+}
+
+// jsonBuffers pools SendJSON buffers.
+var jsonBuffers = sync.Pool{
+	New: func() any { return new(jsonBuffer) },
+}
+
+// putJSONBuffer returns buf to the pool unless it grew too large.
+func putJSONBuffer(buf *jsonBuffer) {
+	if buf.data.Cap() <= maxPooledJSONBuffer && cap(buf.event) <= maxPooledJSONBuffer {
+		jsonBuffers.Put(buf)
+	}
+}
+
+// SendComment sends an SSE comment line to the client.
+//
+// Comments are written as ": <text>\n\n" and ignored by EventSource clients.
+// They're useful for debugging streams or sending manual keep-alives.
+//
+// Returns ErrConnectionClosed if the connection is already closed.
+//
+// Example:
+//
+//	err := conn.SendComment("heartbeat")
+func (c *Conn) SendComment(text string) error {
+	return c.writeComment(Comment(text))
+}
+
+// StartKeepAlive starts sending keep-alive comments at the given interval.
+//
+// Proxies and load balancers often drop idle HTTP connections. Sending an
+// empty comment line (":\n\n") periodically keeps the connection active
+// without producing events on the client side.
+//
+// The comment text and a random jitter added to each interval are set with
+// UpgradeOptions.KeepAliveComment and UpgradeOptions.KeepAliveJitter.
+//
+// The keep-alive goroutine stops automatically when the connection is closed.
+// Writes are serialized with Send, so it's safe to use concurrently.
+// Calling StartKeepAlive again replaces the running keep-alive with the new
+// interval. Non-positive intervals are ignored.
+//
+// Example:
+//
+//	conn, err := sse.Upgrade(w, r)
+//	if err != nil {
+//	    return
+//	}
+//	conn.StartKeepAlive(15 * time.Second)
+func (c *Conn) StartKeepAlive(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.closed.Load() {
+		return
+	}
+	if c.stopKeepAlive != nil {
+		close(c.stopKeepAlive)
+	}
+	c.stopKeepAlive = make(chan struct{})
+	go c.keepAlive(interval, c.stopKeepAlive)
+}
+
+// keepAlive writes comment lines every interval until the connection closes
+// or stop is closed.
+func (c *Conn) keepAlive(interval time.Duration, stop <-chan struct{}) {
+	timer := time.NewTimer(keepAliveDelay(interval, c.keepAliveJitter))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := c.writeComment(c.keepAliveComment); err != nil {
+				return
+			}
+			timer.Reset(keepAliveDelay(interval, c.keepAliveJitter))
+		case <-stop:
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// keepAliveDelay returns interval plus a random jitter in [0, jitter).
+func keepAliveDelay(interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return interval + rand.N(jitter)
+}
+
+// writeComment writes a pre-formatted comment line and flushes.
+func (c *Conn) writeComment(comment string) error {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if c.closed.Load() {
+		return ErrConnectionClosed
+	}
+
+	return c.writeBuffered([]byte(comment), "comment")
+}
+
+// writeBuffered writes queued events followed by p in a single write.
+// Caller must hold c.mu.
+func (c *Conn) writeBuffered(p []byte, what string) error {
+	if len(c.buffered) == 0 {
+		return c.write(p, what)
+	}
+
+	// The queue is dropped even if the write fails; a failed write
+	// leaves the stream in an unknown state anyway
+	buf := append(c.buffered, p...)
+	c.buffered = buf[:0]
+	return c.write(buf, what)
+}
+
+// write writes p and flushes, bounded by the write timeout if set.
+// what names the payload in errors. Caller must hold c.mu.
+//
+// A failed write or flush closes the connection: the response can't be
+// written to reliably afterwards (e.g. the client went away, or
+// http.Server's WriteTimeout expired), and closing unblocks handlers
+// waiting on Done.
+func (c *Conn) write(p []byte, what string) error {
+	rc := http.NewResponseController(c.w)
+
+	timeout := time.Duration(c.writeTimeout.Load())
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+		_ = rc.SetWriteDeadline(deadline)
+	}
+
+	// Flush through the controller to surface errors from the socket
+	var err error
+	if c.bw != nil {
+		_, err = c.bw.Write(p)
+		if err == nil {
+			err = c.bw.Flush()
+		}
+	} else {
+		_, err = c.w.Write(p)
+	}
+	if err == nil {
+		err = rc.Flush()
+	}
+
+	if err != nil {
+		// net/http cancels the request context on write errors, so the
+		// connection may already be closing; check that our deadline
+		// expired rather than one set by Close
+		if timeout > 0 && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(deadline) {
+			err = c.writeFailed(fmt.Errorf("%w: %w", ErrConnectionClosed, ErrWriteTimeout))
+			_ = c.close(ClientDisconnected)
+			return err
+		}
+		err = c.writeFailed(fmt.Errorf("%w: failed to write %s: %w", ErrConnectionClosed, what, err))
+		_ = c.close(ClientDisconnected)
+		return err
+	}
+
+	if timeout > 0 {
+		// Clear the deadline so it can't affect writes outside Send
+		_ = rc.SetWriteDeadline(time.Time{})
+	}
+	return nil
+}
+
+// writeFailed reports err to the OnWriteError callback, if any, and
+// returns err. Caller must hold c.mu.
+func (c *Conn) writeFailed(err error) error {
+	c.log().Debug("sse: write failed", "client", c.clientIP, "err", err)
+	if fn := c.onWriteError.Load(); fn != nil {
+		(*fn)(err)
+	}
+	return err
+}
+
+// OnWriteError sets a callback invoked with the error whenever a write to
+// the client fails, including keep-alives and auto-flushes that have no
+// caller to return the error to. Pass nil to remove it.
+//
+// A failed write closes the connection, but fn is called first, so it
+// still sees the connection open. Like the error returned by Send, err
+// wraps ErrConnectionClosed and the cause (ErrWriteTimeout on a write
+// timeout). fn runs while the connection's write lock is held: it must not
+// send on the connection, but may call Close.
+//
+// Example:
+//
+//	conn.OnWriteError(func(err error) {
+//	    log.Printf("sse: write to %s failed: %v", r.RemoteAddr, err)
+//	})
+func (c *Conn) OnWriteError(fn func(error)) {
+	if fn == nil {
+		c.onWriteError.Store(nil)
+		return
+	}
+	c.onWriteError.Store(&fn)
+}
+
+// SetWriteTimeout bounds how long each write may block.
+//
+// A client that stops reading eventually fills the TCP buffers, and writes
+// block until it reads again. With a write timeout, a write that doesn't
+// complete in time fails with ErrWriteTimeout and the connection is closed:
+// Done is closed and later sends return ErrConnectionClosed. Handlers
+// blocked on Done can then return, freeing the request goroutine.
+//
+// The timeout applies to every Send and comment (including keep-alives).
+// It requires a ResponseWriter that supports write deadlines via
+// http.ResponseController, as net/http's does; otherwise it has no effect.
+// Zero or negative disables the timeout (default).
+//
+// Example:
+//
+//	conn, err := sse.Upgrade(w, r)
+//	if err != nil {
+//	    return
+//	}
+//	conn.SetWriteTimeout(10 * time.Second)
+func (c *Conn) SetWriteTimeout(d time.Duration) {
+	c.writeTimeout.Store(int64(d))
+}
+
+// EnableAutoID makes the connection assign an ID to every event sent
+// without one.
+//
+// IDs are decimal integers increasing by one from 1 ("1", "2", "3", ...),
+// per connection. Events with an ID set by the caller keep it and don't
+// consume an auto ID. Auto IDs apply to Send, SendData, SendJSON, SendBytes
+// and SendNoFlush; events broadcast by a Hub are sent as the Hub encoded
+// them.
+//
+// The client reports the last ID it received in Last-Event-ID when it
+// reconnects (see LastEventID).
+//
+// Example:
+//
+//	conn.EnableAutoID()
+//	_ = conn.SendData("first")  // id: 1
+//	_ = conn.SendData("second") // id: 2
+func (c *Conn) EnableAutoID() {
+	c.autoID.Store(true)
+}
+
+// LastEventID returns the event ID the client last received.
+//
+// It's populated from the Last-Event-ID request header (or the lastEventId
+// query parameter) during Upgrade. Empty for first-time connections.
+//
+// Use it to replay events the client missed while disconnected. A Hub
+// created with NewHubWithHistory does this automatically on Register.
+//
+// Example:
+//
+//	if id := conn.LastEventID(); id != "" {
+//	    log.Printf("client resuming after event %s", id)
+//	}
+func (c *Conn) LastEventID() string {
+	return c.lastEventID
+}
+
+// ClientIP returns the IP address of the client.
+//
+// By default it's the host of the request's RemoteAddr. Behind a reverse
+// proxy, use UpgradeWithOptions with UpgradeOptions.TrustedProxyHeader to
+// take it from a header such as X-Forwarded-For instead.
+//
+// Example:
+//
+//	slog.Info("Client connected", "ip", conn.ClientIP())
+func (c *Conn) ClientIP() string {
+	return c.clientIP
+}
+
+// SetMetadata stores an application value on the connection, such as the
+// user's locale or role. Hub.BroadcastFunc predicates can read it with
+// Metadata to pick recipients. A nil value removes the key.
+// Safe to call concurrently with Metadata and hub broadcasts.
+//
+// Example:
+//
+//	conn.SetMetadata("role", "admin")
+//	hub.Register(conn)
+func (c *Conn) SetMetadata(key string, value any) {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+
+	if value == nil {
+		delete(c.metadata, key)
+		return
+	}
+	if c.metadata == nil {
+		c.metadata = make(map[string]any)
+	}
+	c.metadata[key] = value
+}
+
+// Metadata returns the value stored with SetMetadata, or nil if key
+// isn't set.
+func (c *Conn) Metadata(key string) any {
+	c.metaMu.RLock()
+	defer c.metaMu.RUnlock()
+	return c.metadata[key]
 }
 
 // Close closes the SSE connection.
@@ -192,23 +1026,61 @@ func (c *Conn) SendJSON(v any) error {
 //
 // After Close, all Send operations will return ErrConnectionClosed.
 //
+// Close doesn't wait for a Send in progress. If one is blocked on a client
+// that stopped reading, Close sets an immediate write deadline (where the
+// ResponseWriter supports it) so the pending write fails. Done is closed
+// once that write returns, so handlers waiting on Done never return while
+// the ResponseWriter is still in use.
+//
 // Example:
 //
 //	defer conn.Close()
 func (c *Conn) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	return c.close(ServerClosed)
+}
 
-	if c.closed {
+// close implements Close, recording reason if this is the first close.
+func (c *Conn) close(reason CloseReason) error {
+	if !c.closeReason.CompareAndSwap(int32(NotClosed), int32(reason)) {
 		return nil
 	}
+	c.closed.Store(true)
 
-	c.closed = true
 	c.cancel()
-	close(c.done)
+
+	if c.mu.TryLock() {
+		// No write in progress
+		c.unlock()
+		return nil
+	}
+
+	// The writer closes done when it unlocks
+	_ = http.NewResponseController(c.w).SetWriteDeadline(time.Now())
 	return nil
 }
 
+// unlock releases mu. If the connection was closed while mu was held,
+// it closes done, so Done never fires while a write is still in progress.
+func (c *Conn) unlock() {
+	if c.closed.Load() {
+		c.closeDone.Do(func() { close(c.done) })
+	}
+	c.mu.Unlock()
+}
+
+// CloseReason returns why the connection was closed, or NotClosed while
+// it's open.
+//
+// The reason is final once Done is closed.
+//
+// Example:
+//
+//	<-conn.Done()
+//	slog.Info("Client left", "reason", conn.CloseReason())
+func (c *Conn) CloseReason() CloseReason {
+	return CloseReason(c.closeReason.Load())
+}
+
 // Done returns a channel that's closed when the connection is closed.
 //
 // This is useful for coordinating shutdown with goroutines sending events.
@@ -230,3 +1102,17 @@ func (c *Conn) Close() error {
 func (c *Conn) Done() <-chan struct{} {
 	return c.done
 }
+
+// Context returns the connection's context: the one passed to
+// UpgradeWithContext (the request's context for Upgrade), canceled when
+// the connection is closed for any reason.
+//
+// Use it to scope work to the connection's lifetime, such as database
+// queries for a stream.
+//
+// Example:
+//
+//	rows, err := db.QueryContext(conn.Context(), query)
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
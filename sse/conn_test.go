@@ -1,11 +1,18 @@
 package sse
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -72,6 +79,93 @@ func TestUpgrade_Success(t *testing.T) {
 	}
 }
 
+// TestUpgrade_HTTP2 tests streaming events over an HTTP/2 server.
+func TestUpgrade_HTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		for i := range 3 {
+			if err := conn.SendData("event " + strconv.Itoa(i)); err != nil {
+				return
+			}
+		}
+		<-conn.Done()
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Proto = %s, want HTTP/2", resp.Proto)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want %q", got, "text/event-stream")
+	}
+	if got := resp.Header.Get("Connection"); got != "" {
+		t.Errorf("Connection = %q, want none over HTTP/2", got)
+	}
+
+	// Each event must arrive while the stream is still open
+	reader := bufio.NewReader(resp.Body)
+	var events []string
+	for len(events) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString() error = %v (events so far: %q)", err, events)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, strings.TrimSuffix(data, "\n"))
+		}
+	}
+	for i, got := range events {
+		if want := "event " + strconv.Itoa(i); got != want {
+			t.Errorf("event %d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+// TestUpgrade_HeaderOptions tests the Charset and Header options.
+func TestUpgrade_HeaderOptions(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := UpgradeWithOptions(w, r, &UpgradeOptions{
+		Charset: "utf-8",
+		Header: http.Header{
+			"access-control-allow-origin": {"https://app.example.com"},
+			"Content-Type":                {"text/plain"}, // Overridden
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpgradeWithOptions failed: %v", err)
+	}
+	defer conn.Close()
+
+	if got, want := w.Header().Get("Content-Type"), "text/event-stream; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	if got, want := w.Header().Get("Access-Control-Allow-Origin"), "https://app.example.com"; got != want {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, want)
+	}
+}
+
 // TestUpgrade_NoFlusher tests upgrade failure when ResponseWriter doesn't support flushing.
 func TestUpgrade_NoFlusher(t *testing.T) {
 	w := newMockResponseWriter()
@@ -105,6 +199,79 @@ func TestUpgradeWithContext_CustomContext(t *testing.T) {
 	}
 }
 
+// TestUpgrade_LastEventID tests that Last-Event-ID is read from the request.
+func TestUpgrade_LastEventID(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   string
+	}{
+		{"none", "/events", "", ""},
+		{"header", "/events", "evt-42", "evt-42"},
+		{"query", "/events?lastEventId=evt-7", "", "evt-7"},
+		{"header wins", "/events?lastEventId=evt-7", "evt-42", "evt-42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tt.target, http.NoBody)
+			if tt.header != "" {
+				r.Header.Set("Last-Event-ID", tt.header)
+			}
+
+			conn, err := Upgrade(w, r)
+			if err != nil {
+				t.Fatalf("Upgrade failed: %v", err)
+			}
+			defer conn.Close()
+
+			if got := conn.LastEventID(); got != tt.want {
+				t.Errorf("LastEventID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUpgrade_ClientIP tests that X-Forwarded-For is used only when
+// TrustedProxyHeader is set.
+func TestUpgrade_ClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   *UpgradeOptions
+		header string
+		want   string
+	}{
+		{"default", nil, "203.0.113.7", "192.0.2.1"},
+		{"not trusted", &UpgradeOptions{}, "203.0.113.7", "192.0.2.1"},
+		{"trusted", &UpgradeOptions{TrustedProxyHeader: "X-Forwarded-For"}, "203.0.113.7", "203.0.113.7"},
+		{"proxy chain", &UpgradeOptions{TrustedProxyHeader: "X-Forwarded-For"}, "198.51.100.1, 203.0.113.7", "203.0.113.7"},
+		{"missing header", &UpgradeOptions{TrustedProxyHeader: "X-Forwarded-For"}, "", "192.0.2.1"},
+		{"invalid header", &UpgradeOptions{TrustedProxyHeader: "X-Forwarded-For"}, "unknown", "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/events", http.NoBody)
+			if tt.header != "" {
+				r.Header.Set("X-Forwarded-For", tt.header)
+			}
+
+			conn, err := UpgradeWithOptions(w, r, tt.opts)
+			if err != nil {
+				t.Fatalf("UpgradeWithOptions failed: %v", err)
+			}
+			defer conn.Close()
+
+			if got := conn.ClientIP(); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestConn_Send tests sending an event.
 func TestConn_Send(t *testing.T) {
 	w := httptest.NewRecorder()
@@ -294,7 +461,315 @@ func TestConn_Close(t *testing.T) {
 	}
 }
 
+// TestConn_Close_StalledWrite tests that Close doesn't wait on a stuck write.
+func TestConn_Close_StalledWrite(t *testing.T) {
+	w := newStalledWriter()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	w.stalled.Store(true)
+
+	sent := make(chan struct{})
+	go func() {
+		_ = conn.SendData("stuck")
+		close(sent)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		_ = conn.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close() blocked on stalled write")
+	}
+
+	// The write deadline aborts the write, then Done fires
+	select {
+	case <-conn.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done channel not closed after stalled write was aborted")
+	}
+	<-sent
+
+	if err := conn.SendData("after"); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("SendData() after Close error = %v, want ErrConnectionClosed", err)
+	}
+}
+
+// TestConn_SetWriteTimeout tests that Send fails instead of hanging when
+// the client stops reading.
+func TestConn_SetWriteTimeout(t *testing.T) {
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+	var callbackErr atomic.Pointer[error]
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			errc <- err
+			return
+		}
+		conn.SetWriteTimeout(100 * time.Millisecond)
+		conn.OnWriteError(func(err error) { callbackErr.Store(&err) })
+
+		// Keep writing until the client's buffers fill up
+		payload := strings.Repeat("x", 64*1024)
+		for i := 0; i < 4096; i++ {
+			if err = conn.SendData(payload); err != nil {
+				break
+			}
+		}
+
+		select {
+		case <-conn.Done():
+			close(done)
+		case <-time.After(time.Second):
+		}
+		errc <- err
+	}))
+	defer server.Close()
+
+	// Raw client that sends a request and never reads the response
+	client, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrWriteTimeout) || !errors.Is(err, ErrConnectionClosed) {
+			t.Fatalf("SendData() error = %v, want ErrWriteTimeout and ErrConnectionClosed", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("SendData() blocked on stalled client")
+	}
+
+	select {
+	case <-done:
+	default:
+		t.Error("Done channel not closed after write timeout")
+	}
+	var got error
+	if p := callbackErr.Load(); p != nil {
+		got = *p
+	}
+	if !errors.Is(got, ErrWriteTimeout) || !errors.Is(got, ErrConnectionClosed) {
+		t.Errorf("OnWriteError got %v, want ErrWriteTimeout and ErrConnectionClosed", got)
+	}
+}
+
+// failingWriter is a ResponseWriter whose writes fail once fail is set,
+// simulating a client that went away.
+type failingWriter struct {
+	header http.Header
+	fail   atomic.Bool
+}
+
+func (f *failingWriter) Header() http.Header { return f.header }
+func (f *failingWriter) WriteHeader(int)     {}
+func (f *failingWriter) Flush()              {}
+
+func (f *failingWriter) Write(b []byte) (int, error) {
+	if f.fail.Load() {
+		return 0, context.Canceled
+	}
+	return len(b), nil
+}
+
+// TestConn_OnWriteError tests that the callback fires once per failed write,
+// while the connection is still open.
+func TestConn_OnWriteError(t *testing.T) {
+	w := &failingWriter{header: make(http.Header)}
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	defer conn.Close()
+
+	var calls int
+	var got error
+	conn.OnWriteError(func(err error) {
+		calls++
+		got = err
+		if conn.closed.Load() {
+			t.Error("OnWriteError called after the connection closed")
+		}
+	})
+
+	if err := conn.SendData("ok"); err != nil {
+		t.Fatalf("SendData() error = %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("OnWriteError called %d times for a successful write", calls)
+	}
+
+	w.fail.Store(true)
+	err = conn.SendData("lost")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("SendData() error = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Fatalf("OnWriteError called %d times, want 1", calls)
+	}
+	if !errors.Is(got, context.Canceled) {
+		t.Errorf("OnWriteError got %v, want the write error", got)
+	}
+
+	// Removing the callback stops notifications
+	conn.OnWriteError(nil)
+	_ = conn.SendData("lost again")
+	if calls != 1 {
+		t.Errorf("OnWriteError called %d times after removal, want 1", calls)
+	}
+}
+
+// flushFailWriter is a ResponseWriter whose flushes fail after the first
+// failAfter, like net/http once the server's WriteTimeout has expired.
+type flushFailWriter struct {
+	header    http.Header
+	flushes   int
+	failAfter int
+}
+
+func (f *flushFailWriter) Header() http.Header         { return f.header }
+func (f *flushFailWriter) WriteHeader(int)             {}
+func (f *flushFailWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (f *flushFailWriter) Flush()                      { _ = f.FlushError() }
+
+func (f *flushFailWriter) FlushError() error {
+	f.flushes++
+	if f.flushes > f.failAfter {
+		return os.ErrDeadlineExceeded
+	}
+	return nil
+}
+
+// TestConn_FlushError tests that a failed flush closes the connection and
+// reports ErrConnectionClosed.
+func TestConn_FlushError(t *testing.T) {
+	// Upgrade flushes the connection comment, then the first send succeeds
+	w := &flushFailWriter{header: make(http.Header), failAfter: 2}
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+
+	if err := conn.SendData("first"); err != nil {
+		t.Fatalf("first SendData() error = %v", err)
+	}
+
+	err = conn.SendData("second")
+	if !errors.Is(err, ErrConnectionClosed) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("second SendData() error = %v, want ErrConnectionClosed wrapping the flush error", err)
+	}
+
+	select {
+	case <-conn.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done channel not closed after flush error")
+	}
+	if reason := conn.CloseReason(); reason != ClientDisconnected {
+		t.Errorf("CloseReason() = %v, want ClientDisconnected", reason)
+	}
+
+	if err := conn.SendData("third"); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("SendData() after flush error = %v, want ErrConnectionClosed", err)
+	}
+	if w.flushes != 3 {
+		t.Errorf("flushes = %d, want 3 (no write after the failure)", w.flushes)
+	}
+}
+
 // TestConn_Close_MultipleCalls tests that Close is idempotent.
+// slowFlushWriter is a ResponseWriter whose flushes block once slow is
+// set, until a write deadline in the past aborts them with
+// os.ErrDeadlineExceeded, like a net/http connection to a stalled client.
+type slowFlushWriter struct {
+	header  http.Header
+	slow    atomic.Bool
+	expired chan struct{}
+	once    sync.Once
+}
+
+func (s *slowFlushWriter) Header() http.Header         { return s.header }
+func (s *slowFlushWriter) WriteHeader(int)             {}
+func (s *slowFlushWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (s *slowFlushWriter) Flush()                      { _ = s.FlushError() }
+
+func (s *slowFlushWriter) FlushError() error {
+	if !s.slow.Load() {
+		return nil
+	}
+	<-s.expired
+	return os.ErrDeadlineExceeded
+}
+
+func (s *slowFlushWriter) SetWriteDeadline(t time.Time) error {
+	if !t.IsZero() && !t.After(time.Now()) {
+		s.once.Do(func() { close(s.expired) })
+	}
+	return nil
+}
+
+// TestConn_SendContext tests that canceling the context aborts a stuck
+// flush promptly and closes the connection.
+func TestConn_SendContext(t *testing.T) {
+	w := &slowFlushWriter{header: make(http.Header), expired: make(chan struct{})}
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+
+	if err := conn.SendContext(context.Background(), NewEvent("fast")); err != nil {
+		t.Fatalf("SendContext() error = %v", err)
+	}
+
+	w.slow.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	errc := make(chan error, 1)
+	go func() { errc <- conn.SendContext(ctx, NewEvent("stuck")) }()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("SendContext() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendContext() did not return after the context was canceled")
+	}
+
+	select {
+	case <-conn.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done channel not closed after canceled send")
+	}
+
+	// A context that's already done sends nothing
+	if err := conn.SendContext(ctx, NewEvent("late")); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendContext() with canceled context = %v, want context.Canceled", err)
+	}
+}
+
 func TestConn_Close_MultipleCalls(t *testing.T) {
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/events", http.NoBody)
@@ -338,6 +813,134 @@ func TestConn_Done(t *testing.T) {
 	}
 }
 
+// TestConn_WriteBufferSize tests sending through a custom write buffer,
+// with events both smaller and larger than the buffer.
+func TestConn_WriteBufferSize(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := UpgradeWithOptions(w, r, &UpgradeOptions{WriteBufferSize: 64})
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	var want strings.Builder
+	want.WriteString(": connected\n\n")
+	for i := 0; i < 200; i++ {
+		data := strconv.Itoa(i)
+		if i%50 == 0 {
+			data = strings.Repeat("x", 100) // Larger than the buffer
+		}
+		if err := conn.SendData(data); err != nil {
+			t.Fatalf("SendData(%d) failed: %v", i, err)
+		}
+		want.WriteString("data: " + data + "\n\n")
+
+		// Every event is flushed as it's sent
+		if got := w.Body.Len(); got != want.Len() {
+			t.Fatalf("after event %d, body has %d bytes, want %d", i, got, want.Len())
+		}
+	}
+
+	if body := w.Body.String(); body != want.String() {
+		t.Errorf("body mismatch:\n got %q\nwant %q", body, want.String())
+	}
+}
+
+// TestConn_Context tests that the connection's context carries the
+// upgrade context's values and is canceled by Close.
+func TestConn_Context(t *testing.T) {
+	type ctxKey struct{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	conn, err := UpgradeWithContext(ctx, w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+
+	connCtx := conn.Context()
+	if got := connCtx.Value(ctxKey{}); got != "value" {
+		t.Errorf("Context().Value() = %v, want %q", got, "value")
+	}
+	if err := connCtx.Err(); err != nil {
+		t.Fatalf("Context().Err() = %v before Close, want nil", err)
+	}
+
+	conn.Close()
+
+	select {
+	case <-connCtx.Done():
+		if !errors.Is(connCtx.Err(), context.Canceled) {
+			t.Errorf("Context().Err() = %v, want context.Canceled", connCtx.Err())
+		}
+	case <-time.After(time.Second):
+		t.Error("Context() not canceled after Close")
+	}
+}
+
+// TestConn_CloseReason tests that each way of closing records its reason.
+func TestConn_CloseReason(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(conn *Conn, cancelRequest, cancelCtx context.CancelFunc)
+		want  CloseReason
+	}{
+		{
+			name:  "server closed",
+			close: func(conn *Conn, _, _ context.CancelFunc) { _ = conn.Close() },
+			want:  ServerClosed,
+		},
+		{
+			name:  "client disconnected",
+			close: func(_ *Conn, cancelRequest, _ context.CancelFunc) { cancelRequest() },
+			want:  ClientDisconnected,
+		},
+		{
+			name:  "context canceled",
+			close: func(_ *Conn, _, cancelCtx context.CancelFunc) { cancelCtx() },
+			want:  ContextCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqCtx, cancelRequest := context.WithCancel(context.Background())
+			defer cancelRequest()
+			ctx, cancelCtx := context.WithCancel(reqCtx)
+			defer cancelCtx()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/events", http.NoBody).WithContext(reqCtx)
+
+			conn, err := UpgradeWithContext(ctx, w, r)
+			if err != nil {
+				t.Fatalf("UpgradeWithContext failed: %v", err)
+			}
+			if got := conn.CloseReason(); got != NotClosed {
+				t.Errorf("CloseReason() before close = %v, want %v", got, NotClosed)
+			}
+
+			tt.close(conn, cancelRequest, cancelCtx)
+
+			select {
+			case <-conn.Done():
+			case <-time.After(time.Second):
+				t.Fatal("Done channel not closed")
+			}
+
+			// Later closes don't change the reason
+			_ = conn.Close()
+			if got := conn.CloseReason(); got != tt.want {
+				t.Errorf("CloseReason() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestConn_ContextCancellation tests that context cancellation closes connection.
 func TestConn_ContextCancellation(t *testing.T) {
 	w := httptest.NewRecorder()
@@ -420,6 +1023,117 @@ func TestConn_MultipleEvents(t *testing.T) {
 	}
 }
 
+// TestConn_EnableAutoID tests that events without an ID get increasing IDs.
+func TestConn_EnableAutoID(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	conn.EnableAutoID()
+
+	event := NewEvent("first")
+	if err := conn.Send(event); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := conn.SendData("second"); err != nil {
+		t.Fatalf("SendData failed: %v", err)
+	}
+	if err := conn.Send(NewEvent("custom").WithID("evt-x")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := conn.SendBytes([]byte("third")); err != nil {
+		t.Fatalf("SendBytes failed: %v", err)
+	}
+
+	body := w.Body.String()
+	want := "id: 1\ndata: first\n\n" +
+		"id: 2\ndata: second\n\n" +
+		"id: evt-x\ndata: custom\n\n" +
+		"id: 3\ndata: third\n\n"
+	if !strings.HasSuffix(body, want) {
+		t.Errorf("expected auto IDs 1/2/3, got: %q", body)
+	}
+
+	if event.ID != "" {
+		t.Errorf("caller's event ID = %q, want unchanged", event.ID)
+	}
+}
+
+// TestConn_SendData_MultiLine tests that multi-line data is sent as repeated data lines.
+func TestConn_SendData_MultiLine(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.SendData("line1\nline2")
+	if err != nil {
+		t.Errorf("SendData failed: %v", err)
+	}
+
+	body := w.Body.String()
+	if !strings.HasSuffix(body, "data: line1\ndata: line2\n\n") {
+		t.Errorf("expected two data lines, got: %q", body)
+	}
+}
+
+// TestConn_SendBytes tests sending a raw byte payload as data lines.
+func TestConn_SendBytes(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.SendBytes([]byte("line1\nline2"))
+	if err != nil {
+		t.Errorf("SendBytes failed: %v", err)
+	}
+
+	body := w.Body.String()
+	if !strings.HasSuffix(body, "data: line1\ndata: line2\n\n") {
+		t.Errorf("expected two data lines, got: %q", body)
+	}
+
+	conn.Close()
+	if err := conn.SendBytes([]byte("x")); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("SendBytes after Close = %v, want ErrConnectionClosed", err)
+	}
+}
+
+// TestConn_SendBinary tests sending bytes as a base64 binary event.
+func TestConn_SendBinary(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SendBinary([]byte{0xDE, 0xAD, 0xBE, 0xEF}); err != nil {
+		t.Fatalf("SendBinary failed: %v", err)
+	}
+
+	body := w.Body.String()
+	if !strings.HasSuffix(body, "event: binary\ndata: 3q2+7w==\n\n") {
+		t.Errorf("expected base64 binary event, got: %q", body)
+	}
+}
+
 // TestConn_EmptyEvent tests sending empty data.
 func TestConn_EmptyEvent(t *testing.T) {
 	w := httptest.NewRecorder()
@@ -496,6 +1210,263 @@ func TestConn_ThreadSafety(t *testing.T) {
 	}
 }
 
+// TestConn_SendComment tests sending a comment line.
+func TestConn_SendComment(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.SendComment("debug info")
+	if err != nil {
+		t.Errorf("SendComment failed: %v", err)
+	}
+
+	body := w.Body.String()
+	if !strings.HasSuffix(body, ": debug info\n\n") {
+		t.Errorf("body missing comment, got: %q", body)
+	}
+}
+
+// TestConn_SendComment_Newline tests that newlines in a comment can't inject event fields.
+func TestConn_SendComment_Newline(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SendComment("x\ndata: evil"); err != nil {
+		t.Errorf("SendComment failed: %v", err)
+	}
+
+	body := w.Body.String()
+	for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		if line != "" && !strings.HasPrefix(line, ":") {
+			t.Errorf("non-comment line %q in body: %q", line, body)
+		}
+	}
+}
+
+// TestConn_SendComment_Closed tests SendComment on closed connection.
+func TestConn_SendComment_Closed(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+
+	conn.Close()
+
+	err = conn.SendComment("debug info")
+	if !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("expected ErrConnectionClosed, got: %v", err)
+	}
+}
+
+// TestConn_StartKeepAlive tests that keep-alive comments are sent at the configured cadence.
+func TestConn_StartKeepAlive(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+
+	conn.StartKeepAlive(20 * time.Millisecond)
+
+	// Send concurrently with keep-alives to exercise write serialization
+	for i := 0; i < 5; i++ {
+		if err := conn.SendData("tick"); err != nil {
+			t.Errorf("SendData failed: %v", err)
+		}
+		time.Sleep(22 * time.Millisecond)
+	}
+
+	// Close before inspecting the body so no writes race with the read
+	conn.Close()
+
+	// Only a lower bound: a loaded scheduler may delay ticks, never add them
+	body := w.Body.String()
+	if count := strings.Count(body, "\n:\n\n"); count < 2 {
+		t.Errorf("expected at least 2 keep-alive comments in 110ms at 20ms interval, found %d", count)
+	}
+	if got := strings.Count(body, "data: tick\n"); got != 5 {
+		t.Errorf("expected 5 events, found %d", got)
+	}
+}
+
+// TestConn_StartKeepAlive_Replace tests that a second call replaces the running keep-alive.
+func TestConn_StartKeepAlive_Replace(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	conn.StartKeepAlive(time.Hour)
+	conn.mu.Lock()
+	first := conn.stopKeepAlive
+	conn.mu.Unlock()
+
+	conn.StartKeepAlive(time.Hour)
+
+	select {
+	case <-first:
+		// Expected: first keep-alive was stopped
+	default:
+		t.Error("first keep-alive still running after second StartKeepAlive")
+	}
+}
+
+// TestConn_StartKeepAlive_StopsOnClose tests that no keep-alives are written after Close.
+func TestConn_StartKeepAlive_StopsOnClose(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+
+	conn.StartKeepAlive(10 * time.Millisecond)
+	conn.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if body := w.Body.String(); body != ": connected\n\n" {
+		t.Errorf("expected no keep-alives after Close, got: %q", body)
+	}
+}
+
+// TestConn_StartKeepAlive_Comment tests a custom keep-alive comment.
+func TestConn_StartKeepAlive_Comment(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := UpgradeWithOptions(w, r, &UpgradeOptions{
+		KeepAliveComment: "ping",
+		KeepAliveJitter:  5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+
+	conn.StartKeepAlive(10 * time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	conn.Close()
+
+	body := w.Body.String()
+	if count := strings.Count(body, ": ping\n\n"); count < 2 {
+		t.Errorf("expected at least 2 %q comments, found %d in %q", ": ping\n\n", count, body)
+	}
+	if strings.Contains(body, "\n:\n\n") {
+		t.Errorf("default keep-alive sent despite KeepAliveComment: %q", body)
+	}
+}
+
+// TestKeepAliveDelay tests that jittered intervals vary within the band.
+func TestKeepAliveDelay(t *testing.T) {
+	const interval, jitter = time.Second, 100 * time.Millisecond
+
+	if got := keepAliveDelay(interval, 0); got != interval {
+		t.Errorf("keepAliveDelay() without jitter = %v, want %v", got, interval)
+	}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		d := keepAliveDelay(interval, jitter)
+		if d < interval || d >= interval+jitter {
+			t.Fatalf("keepAliveDelay() = %v, want in [%v, %v)", d, interval, interval+jitter)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Error("keepAliveDelay() returned the same delay every time, want jitter")
+	}
+}
+
+// TestConn_SendNoFlush tests that queued events are written only on Flush.
+func TestConn_SendNoFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	for _, data := range []string{"a", "b"} {
+		if err := conn.SendNoFlush(NewEvent(data)); err != nil {
+			t.Fatalf("SendNoFlush failed: %v", err)
+		}
+	}
+	if body := w.Body.String(); body != ": connected\n\n" {
+		t.Fatalf("expected nothing written before Flush, got: %q", body)
+	}
+
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := conn.Flush(); err != nil {
+		t.Errorf("Flush with nothing queued failed: %v", err)
+	}
+
+	// Send writes queued events first, keeping them in order
+	_ = conn.SendNoFlush(NewEvent("c"))
+	_ = conn.SendData("d")
+
+	want := ": connected\n\ndata: a\n\ndata: b\n\ndata: c\n\ndata: d\n\n"
+	if body := w.Body.String(); body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	conn.Close()
+	if err := conn.SendNoFlush(NewEvent("x")); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("SendNoFlush after Close = %v, want ErrConnectionClosed", err)
+	}
+	if err := conn.Flush(); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Flush after Close = %v, want ErrConnectionClosed", err)
+	}
+}
+
+// TestConn_StartAutoFlush tests that queued events are flushed on the interval.
+func TestConn_StartAutoFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+
+	conn.StartAutoFlush(10 * time.Millisecond)
+	_ = conn.SendNoFlush(NewEvent("queued"))
+
+	time.Sleep(50 * time.Millisecond)
+
+	// Close before inspecting the body so no writes race with the read
+	conn.Close()
+
+	if body := w.Body.String(); !strings.HasSuffix(body, "data: queued\n\n") {
+		t.Errorf("expected queued event to be auto-flushed, got: %q", body)
+	}
+}
+
 // BenchmarkConn_Send benchmarks sending events.
 func BenchmarkConn_Send(b *testing.B) {
 	w := httptest.NewRecorder()
@@ -536,6 +1507,48 @@ func BenchmarkConn_SendData(b *testing.B) {
 	}
 }
 
+func BenchmarkConn_SendData_1KB(b *testing.B) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		b.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	data := strings.Repeat("x", 1024)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		w.Body.Reset()
+		conn.SendData(data)
+	}
+}
+
+func BenchmarkConn_SendBytes_1KB(b *testing.B) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		b.Fatalf("Upgrade failed: %v", err)
+	}
+	defer conn.Close()
+
+	data := bytes.Repeat([]byte("x"), 1024)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		w.Body.Reset()
+		conn.SendBytes(data)
+	}
+}
+
 // BenchmarkConn_SendJSON benchmarks sending JSON events.
 func BenchmarkConn_SendJSON(b *testing.B) {
 	w := httptest.NewRecorder()
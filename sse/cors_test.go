@@ -0,0 +1,84 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWithCORS tests that WithCORS answers preflights itself and adds the
+// CORS headers to the event stream.
+func TestWithCORS(t *testing.T) {
+	const origin = "https://app.example.com"
+
+	var calls int
+	handler := WithCORS(origin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = conn.SendData("hello")
+		conn.Close()
+	}))
+
+	// Preflight
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodOptions, "/events", http.NoBody)
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	r.Header.Set("Access-Control-Request-Headers", "authorization")
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":      origin,
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, OPTIONS",
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Errorf("preflight %s = %q, want %q", name, got, want)
+		}
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") {
+		t.Errorf("preflight Access-Control-Allow-Headers = %q, want Authorization", got)
+	}
+	if calls != 0 {
+		t.Errorf("handler called %d times for preflight, want 0", calls)
+	}
+
+	// Event stream
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/events", http.NoBody)
+	r.Header.Set("Origin", origin)
+	handler.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != origin {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, origin)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want %q", got, "text/event-stream")
+	}
+	if !strings.Contains(w.Body.String(), "data: hello\n\n") {
+		t.Errorf("body = %q, want event", w.Body.String())
+	}
+}
+
+// TestWithCORS_Wildcard tests that "*" doesn't allow credentials.
+func TestWithCORS_Wildcard(t *testing.T) {
+	handler := WithCORS("*")(http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", http.NoBody))
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "*")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+}
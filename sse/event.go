@@ -5,7 +5,8 @@
 package sse
 
 import (
-	"fmt"
+	"encoding/base64"
+	"strconv"
 	"strings"
 )
 
@@ -62,6 +63,21 @@ func NewEvent(data string) *Event {
 	return &Event{Data: data}
 }
 
+// NewEventLines creates a new Event whose data is lines joined with "\n".
+//
+// Each line is serialized as its own "data:" field, and clients rejoin
+// them with newlines, so NewEventLines("a", "b") is received as "a\nb".
+// Line breaks inside a line also start a new field.
+//
+// Example:
+//
+//	event := sse.NewEventLines("CPU: 42%", "Memory: 1.2GB")
+//	// data: CPU: 42%
+//	// data: Memory: 1.2GB
+func NewEventLines(lines ...string) *Event {
+	return &Event{Data: strings.Join(lines, "\n")}
+}
+
 // WithType sets the event type.
 //
 // Example:
@@ -84,6 +100,32 @@ func (e *Event) WithID(id string) *Event {
 	return e
 }
 
+// BinaryEventType is the event type set by WithBinary, marking data as
+// base64-encoded bytes.
+const BinaryEventType = "binary"
+
+// WithBinary sets the data to b, base64-encoded (standard encoding with
+// padding), and the type to BinaryEventType.
+//
+// SSE carries text only, so binary payloads must be encoded. Clients
+// listen for the "binary" event and decode the data:
+//
+//	source.addEventListener("binary", (e) => {
+//	    const bytes = Uint8Array.from(atob(e.data), (c) => c.charCodeAt(0));
+//	});
+//
+// Base64 grows the payload by a third; it suits small blobs. Calling
+// WithType afterwards replaces the marker type.
+//
+// Example:
+//
+//	event := sse.NewEvent("").WithBinary(thumbnail).WithID("img-7")
+func (e *Event) WithBinary(b []byte) *Event {
+	e.Type = BinaryEventType
+	e.Data = base64.StdEncoding.EncodeToString(b)
+	return e
+}
+
 // WithRetry sets the reconnection retry time in milliseconds.
 //
 // Example:
@@ -94,11 +136,31 @@ func (e *Event) WithRetry(ms int) *Event {
 	return e
 }
 
+// Clone returns a copy of the Event that shares no state with e.
+//
+// The builder methods modify the Event in place, so an event retained
+// after it's sent, e.g. in a replay buffer, should be stored as a clone;
+// later changes to the original would otherwise rewrite history. Clones
+// compare equal with *a == *b.
+//
+// Example:
+//
+//	history = append(history, event.Clone())
+//	event.WithID("msg-2") // history is unchanged
+func (e *Event) Clone() *Event {
+	clone := *e
+	return &clone
+}
+
 // String serializes the Event to SSE text/event-stream format.
 //
 // The format follows the SSE specification:
 //   - Each field starts with field name + colon + space
 //   - Multi-line data becomes multiple "data:" lines
+//   - CRLF and CR are treated as line breaks, same as LF
+//   - CR and LF are stripped from the type and ID, which must be single lines
+//   - A trailing newline becomes a final empty "data:" line, so clients
+//     reconstruct the original payload exactly
 //   - Message ends with double newline (\n\n)
 //
 // Example:
@@ -111,41 +173,93 @@ func (e *Event) WithRetry(ms int) *Event {
 //	// data: line2
 //	//
 func (e *Event) String() string {
-	var b strings.Builder
+	return string(e.Bytes())
+}
 
+// Bytes serializes the Event to SSE text/event-stream format.
+//
+// The output is the same as String: the event:, id:, retry: and data:
+// fields, terminated by a blank line. It is exactly what Conn.Send writes,
+// so events can be pre-rendered for caching or checked in tests.
+//
+// Example:
+//
+//	payload := sse.NewEvent("hello").WithID("1").Bytes()
+//	// payload == []byte("id: 1\ndata: hello\n\n")
+func (e *Event) Bytes() []byte {
+	return e.appendTo(make([]byte, 0, e.encodedLen()))
+}
+
+// appendTo appends the serialized event to dst and returns the result.
+func (e *Event) appendTo(dst []byte) []byte {
 	// Event type (optional)
 	if e.Type != "" {
-		b.WriteString("event: ")
-		b.WriteString(e.Type)
-		b.WriteByte('\n')
+		dst = appendField(dst, "event: ", e.Type)
 	}
 
 	// Event ID (optional)
 	if e.ID != "" {
-		b.WriteString("id: ")
-		b.WriteString(e.ID)
-		b.WriteByte('\n')
+		dst = appendField(dst, "id: ", e.ID)
 	}
 
 	// Retry (optional)
 	if e.Retry > 0 {
-		b.WriteString("retry: ")
-		b.WriteString(fmt.Sprintf("%d", e.Retry))
-		b.WriteByte('\n')
+		dst = append(dst, "retry: "...)
+		dst = strconv.AppendInt(dst, int64(e.Retry), 10)
+		dst = append(dst, '\n')
 	}
 
 	// Data (required) - handle multi-line
-	lines := strings.Split(e.Data, "\n")
-	for _, line := range lines {
-		b.WriteString("data: ")
-		b.WriteString(line)
-		b.WriteByte('\n')
-	}
+	dst = appendLines(dst, "data: ", e.Data)
 
 	// End with double newline
-	b.WriteByte('\n')
+	return append(dst, '\n')
+}
 
-	return b.String()
+// encodedLen estimates the serialized size of e, for preallocation.
+func (e *Event) encodedLen() int {
+	return len(e.Type) + len(e.ID) + len(e.Data) + 32
+}
+
+// appendField appends a single-line field (event type or ID).
+//
+// CR and LF can't appear in these fields: the client would end the field
+// there and parse the rest as another field. They're stripped, so a value
+// like "a\nretry: 0" can't inject fields into the stream.
+func appendField(dst []byte, prefix, value string) []byte {
+	dst = append(dst, prefix...)
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c != '\r' && c != '\n' {
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, '\n')
+}
+
+// appendLines appends prefix+line+"\n" for each line of data.
+//
+// Lines are split on any SSE line terminator (CRLF, LF, or CR). The SSE
+// spec treats all three as line endings, so an embedded CR must start a
+// new line or it would terminate the field early on the client.
+func appendLines[S string | []byte](dst []byte, prefix string, data S) []byte {
+	for {
+		i := 0
+		for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+			i++
+		}
+
+		dst = append(dst, prefix...)
+		dst = append(dst, data[:i]...)
+		dst = append(dst, '\n')
+
+		if i == len(data) {
+			return dst
+		}
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+		data = data[i+1:]
+	}
 }
 
 // Comment creates an SSE comment for keep-alive or debugging.
@@ -153,6 +267,9 @@ func (e *Event) String() string {
 // Comments start with colon (:) and are ignored by clients.
 // They're commonly used to keep the connection alive and prevent timeouts.
 //
+// Multi-line text becomes multiple comment lines, so embedded newlines
+// can't inject event fields.
+//
 // Example:
 //
 //	keepAlive := sse.Comment("keep-alive")
@@ -161,5 +278,6 @@ func (e *Event) String() string {
 //	// : keep-alive
 //	//
 func Comment(text string) string {
-	return ": " + text + "\n\n"
+	dst := appendLines(make([]byte, 0, len(text)+4), ": ", text)
+	return string(append(dst, '\n'))
 }
@@ -1,6 +1,9 @@
 package sse
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
 	"strings"
 	"testing"
 )
@@ -23,6 +26,105 @@ func TestEvent_String_MultiLine(t *testing.T) {
 	}
 }
 
+// TestEvent_String_TrailingNewline tests that a trailing newline round-trips.
+func TestEvent_String_TrailingNewline(t *testing.T) {
+	event := NewEvent("line1\n")
+	expected := "data: line1\ndata: \n\n"
+	if got := event.String(); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+// TestNewEventLines tests that each line becomes a data field and is
+// rejoined by clients.
+func TestNewEventLines(t *testing.T) {
+	event := NewEventLines("a", "b")
+	expected := "data: a\ndata: b\n\n"
+	if got := event.String(); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(event.String()))
+	scanner.Split(scanLines)
+
+	var p parser
+	var got []Event
+	for scanner.Scan() {
+		if parsed, ok := p.parseLine(scanner.Text()); ok {
+			got = append(got, parsed)
+		}
+	}
+	if len(got) != 1 || got[0].Data != "a\nb" {
+		t.Errorf("client received %+v, want one event with data %q", got, "a\nb")
+	}
+}
+
+// TestEvent_String_CarriageReturns tests that CRLF and CR are split like LF.
+func TestEvent_String_CarriageReturns(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"CRLF", "line1\r\nline2"},
+		{"CR", "line1\rline2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := "data: line1\ndata: line2\n\n"
+			if got := NewEvent(tt.data).String(); got != expected {
+				t.Errorf("got %q, want %q", got, expected)
+			}
+		})
+	}
+}
+
+// TestEvent_String_FieldNewlines tests that newlines in the type and ID are
+// stripped instead of injecting fields.
+func TestEvent_String_FieldNewlines(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *Event
+		want  string
+	}{
+		{"ID LF", NewEvent("x").WithID("a\nb"), "id: ab\ndata: x\n\n"},
+		{"ID CRLF", NewEvent("x").WithID("a\r\nb"), "id: ab\ndata: x\n\n"},
+		{"ID injection", NewEvent("x").WithID("1\nretry: 0"), "id: 1retry: 0\ndata: x\n\n"},
+		{"Type CR", NewEvent("x").WithType("news\rupdate"), "event: newsupdate\ndata: x\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEvent_WithBinary tests that binary data round-trips through base64.
+func TestEvent_WithBinary(t *testing.T) {
+	payload := []byte{0x00, 0xFF, '\n', '\r', 0x80, 'h', 'i'}
+	result := NewEvent("").WithBinary(payload).String()
+
+	if !strings.HasPrefix(result, "event: binary\n") {
+		t.Errorf("missing binary marker, got %q", result)
+	}
+
+	// A client sees a single data line holding the encoded bytes
+	lines := strings.Split(strings.TrimSuffix(result, "\n\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "data: ") {
+		t.Fatalf("expected type and one data line, got %q", result)
+	}
+	got, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(lines[1], "data: "))
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("decoded %v, want %v", got, payload)
+	}
+}
+
 // TestEvent_String_AllFields tests serialization with all fields populated.
 func TestEvent_String_AllFields(t *testing.T) {
 	event := NewEvent("test data").
@@ -51,6 +153,22 @@ func TestEvent_String_AllFields(t *testing.T) {
 	}
 }
 
+// TestEvent_Bytes tests the wire encoding of an event with all fields.
+func TestEvent_Bytes(t *testing.T) {
+	event := NewEvent("line1\nline2").
+		WithType("update").
+		WithID("42").
+		WithRetry(1500)
+
+	expected := "event: update\nid: 42\nretry: 1500\ndata: line1\ndata: line2\n\n"
+	if got := string(event.Bytes()); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+	if got := event.String(); got != expected {
+		t.Errorf("String() = %q, want Bytes() output %q", got, expected)
+	}
+}
+
 // TestEvent_String_TypeOnly tests serialization with type field.
 func TestEvent_String_TypeOnly(t *testing.T) {
 	event := NewEvent("data").WithType("notification")
@@ -171,6 +289,8 @@ func TestComment(t *testing.T) {
 		{"empty", "", ": \n\n"},
 		{"multiword", "connection active", ": connection active\n\n"},
 		{"special chars", "debug: test-123", ": debug: test-123\n\n"},
+		{"multiline", "x\ndata: evil", ": x\n: data: evil\n\n"},
+		{"carriage return", "x\rdata: evil", ": x\n: data: evil\n\n"},
 	}
 
 	for _, tt := range tests {
@@ -219,6 +339,25 @@ func TestEvent_Immutability(t *testing.T) {
 	}
 }
 
+// TestEvent_Clone tests that changing the original doesn't affect a clone.
+func TestEvent_Clone(t *testing.T) {
+	event := NewEvent("original").WithType("update").WithID("1").WithRetry(1000)
+	clone := event.Clone()
+
+	if clone == event {
+		t.Fatal("Clone() returned the same event")
+	}
+	if *clone != *event {
+		t.Errorf("Clone() = %+v, want %+v", *clone, *event)
+	}
+
+	event.WithType("changed").WithID("2").WithRetry(2000).WithBinary([]byte{1, 2})
+	want := Event{Type: "update", ID: "1", Data: "original", Retry: 1000}
+	if *clone != want {
+		t.Errorf("clone after changing original = %+v, want %+v", *clone, want)
+	}
+}
+
 // BenchmarkEvent_String benchmarks event serialization.
 func BenchmarkEvent_String(b *testing.B) {
 	event := NewEvent("hello world").WithType("message").WithID("123")
@@ -1,18 +1,110 @@
 package sse
 
 import (
+	"encoding"
 	"encoding/json/v2"
 	"errors"
 	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coregx/stream/metrics"
 )
 
 // Common errors returned by Hub.
 var (
 	// ErrHubClosed is returned when attempting to use a closed hub.
 	ErrHubClosed = errors.New("sse: hub closed")
+
+	// ErrClientNotFound is returned by SendTo when no client has the given ID.
+	ErrClientNotFound = errors.New("sse: client not found")
+
+	// ErrSlowClient is returned by SendTo when the client's send buffer
+	// stays full and the event is dropped.
+	ErrSlowClient = errors.New("sse: client send buffer full")
+
+	// ErrHubBusy is returned by Broadcast, BroadcastTopic, and Publish when
+	// the hub's broadcast queue is full. The event is not sent.
+	ErrHubBusy = errors.New("sse: hub broadcast queue full")
+
+	// ErrTooManyClients is returned by Register when the hub already has
+	// HubOptions.MaxClients clients.
+	ErrTooManyClients = errors.New("sse: too many clients")
+)
+
+// Default per-client queue settings.
+const (
+	defaultClientBufferSize  = 64
+	defaultSlowClientTimeout = 100 * time.Millisecond
+)
+
+// defaultBroadcastQueueSize is the default depth of the broadcast queue.
+const defaultBroadcastQueueSize = 256
+
+// SlowClientPolicy determines what a Hub does with events for a client
+// that isn't keeping up.
+type SlowClientPolicy int
+
+const (
+	// SlowClientDropEvents drops events for the slow client (default).
+	// The client stays connected and receives later events once it
+	// catches up.
+	SlowClientDropEvents SlowClientPolicy = iota
+
+	// SlowClientDisconnect removes and closes the slow client.
+	// Use this when gaps in the stream are unacceptable; the client can
+	// reconnect with Last-Event-ID to resume (see HubOptions.HistorySize).
+	SlowClientDisconnect
 )
 
+// HubOptions configures Hub behavior.
+//
+// All fields are optional. Zero values use sensible defaults.
+type HubOptions struct {
+	// HistorySize is the number of recent broadcast events retained for
+	// Last-Event-ID replay (default: 0, disabled). See NewHubWithHistory.
+	HistorySize int
+
+	// ClientBufferSize is the number of events queued per client
+	// (default: 64).
+	ClientBufferSize int
+
+	// BroadcastQueueSize is the number of broadcasts queued for the Run
+	// loop (default: 256). When the queue is full, Broadcast returns
+	// ErrHubBusy instead of blocking the caller.
+	BroadcastQueueSize int
+
+	// MaxClients limits the number of registered clients (default: 0,
+	// unlimited). Register returns ErrTooManyClients once it's reached.
+	MaxClients int
+
+	// SlowClientTimeout is how long to wait for room in a full client
+	// queue before applying SlowClientPolicy (default: 100ms).
+	SlowClientTimeout time.Duration
+
+	// SlowClientPolicy is applied when a client's queue stays full
+	// (default: SlowClientDropEvents).
+	SlowClientPolicy SlowClientPolicy
+
+	// Metrics receives connection, broadcast, and write events, reported
+	// with transport metrics.TransportSSE (default: none).
+	Metrics metrics.MetricsSink
+
+	// Logger receives events the hub handles on its own: clients dropped
+	// after a failed write, events dropped for slow clients, and
+	// broadcasts that fail to encode (default: discard).
+	Logger Logger
+
+	// CoalesceInterval is the window over which BroadcastKeyed coalesces
+	// events with the same key, sending only the latest (default: 0,
+	// BroadcastKeyed sends every event like Broadcast).
+	CoalesceInterval time.Duration
+}
+
 // Hub manages broadcasting events to multiple SSE connections.
 //
 // Hub[T] is a generic type that manages a pool of SSE connections and enables
@@ -34,15 +126,47 @@ var (
 //
 // The Hub uses channels for thread-safe coordination and a select loop in Run()
 // to handle concurrent registration, unregistration, and broadcasting operations.
+//
+// Each client has its own buffered queue drained by a dedicated goroutine,
+// so a slow client doesn't stall delivery to the others. When a client's
+// queue stays full, the hub applies its SlowClientPolicy.
+//
+// Ordering: each client receives broadcasts in the order they were
+// submitted to the hub (Broadcast, BroadcastTopic, and Publish calls that
+// returned nil, as ordered by the caller). Run hands events to the
+// per-client FIFO queues one at a time, and each queue is written by a
+// single goroutine. A slow client may miss events under
+// SlowClientDropEvents, but never sees them out of order. Replayed history
+// is delivered before any live event. Events from SendTo are queued
+// directly and may land between broadcasts submitted around the same time.
 type Hub[T any] struct {
-	// clients is the set of active connections.
-	clients map[*Conn]bool
+	// clients maps active connections to their hub state.
+	clients map[*Conn]*hubClient
+
+	// ids maps client IDs to clients for directed sends.
+	// Populated at Register time so SendTo works immediately.
+	ids map[uint64]*hubClient
+
+	// pending holds clients whose registration hasn't completed yet
+	// (queued for Run, or catching up on history).
+	pending map[*Conn]*hubClient
+
+	// nextID is the last client ID assigned.
+	nextID uint64
 
-	// broadcast channel receives events to broadcast to all clients.
-	broadcast chan T
+	// broadcast channel receives events to broadcast to all clients
+	// (or to a topic's subscribers).
+	broadcast chan hubMessage[T]
+
+	// topics maps topic names to their subscribed connections.
+	topics map[string]map[*Conn]struct{}
+
+	// subscriptions maps connections to the topics they're subscribed to.
+	// Used to clean up topics when a client is removed.
+	subscriptions map[*Conn]map[string]struct{}
 
 	// register channel receives new client connections.
-	register chan *Conn
+	register chan *hubClient
 
 	// unregister channel receives clients to disconnect.
 	unregister chan *Conn
@@ -50,11 +174,109 @@ type Hub[T any] struct {
 	// done channel signals hub shutdown.
 	done chan struct{}
 
-	// mu protects clients map during read operations.
+	// mu protects clients and ids maps, topics, and history.
 	mu sync.RWMutex
 
 	// closed indicates if the hub is shut down.
 	closed bool
+
+	// started indicates the event loop is running (by Run or lazily).
+	started bool
+
+	// history holds the most recent broadcast events for replay.
+	// Empty if historySize is 0.
+	history []historyEntry
+
+	// historySize is the maximum number of events retained (0 = disabled).
+	historySize int
+
+	// lastID is the ID assigned to the most recent broadcast event.
+	lastID uint64
+
+	// clientBufferSize is the per-client queue length.
+	clientBufferSize int
+
+	// slowClientTimeout is how long enqueue waits on a full queue.
+	slowClientTimeout time.Duration
+
+	// slowClientPolicy is applied when a client's queue stays full.
+	slowClientPolicy SlowClientPolicy
+
+	// maxClients limits registered plus pending clients (0 = unlimited).
+	maxClients int
+
+	// dropped counts events dropped for slow clients.
+	dropped atomic.Uint64
+
+	// broadcasts counts processed broadcasts. Protected by mu.
+	broadcasts uint64
+
+	// bytesWritten counts event bytes written to clients.
+	bytesWritten atomic.Uint64
+
+	// metrics receives hub events (metrics.Discard if unset).
+	metrics metrics.MetricsSink
+
+	// logger receives dropped-client events (nopLogger if unset).
+	logger Logger
+
+	// writers tracks running client writer goroutines.
+	writers sync.WaitGroup
+
+	// onRegister and onUnregister are lifecycle hooks, and
+	// onDeliveryError reports failed deliveries (nil if unset).
+	// Protected by mu.
+	onRegister      func(*Conn)
+	onUnregister    func(*Conn)
+	onDeliveryError func(*Conn, error)
+
+	// Retry policy set by SetRetryPolicy (retryBase 0 = none).
+	// Protected by mu.
+	retryBase   time.Duration
+	retryMax    time.Duration
+	retryFactor float64
+
+	// coalesceInterval is the BroadcastKeyed window (0 = no coalescing).
+	coalesceInterval time.Duration
+
+	// coalesced holds the latest value per key waiting for its window to
+	// end. Protected by coalesceMu.
+	coalesceMu sync.Mutex
+	coalesced  map[string]T
+}
+
+// hubClient is a registered connection with its hub-assigned ID and
+// outgoing event queue.
+type hubClient struct {
+	conn *Conn
+	id   uint64
+	send chan []byte
+
+	// quit is closed when the client is removed from the hub.
+	quit chan struct{}
+
+	// stalled is set when an event was dropped, so further events are
+	// dropped without waiting until the writer makes progress.
+	stalled atomic.Bool
+}
+
+// hubMessage is a queued broadcast. An empty topic targets all clients.
+type hubMessage[T any] struct {
+	topic string
+	data  T
+
+	// filter selects recipients among the targeted clients (nil = all).
+	filter func(*Conn) bool
+
+	// event is a pre-converted event (from Publish and BroadcastJSON).
+	// Used instead of data when non-nil.
+	event *Event
+}
+
+// historyEntry is a broadcast event retained for replay.
+type historyEntry struct {
+	id   uint64
+	data []byte // serialized event
 }
 
 // NewHub creates a new Hub for broadcasting events of type T.
@@ -68,14 +290,105 @@ type Hub[T any] struct {
 //	go hub.Run()
 //	defer hub.Close()
 func NewHub[T any]() *Hub[T] {
-	return &Hub[T]{
-		clients:    make(map[*Conn]bool),
-		broadcast:  make(chan T, 256), // Buffered for burst traffic
-		register:   make(chan *Conn, 16),
+	return NewHubWithOptions[T](nil)
+}
+
+// NewHubWithOptions creates a new Hub configured by opts.
+//
+// A nil opts is equivalent to NewHub. Like NewHub, the returned Hub must be
+// started by calling Run() in a goroutine.
+//
+// Example:
+//
+//	hub := sse.NewHubWithOptions[string](&sse.HubOptions{
+//	    ClientBufferSize: 16,
+//	    SlowClientPolicy: sse.SlowClientDisconnect,
+//	})
+//	go hub.Run()
+//	defer hub.Close()
+func NewHubWithOptions[T any](opts *HubOptions) *Hub[T] {
+	if opts == nil {
+		opts = &HubOptions{}
+	}
+
+	queueSize := opts.BroadcastQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultBroadcastQueueSize
+	}
+
+	h := &Hub[T]{
+		clients:    make(map[*Conn]*hubClient),
+		ids:        make(map[uint64]*hubClient),
+		pending:    make(map[*Conn]*hubClient),
+		broadcast:  make(chan hubMessage[T], queueSize), // Buffered for burst traffic
+		register:   make(chan *hubClient, 16),
 		unregister: make(chan *Conn, 16),
 		done:       make(chan struct{}),
 		closed:     false,
+
+		topics:        make(map[string]map[*Conn]struct{}),
+		subscriptions: make(map[*Conn]map[string]struct{}),
+
+		clientBufferSize:  opts.ClientBufferSize,
+		slowClientTimeout: opts.SlowClientTimeout,
+		slowClientPolicy:  opts.SlowClientPolicy,
+		maxClients:        opts.MaxClients,
+		metrics:           opts.Metrics,
+		logger:            opts.Logger,
+		coalesceInterval:  opts.CoalesceInterval,
+		coalesced:         make(map[string]T),
 	}
+
+	if h.clientBufferSize <= 0 {
+		h.clientBufferSize = defaultClientBufferSize
+	}
+	if h.slowClientTimeout <= 0 {
+		h.slowClientTimeout = defaultSlowClientTimeout
+	}
+	if h.metrics == nil {
+		h.metrics = metrics.Discard
+	}
+	if h.logger == nil {
+		h.logger = nopLogger{}
+	}
+	if opts.HistorySize > 0 {
+		h.historySize = opts.HistorySize
+		h.history = make([]historyEntry, 0, opts.HistorySize)
+	}
+
+	return h
+}
+
+// NewHubWithHistory creates a Hub that retains the last size broadcast events.
+//
+// Every broadcast is assigned a monotonically increasing numeric event ID
+// (starting at 1) that clients echo back via Last-Event-ID on reconnect.
+// When a connection whose LastEventID is set registers, the retained events
+// newer than that ID are replayed before any live broadcasts. Replay runs on
+// the client's writer goroutine, so a slow reconnecting client doesn't
+// stall the hub.
+//
+// IDs are consecutive, so clients can detect gaps from the first ID they
+// receive:
+//   - If LastEventID is older than the oldest retained event, all retained
+//     events are replayed; the events in between are lost.
+//   - If LastEventID is newer than any ID issued (e.g. after a process
+//     restart, since IDs restart at 1), all retained events are replayed
+//     and the client sees IDs lower than its own.
+//
+// A non-positive size returns a Hub without history, same as NewHub.
+//
+// Example:
+//
+//	hub := sse.NewHubWithHistory[string](100)
+//	go hub.Run()
+//	defer hub.Close()
+//
+//	// Reconnecting clients catch up automatically
+//	conn, _ := sse.Upgrade(w, r)
+//	hub.Register(conn)
+func NewHubWithHistory[T any](size int) *Hub[T] {
+	return NewHubWithOptions[T](&HubOptions{HistorySize: size})
 }
 
 // Run starts the hub's event loop.
@@ -83,11 +396,51 @@ func NewHub[T any]() *Hub[T] {
 // Run processes client registration, unregistration, and broadcast operations.
 // It should be called in a goroutine and will block until Close() is called.
 //
+// Register and Unregister start the event loop if it isn't running, so a
+// hub used before its Run goroutine gets scheduled doesn't block. Only one
+// event loop ever runs; a Run call after the loop has started doesn't
+// start another, and just blocks until Close.
+//
 // Example:
 //
 //	hub := sse.NewHub[string]()
 //	go hub.Run()
 func (h *Hub[T]) Run() {
+	if !h.claimLoop() {
+		<-h.done
+		return
+	}
+	h.loop()
+}
+
+// claimLoop marks the event loop as started and reports whether the caller
+// should run it (false if it's already running or the hub is closed).
+func (h *Hub[T]) claimLoop() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed || h.started {
+		return false
+	}
+	h.started = true
+	return true
+}
+
+// open reports whether the hub accepts operations (false after Close),
+// starting the event loop if Run hasn't been called yet.
+func (h *Hub[T]) open() bool {
+	h.mu.RLock()
+	closed, started := h.closed, h.started
+	h.mu.RUnlock()
+
+	if !closed && !started && h.claimLoop() {
+		go h.loop()
+	}
+	return !closed
+}
+
+// loop is the event loop run by Run.
+func (h *Hub[T]) loop() {
 	for {
 		select {
 		case client := <-h.register:
@@ -96,8 +449,8 @@ func (h *Hub[T]) Run() {
 		case client := <-h.unregister:
 			h.handleUnregister(client)
 
-		case data := <-h.broadcast:
-			h.handleBroadcast(data)
+		case msg := <-h.broadcast:
+			h.handleBroadcast(msg)
 
 		case <-h.done:
 			return
@@ -105,70 +458,373 @@ func (h *Hub[T]) Run() {
 	}
 }
 
-// handleRegister adds a new client to the hub.
-func (h *Hub[T]) handleRegister(client *Conn) {
+// handleRegister adds a new client to the hub and starts its writer.
+func (h *Hub[T]) handleRegister(client *hubClient) {
 	h.mu.Lock()
-	h.clients[client] = true
+	if h.pending[client.conn] != client {
+		// Unregistered (or re-registered) before registration completed
+		h.mu.Unlock()
+		return
+	}
+
+	prev, reregistered := h.clients[client.conn]
+	if reregistered {
+		// Re-registration replaces the previous ID and queue
+		delete(h.ids, prev.id)
+		close(prev.quit)
+	}
+	delete(h.pending, client.conn)
+	h.clients[client.conn] = client
+
+	// Missed events are collected under the same lock record uses, so each
+	// event is either replayed or queued as a live broadcast, never both
+	h.writers.Add(1)
+	go h.writeLoop(client, h.missedEvents(client.conn))
+
+	if !reregistered {
+		// Reported under the lock so it precedes the matching disconnect
+		h.metrics.ClientConnected(metrics.TransportSSE)
+	}
+
+	onRegister := h.onRegister
 	h.mu.Unlock()
+
+	if onRegister != nil && !reregistered {
+		onRegister(client.conn)
+	}
 }
 
-// handleUnregister removes a client from the hub.
-func (h *Hub[T]) handleUnregister(client *Conn) {
-	h.mu.Lock()
-	if _, ok := h.clients[client]; ok {
-		delete(h.clients, client)
-		_ = client.Close()
+// missedEvents returns retained events newer than the client's LastEventID.
+// Caller must hold h.mu.
+func (h *Hub[T]) missedEvents(client *Conn) [][]byte {
+	if h.historySize == 0 || client.LastEventID() == "" {
+		return nil
+	}
+
+	lastID, err := strconv.ParseUint(client.LastEventID(), 10, 64)
+	if err != nil {
+		// Not an ID issued by this hub
+		return nil
+	}
+	if lastID > h.lastID {
+		// ID from a previous hub instance (e.g. process restart)
+		lastID = 0
+	}
+
+	var events [][]byte
+	for _, entry := range h.history {
+		if entry.id > lastID {
+			events = append(events, entry.data)
+		}
+	}
+	return events
+}
+
+// writeLoop delivers a single client's events.
+//
+// Replayed events are sent first, before anything queued by live broadcasts.
+// The loop exits when the client is removed or the hub closes. A failed
+// send removes the client from the hub.
+//
+// Events are serialized once by the hub and written to each client as-is.
+func (h *Hub[T]) writeLoop(client *hubClient, replay [][]byte) {
+	defer h.writers.Done()
+
+	for _, event := range replay {
+		select {
+		case <-client.quit:
+			return
+		case <-h.done:
+			return
+		default:
+		}
+		if err := client.conn.sendEncoded(event); err != nil {
+			// Writes aborted by Unregister or Close aren't failures
+			if h.removeClient(client) {
+				h.deliveryFailed(client, err)
+			}
+			return
+		}
+		h.wrote(len(event))
+	}
+
+	for {
+		select {
+		case event := <-client.send:
+			if err := client.conn.sendEncoded(event); err != nil {
+				if h.removeClient(client) {
+					h.deliveryFailed(client, err)
+				}
+				return
+			}
+			h.wrote(len(event))
+			client.stalled.Store(false)
+		case <-client.quit:
+			return
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// deliveryFailed logs a failed delivery to client and reports it to the
+// OnDeliveryError hook, if any.
+func (h *Hub[T]) deliveryFailed(client *hubClient, err error) {
+	if errors.Is(err, ErrSlowClient) {
+		h.logger.Warn("sse: hub dropped event for slow client", "client", client.conn.ClientIP())
+	} else {
+		h.logger.Warn("sse: hub dropped client after failed write", "client", client.conn.ClientIP(), "err", err)
+	}
+
+	h.mu.RLock()
+	fn := h.onDeliveryError
+	h.mu.RUnlock()
+
+	if fn != nil {
+		fn(client.conn, err)
 	}
-	h.mu.Unlock()
 }
 
-// handleBroadcast sends data to all connected clients.
-func (h *Hub[T]) handleBroadcast(data T) {
-	// Get snapshot of clients
+// wrote counts n event bytes written to a client.
+func (h *Hub[T]) wrote(n int) {
+	h.bytesWritten.Add(uint64(n))
+	h.metrics.BytesWritten(metrics.TransportSSE, n)
+}
+
+// enqueue queues event for client.
+//
+// If the client's queue is full, enqueue waits up to slowClientTimeout for
+// the writer to make room; a client that still can't keep up has the event
+// dropped and the slow-client policy applied. Once a client has dropped an
+// event, further events are dropped immediately until its writer completes
+// a send, so a stuck client costs the hub at most one timeout.
+func (h *Hub[T]) enqueue(client *hubClient, event []byte) error {
+	select {
+	case client.send <- event:
+		return nil
+	default:
+	}
+
+	if !client.stalled.Load() {
+		timer := time.NewTimer(h.slowClientTimeout)
+		defer timer.Stop()
+
+		select {
+		case client.send <- event:
+			return nil
+		case <-timer.C:
+		case <-client.quit:
+			return ErrClientNotFound
+		case <-h.done:
+			return ErrHubClosed
+		}
+	}
+
+	client.stalled.Store(true)
+	h.dropped.Add(1)
+	h.metrics.MessageDropped(metrics.TransportSSE)
+	if h.slowClientPolicy == SlowClientDisconnect {
+		h.removeClient(client)
+	}
+	h.deliveryFailed(client, ErrSlowClient)
+	return ErrSlowClient
+}
+
+// record assigns the next event ID, retains the event in history, and
+// returns it serialized. Caller must hold h.mu.
+func (h *Hub[T]) record(event *Event) []byte {
+	h.lastID++
+	event.ID = strconv.FormatUint(h.lastID, 10)
+	data := event.Bytes()
+
+	if len(h.history) == h.historySize {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:len(h.history)-1]
+	}
+	h.history = append(h.history, historyEntry{id: h.lastID, data: data})
+	return data
+}
+
+// handleUnregister removes a client from the hub.
+func (h *Hub[T]) handleUnregister(conn *Conn) {
 	h.mu.RLock()
-	clients := make([]*Conn, 0, len(h.clients))
-	for client := range h.clients {
-		clients = append(clients, client)
+	client, ok := h.clients[conn]
+	if !ok {
+		// Cancels a registration still queued
+		client, ok = h.pending[conn]
 	}
 	h.mu.RUnlock()
 
-	// Convert data to string
-	dataStr := h.convertToString(data)
-	if dataStr == "" {
-		return
+	if ok {
+		h.removeClient(client)
 	}
+}
 
-	// Send to all clients (outside lock to avoid blocking)
-	for _, client := range clients {
-		if err := client.SendData(dataStr); err != nil {
-			h.removeClient(client)
+// handleBroadcast sends data to all connected clients, or to the
+// subscribers of msg.topic if set.
+func (h *Hub[T]) handleBroadcast(msg hubMessage[T]) {
+	// Convert data to an event
+	event := msg.event
+	if event == nil {
+		if event = h.convertToEvent(msg.data); event == nil {
+			return
+		}
+	}
+	event.Retry = h.retryMillis()
+
+	// Topic and filtered events are not replayed (they'd leak to other
+	// clients)
+	recorded := h.historySize > 0 && msg.topic == "" && msg.filter == nil
+
+	// Serialize once for all recipients. Recorded events need their ID
+	// first, so they're serialized by record.
+	var data []byte
+	if !recorded {
+		data = event.Bytes()
+	}
+
+	// Record and snapshot recipients atomically, so clients catching up
+	// on history either get this event in replay or as a live broadcast
+	h.mu.Lock()
+	if recorded {
+		data = h.record(event)
+	}
+	h.broadcasts++
+
+	var clients []*hubClient
+	if msg.topic == "" {
+		clients = make([]*hubClient, 0, len(h.clients))
+		for conn, client := range h.clients {
+			if msg.filter == nil || msg.filter(conn) {
+				clients = append(clients, client)
+			}
+		}
+	} else {
+		subscribers := h.topics[msg.topic]
+		clients = make([]*hubClient, 0, len(subscribers))
+		for conn := range subscribers {
+			// Only registered clients receive topic events
+			if client, ok := h.clients[conn]; ok {
+				clients = append(clients, client)
+			}
 		}
 	}
+	h.mu.Unlock()
+
+	h.metrics.Broadcast(metrics.TransportSSE)
+
+	// Queue for each client (outside lock to avoid blocking)
+	for _, client := range clients {
+		_ = h.enqueue(client, data)
+	}
+}
+
+// retryMillis returns the retry value for the next broadcast under the
+// current load, in milliseconds (0 if no retry policy is set).
+func (h *Hub[T]) retryMillis() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.retryBase <= 0 {
+		return 0
+	}
+
+	// One factor step per doubling of the client count
+	steps := 0
+	if n := len(h.clients); n > 0 {
+		steps = bits.Len(uint(n)) - 1
+	}
+	retry := float64(h.retryBase) * math.Pow(h.retryFactor, float64(steps))
+	if retry > float64(h.retryMax) {
+		retry = float64(h.retryMax)
+	}
+	return int(time.Duration(retry) / time.Millisecond)
 }
 
-// convertToString converts T to string for sending.
-func (h *Hub[T]) convertToString(data T) string {
+// convertToEvent converts T to an event for sending (see Broadcast).
+// Returns nil if there's nothing to send: the data is empty or failed to
+// encode.
+func (h *Hub[T]) convertToEvent(data T) *Event {
+	event := &Event{}
 	switch v := any(data).(type) {
 	case string:
-		return v
+		event.Data = v
+	case []byte:
+		event.WithBinary(v)
 	case fmt.Stringer:
-		return v.String()
+		event.Data = v.String()
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			h.logger.Error("sse: hub dropped broadcast that failed to encode", "err", err)
+			return nil
+		}
+		event.WithBinary(b)
 	default:
 		// Try JSON encoding
 		jsonData, err := json.Marshal(v)
 		if err != nil {
-			return ""
+			h.logger.Error("sse: hub dropped broadcast that failed to encode", "err", err)
+			return nil
 		}
-		return string(jsonData)
+		event.Data = string(jsonData)
+	}
+
+	if event.Data == "" {
+		return nil
 	}
+	return event
 }
 
-// removeClient removes a failed client from the hub.
-func (h *Hub[T]) removeClient(client *Conn) {
+// removeClient removes a client from the hub and closes its connection,
+// reporting whether it did. It's a no-op if the client was already removed
+// or replaced.
+func (h *Hub[T]) removeClient(client *hubClient) bool {
 	h.mu.Lock()
-	delete(h.clients, client)
-	_ = client.Close()
+	registered := h.clients[client.conn] == client
+	switch {
+	case registered:
+		delete(h.clients, client.conn)
+	case h.pending[client.conn] == client:
+		delete(h.pending, client.conn)
+	default:
+		h.mu.Unlock()
+		return false
+	}
+	delete(h.ids, client.id)
+	close(client.quit)
+	h.unsubscribeAll(client.conn)
+	onUnregister := h.onUnregister
 	h.mu.Unlock()
+
+	// Close doesn't wait on a stuck write, so this never blocks
+	_ = client.conn.Close()
+
+	if !registered {
+		return true
+	}
+	h.metrics.ClientDisconnected(metrics.TransportSSE)
+	if onUnregister != nil {
+		onUnregister(client.conn)
+	}
+	return true
+}
+
+// unsubscribeAll removes client from all topics. Caller must hold h.mu.
+func (h *Hub[T]) unsubscribeAll(client *Conn) {
+	for topic := range h.subscriptions[client] {
+		h.removeSubscriber(topic, client)
+	}
+	delete(h.subscriptions, client)
+}
+
+// removeSubscriber removes client from a single topic. Caller must hold h.mu.
+func (h *Hub[T]) removeSubscriber(topic string, client *Conn) {
+	subscribers := h.topics[topic]
+	delete(subscribers, client)
+	if len(subscribers) == 0 {
+		delete(h.topics, topic)
+	}
 }
 
 // Register adds a connection to the hub.
@@ -176,7 +832,8 @@ func (h *Hub[T]) removeClient(client *Conn) {
 // The connection will receive all future broadcasts until it's unregistered
 // or fails to send.
 //
-// Returns ErrHubClosed if the hub is already closed.
+// Returns ErrHubClosed if the hub is already closed, or ErrTooManyClients
+// if HubOptions.MaxClients is reached.
 //
 // Example:
 //
@@ -186,16 +843,74 @@ func (h *Hub[T]) removeClient(client *Conn) {
 //	}
 //	err = hub.Register(conn)
 func (h *Hub[T]) Register(conn *Conn) error {
-	h.mu.RLock()
-	closed := h.closed
-	h.mu.RUnlock()
+	_, err := h.RegisterWithID(conn)
+	return err
+}
 
-	if closed {
-		return ErrHubClosed
+// RegisterWithID adds a connection to the hub and returns its client ID.
+//
+// The ID can be passed to SendTo to push events to this connection only.
+// IDs are unique for the lifetime of the Hub and never reused.
+//
+// Returns ErrHubClosed if the hub is already closed, or ErrTooManyClients
+// if HubOptions.MaxClients is reached. The limit counts registrations
+// still being processed, so concurrent Registers can't overshoot it;
+// re-registering a connection doesn't count twice. A rejected connection
+// is left open for the caller to close.
+//
+// Example:
+//
+//	id, err := hub.RegisterWithID(conn)
+//	if err != nil {
+//	    return err
+//	}
+//	sessions[userID] = id
+func (h *Hub[T]) RegisterWithID(conn *Conn) (uint64, error) {
+	if !h.open() {
+		return 0, ErrHubClosed
 	}
 
-	h.register <- conn
-	return nil
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return 0, ErrHubClosed
+	}
+	if h.maxClients > 0 && len(h.clients)+len(h.pending) >= h.maxClients && !h.isRegistered(conn) {
+		h.mu.Unlock()
+		return 0, ErrTooManyClients
+	}
+	if prev, ok := h.pending[conn]; ok {
+		// Replaces a registration that hasn't completed yet
+		delete(h.ids, prev.id)
+		close(prev.quit)
+	}
+	h.nextID++
+	client := &hubClient{
+		conn: conn,
+		id:   h.nextID,
+		send: make(chan []byte, h.clientBufferSize),
+		quit: make(chan struct{}),
+	}
+	h.ids[client.id] = client
+	h.pending[conn] = client
+	h.mu.Unlock()
+
+	select {
+	case h.register <- client:
+		return client.id, nil
+	case <-h.done:
+		return 0, ErrHubClosed
+	}
+}
+
+// isRegistered reports whether conn is registered or pending.
+// Caller must hold h.mu.
+func (h *Hub[T]) isRegistered(conn *Conn) bool {
+	if _, ok := h.clients[conn]; ok {
+		return true
+	}
+	_, ok := h.pending[conn]
+	return ok
 }
 
 // Unregister removes a connection from the hub.
@@ -209,6 +924,45 @@ func (h *Hub[T]) Register(conn *Conn) error {
 //
 //	err := hub.Unregister(conn)
 func (h *Hub[T]) Unregister(conn *Conn) error {
+	if !h.open() {
+		return ErrHubClosed
+	}
+
+	select {
+	case h.unregister <- conn:
+		return nil
+	case <-h.done:
+		return ErrHubClosed
+	}
+}
+
+// Broadcast sends data to all connected clients.
+//
+// The data will be converted to a string representation:
+//   - string: sent as-is
+//   - []byte: base64-encoded, as by Event.WithBinary
+//   - fmt.Stringer: String() method called
+//   - encoding.BinaryMarshaler: MarshalBinary() called, then base64-encoded
+//   - other types: JSON-encoded
+//
+// Binary data is sent with the event type BinaryEventType, so clients
+// can tell it apart and decode it.
+//
+// Failed sends automatically remove the client from the hub.
+// Each client receives broadcasts in submission order (see Hub).
+//
+// Broadcast never blocks: events are queued for the Run loop, and if the
+// queue is full (see HubOptions.BroadcastQueueSize) the event is rejected
+// with ErrHubBusy. Callers can retry later or report the overload, e.g.
+// with 503 Service Unavailable.
+//
+// Returns ErrHubClosed if the hub is already closed, or ErrHubBusy if the
+// broadcast queue is full.
+//
+// Example:
+//
+//	err := hub.Broadcast("Server restarting in 5 minutes")
+func (h *Hub[T]) Broadcast(data T) error {
 	h.mu.RLock()
 	closed := h.closed
 	h.mu.RUnlock()
@@ -217,25 +971,246 @@ func (h *Hub[T]) Unregister(conn *Conn) error {
 		return ErrHubClosed
 	}
 
-	h.unregister <- conn
-	return nil
+	return h.queue(hubMessage[T]{data: data})
 }
 
-// Broadcast sends data to all connected clients.
+// queue hands msg to the Run loop without blocking.
+// Returns ErrHubBusy if the broadcast queue is full.
+func (h *Hub[T]) queue(msg hubMessage[T]) error {
+	select {
+	case h.broadcast <- msg:
+		return nil
+	default:
+		return ErrHubBusy
+	}
+}
+
+// Publish sends msg to all connected clients.
 //
-// The data will be converted to a string representation:
+// Unlike Broadcast, msg may be of any type, so the hub can be used through
+// the transport-independent stream.Broadcaster interface:
+//   - T: sent as by Broadcast
 //   - string: sent as-is
+//   - []byte: base64-encoded, as by Event.WithBinary
 //   - fmt.Stringer: String() method called
+//   - encoding.BinaryMarshaler: MarshalBinary() called, then base64-encoded
 //   - other types: JSON-encoded
 //
-// Failed sends automatically remove the client from the hub.
+// Binary data is sent with the event type BinaryEventType, as by
+// Broadcast. Empty data is not sent.
+//
+// Returns ErrHubClosed if the hub is already closed, ErrHubBusy if the
+// broadcast queue is full, or an error if encoding fails.
+//
+// Example:
+//
+//	var b stream.Broadcaster = hub
+//	err := b.Publish("Server restarting in 5 minutes")
+func (h *Hub[T]) Publish(msg any) error {
+	if data, ok := msg.(T); ok {
+		return h.Broadcast(data)
+	}
+
+	event := &Event{}
+	switch v := msg.(type) {
+	case string:
+		event.Data = v
+	case []byte:
+		event.WithBinary(v)
+	case fmt.Stringer:
+		event.Data = v.String()
+	case encoding.BinaryMarshaler:
+		data, err := v.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("sse: failed to marshal binary data: %w", err)
+		}
+		event.WithBinary(data)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("sse: failed to marshal JSON: %w", err)
+		}
+		event.Data = string(data)
+	}
+
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+
+	if closed {
+		return ErrHubClosed
+	}
+
+	if event.Data == "" {
+		return nil
+	}
+	return h.queue(hubMessage[T]{event: event})
+}
+
+// Subscribe adds a connection to a topic.
+//
+// Subscribed connections receive events sent with BroadcastTopic for that
+// topic, in addition to regular broadcasts. A connection may subscribe to
+// any number of topics. Subscribing twice is a no-op.
+//
+// Subscriptions are removed automatically when the connection is
+// unregistered or fails to send.
+//
+// Returns ErrHubClosed if the hub is already closed, or ErrClientNotFound
+// if the connection isn't registered.
+//
+// Example:
+//
+//	hub.Register(conn)
+//	hub.Subscribe(conn, "user:"+userID)
+func (h *Hub[T]) Subscribe(conn *Conn, topic string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHubClosed
+	}
+
+	if !h.isRegistered(conn) {
+		return ErrClientNotFound
+	}
+
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Conn]struct{})
+	}
+	h.topics[topic][conn] = struct{}{}
+
+	if h.subscriptions[conn] == nil {
+		h.subscriptions[conn] = make(map[string]struct{})
+	}
+	h.subscriptions[conn][topic] = struct{}{}
+	return nil
+}
+
+// Unsubscribe removes a connection from a topic.
+//
+// The connection stays registered and keeps receiving regular broadcasts.
+// It's safe to call Unsubscribe for topics the connection isn't subscribed to.
 //
 // Returns ErrHubClosed if the hub is already closed.
 //
 // Example:
 //
-//	err := hub.Broadcast("Server restarting in 5 minutes")
-func (h *Hub[T]) Broadcast(data T) error {
+//	hub.Unsubscribe(conn, "user:"+userID)
+func (h *Hub[T]) Unsubscribe(conn *Conn, topic string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHubClosed
+	}
+
+	h.removeSubscriber(topic, conn)
+	if topics := h.subscriptions[conn]; topics != nil {
+		delete(topics, topic)
+		if len(topics) == 0 {
+			delete(h.subscriptions, conn)
+		}
+	}
+	return nil
+}
+
+// BroadcastTopic sends data to the connections subscribed to topic.
+//
+// The data is converted to a string the same way as Broadcast.
+// Topic events are not recorded in the replay history.
+// Broadcasting to a topic with no subscribers is a no-op, and an empty
+// topic is equivalent to Broadcast.
+//
+// Returns ErrHubClosed if the hub is already closed, or ErrHubBusy if the
+// broadcast queue is full.
+//
+// Example:
+//
+//	err := hub.BroadcastTopic("user:"+userID, "You have a new message")
+func (h *Hub[T]) BroadcastTopic(topic string, data T) error {
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+
+	if closed {
+		return ErrHubClosed
+	}
+
+	if topic == "" {
+		return h.Broadcast(data)
+	}
+
+	return h.queue(hubMessage[T]{topic: topic, data: data})
+}
+
+// BroadcastFunc sends data to the connected clients for which pred
+// returns true.
+//
+// It's a lightweight alternative to topics when recipients depend on a
+// property of the connection: tag connections with Conn.SetMetadata and
+// select them at broadcast time. pred runs in the Run loop once per client
+// while the hub's lock is held, so it should be fast and must not call Hub
+// methods; reading Conn.Metadata is safe.
+//
+// The data is converted to a string the same way as Broadcast. Filtered
+// events are not recorded in the replay history.
+//
+// Returns ErrHubClosed if the hub is already closed, or ErrHubBusy if the
+// broadcast queue is full.
+//
+// Example:
+//
+//	conn.SetMetadata("locale", "de")
+//
+//	err := hub.BroadcastFunc("Guten Morgen", func(c *sse.Conn) bool {
+//	    return c.Metadata("locale") == "de"
+//	})
+func (h *Hub[T]) BroadcastFunc(data T, pred func(*Conn) bool) error {
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+
+	if closed {
+		return ErrHubClosed
+	}
+
+	return h.queue(hubMessage[T]{data: data, filter: pred})
+}
+
+// BroadcastKeyed sends data to all connected clients, coalescing rapid
+// updates that share key.
+//
+// It's meant for values that change faster than clients need them, such
+// as dashboard gauges. The first event for a key starts a window of
+// HubOptions.CoalesceInterval; events for the same key within it replace
+// the pending value (latest wins), and only the value current when the
+// window ends is broadcast. Different keys are coalesced independently.
+// Without a CoalesceInterval, BroadcastKeyed is equivalent to Broadcast.
+//
+// Coalesced events are delayed by up to the interval, and are ordered
+// relative to other broadcasts by when their window ends, not by when
+// BroadcastKeyed was called. They're converted and recorded in history
+// the same way as Broadcast. The broadcast is queued for the Run loop when
+// the window ends, waiting for room if the broadcast queue is full.
+//
+// Returns ErrHubClosed if the hub is already closed.
+//
+// Example:
+//
+//	hub := sse.NewHubWithOptions[float64](&sse.HubOptions{
+//	    CoalesceInterval: 100 * time.Millisecond,
+//	})
+//
+//	// Clients get at most one cpu update per 100ms, the latest
+//	for sample := range samples {
+//	    _ = hub.BroadcastKeyed("cpu", sample)
+//	}
+func (h *Hub[T]) BroadcastKeyed(key string, data T) error {
+	if h.coalesceInterval <= 0 {
+		return h.Broadcast(data)
+	}
+
 	h.mu.RLock()
 	closed := h.closed
 	h.mu.RUnlock()
@@ -244,21 +1219,80 @@ func (h *Hub[T]) Broadcast(data T) error {
 		return ErrHubClosed
 	}
 
-	h.broadcast <- data
+	h.coalesceMu.Lock()
+	defer h.coalesceMu.Unlock()
+
+	_, pending := h.coalesced[key]
+	h.coalesced[key] = data
+	if !pending {
+		time.AfterFunc(h.coalesceInterval, func() { h.flushKeyed(key) })
+	}
 	return nil
 }
 
+// flushKeyed broadcasts the latest value for key at the end of its
+// coalescing window.
+func (h *Hub[T]) flushKeyed(key string) {
+	h.coalesceMu.Lock()
+	data := h.coalesced[key]
+	delete(h.coalesced, key)
+	h.coalesceMu.Unlock()
+
+	select {
+	case h.broadcast <- hubMessage[T]{data: data}:
+	case <-h.done:
+	}
+}
+
+// SendTo sends data to the single client with the given ID.
+//
+// The data is converted to a string the same way as Broadcast. The event
+// is queued behind any pending broadcasts for the client and is not
+// recorded in the replay history.
+//
+// Returns ErrClientNotFound if no client has the ID (never registered or
+// already removed), ErrSlowClient if the client's queue stayed full and the
+// event was dropped, or ErrHubClosed if the hub is closed.
+//
+// Example:
+//
+//	err := hub.SendTo(sessions[userID], "You have a new message")
+func (h *Hub[T]) SendTo(id uint64, data T) error {
+	h.mu.RLock()
+	closed := h.closed
+	client := h.ids[id]
+	h.mu.RUnlock()
+
+	if closed {
+		return ErrHubClosed
+	}
+	if client == nil {
+		return ErrClientNotFound
+	}
+
+	event := h.convertToEvent(data)
+	if event == nil {
+		return nil
+	}
+
+	return h.enqueue(client, event.Bytes())
+}
+
 // BroadcastJSON sends a JSON-encoded value to all connected clients.
 //
-// This is a convenience method for sending structured data.
-// The value is marshaled to JSON and sent to all clients.
+// This is a convenience method for sending structured data. v is
+// marshaled with encoding/json and the JSON is sent as the event data,
+// whatever the hub's type: it's usually a T, but may be any value, e.g. a
+// map. Unlike Broadcast, a String method on v is ignored, so types don't
+// need to implement fmt.Stringer to control their encoding.
 //
-// Returns an error if JSON marshaling fails or if the hub is closed.
+// Returns an error if JSON marshaling fails, ErrHubClosed if the hub is
+// closed, or ErrHubBusy if the broadcast queue is full.
 //
 // Example:
 //
-//	event := UserEvent{ID: 1, Action: "login"}
-//	err := hub.BroadcastJSON(event)
+//	hub := sse.NewHub[UserEvent]()
+//	err := hub.BroadcastJSON(UserEvent{ID: 1, Action: "login"})
 func (h *Hub[T]) BroadcastJSON(v any) error {
 	h.mu.RLock()
 	closed := h.closed
@@ -273,23 +1307,121 @@ func (h *Hub[T]) BroadcastJSON(v any) error {
 		return fmt.Errorf("sse: failed to marshal JSON: %w", err)
 	}
 
-	// Try to convert to T
-	var t T
-	switch any(t).(type) {
-	case string:
-		// T is string, convert directly
-		return h.Broadcast(any(string(data)).(T))
-	default:
-		// For other types, try to unmarshal into T
-		if err := json.Unmarshal(data, &t); err != nil {
-			// If unmarshal fails, try broadcasting the raw value
-			if typed, ok := v.(T); ok {
-				return h.Broadcast(typed)
-			}
-			return fmt.Errorf("sse: cannot convert to hub type: %w", err)
-		}
-		return h.Broadcast(t)
-	}
+	return h.queue(hubMessage[T]{event: NewEvent(string(data))})
+}
+
+// OnRegister sets a function called after a connection joins the hub.
+//
+// The hook runs on the Run goroutine, outside the hub's internal lock, so
+// it may call other Hub methods; Clients() already counts the new
+// connection. A slow hook delays the hub's event loop. Re-registering a
+// connection that's already registered doesn't call the hook again.
+// Passing nil removes the hook.
+//
+// Example:
+//
+//	hub.OnRegister(func(conn *sse.Conn) {
+//	    metrics.ActiveClients.Inc()
+//	})
+func (h *Hub[T]) OnRegister(fn func(*Conn)) {
+	h.mu.Lock()
+	h.onRegister = fn
+	h.mu.Unlock()
+}
+
+// OnUnregister sets a function called after a connection leaves the hub.
+//
+// It's called exactly once per registered connection, whether it was
+// unregistered, removed after a failed send or as a slow client, or
+// disconnected by Close. The hook runs outside the hub's internal lock on
+// the goroutine that removed the connection (usually Run); Clients() no
+// longer counts it. Passing nil removes the hook.
+//
+// Example:
+//
+//	hub.OnUnregister(func(conn *sse.Conn) {
+//	    metrics.ActiveClients.Dec()
+//	})
+func (h *Hub[T]) OnUnregister(fn func(*Conn)) {
+	h.mu.Lock()
+	h.onUnregister = fn
+	h.mu.Unlock()
+}
+
+// OnDeliveryError sets a function called when an event can't be
+// delivered to a client.
+//
+// Broadcasts are written to each client asynchronously, so Broadcast can't
+// report per-client failures; the hook does. err is the write error when a
+// write to the client failed (the client has been removed from the hub),
+// or ErrSlowClient when the event was dropped because the client's queue
+// stayed full (the client has been removed if the policy is
+// SlowClientDisconnect). Either way the hub has already acted, so the hook
+// is for reporting: Clients() no longer counts a removed client, and
+// OnUnregister has run for it. Writes aborted because the client was
+// unregistered or the hub closed aren't reported.
+//
+// The hook runs outside the hub's internal lock, on the client's writer
+// goroutine or the goroutine that queued the event (usually Run), so it
+// should be fast. It's called once per failed event, but a failed write
+// ends the client, so at most once per client for write errors. Passing
+// nil removes the hook.
+//
+// Example:
+//
+//	hub.OnDeliveryError(func(conn *sse.Conn, err error) {
+//	    slog.Warn("sse: delivery failed", "client", conn.ClientIP(), "error", err)
+//	})
+func (h *Hub[T]) OnDeliveryError(fn func(*Conn, error)) {
+	h.mu.Lock()
+	h.onDeliveryError = fn
+	h.mu.Unlock()
+}
+
+// SetRetryPolicy makes broadcast events carry a retry: value that grows
+// with the number of connected clients.
+//
+// The value starts at base for a single client and is multiplied by factor
+// each time the client count doubles (2, 4, 8, ... clients), up to
+// maxDelay.
+// When the server restarts or a proxy drops every connection at once, the
+// busier the hub was, the longer clients wait before reconnecting, which
+// spreads the reconnection storm out.
+//
+// Browsers apply the most recent retry: value they received, so it's sent
+// with every broadcast (BroadcastTopic and Publish included). Events sent
+// with SendTo and replayed history keep their own values. A factor below 1
+// is treated as 1, and a maxDelay below base as base. A non-positive base
+// removes the policy.
+//
+// Example:
+//
+//	// 1s with one client, 2s with 2-3, 4s with 4-7, ... capped at 30s
+//	hub.SetRetryPolicy(time.Second, 30*time.Second, 2)
+//
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub[T]) SetRetryPolicy(base, maxDelay time.Duration, factor float64) {
+	factor = math.Max(factor, 1)
+	maxDelay = max(maxDelay, base)
+
+	h.mu.Lock()
+	h.retryBase = base
+	h.retryMax = maxDelay
+	h.retryFactor = factor
+	h.mu.Unlock()
+}
+
+// Dropped returns the total number of events dropped for slow clients.
+//
+// This is safe to call concurrently with other Hub operations.
+//
+// Example:
+//
+//	if n := hub.Dropped(); n > 0 {
+//	    log.Printf("Dropped %d events for slow clients", n)
+//	}
+func (h *Hub[T]) Dropped() uint64 {
+	return h.dropped.Load()
 }
 
 // Clients returns the number of currently connected clients.
@@ -306,30 +1438,164 @@ func (h *Hub[T]) Clients() int {
 	return len(h.clients)
 }
 
+// HubStats is a snapshot of a hub's activity.
+type HubStats struct {
+	Clients      int    // Registered clients
+	Broadcasts   uint64 // Broadcasts processed, including topic broadcasts
+	BytesWritten uint64 // Event bytes written to clients
+	Dropped      uint64 // Events dropped for slow clients
+
+	// Topics maps each topic with subscribers to its subscriber count.
+	Topics map[string]int
+}
+
+// Stats returns a snapshot of the hub's activity.
+//
+// Client, broadcast, and topic counts are taken together under the hub's
+// lock, so Broadcasts and Clients agree with each other: a broadcast is
+// counted when the hub hands it to its recipients, not when Broadcast is
+// called. BytesWritten and Dropped are updated by client writers and may
+// trail recent broadcasts still in flight.
+//
+// Stats is meant for quick operational checks; use HubOptions.Metrics to
+// export metrics continuously.
+//
+// Example:
+//
+//	stats := hub.Stats()
+//	log.Printf("%d clients, %d broadcasts, %d bytes",
+//	    stats.Clients, stats.Broadcasts, stats.BytesWritten)
+//
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub[T]) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	topics := make(map[string]int, len(h.topics))
+	for topic, subscribers := range h.topics {
+		topics[topic] = len(subscribers)
+	}
+
+	return HubStats{
+		Clients:      len(h.clients),
+		Broadcasts:   h.broadcasts,
+		BytesWritten: h.bytesWritten.Load(),
+		Dropped:      h.dropped.Load(),
+		Topics:       topics,
+	}
+}
+
 // Close shuts down the hub and closes all client connections.
 //
 // After Close, all operations on the hub will return ErrHubClosed.
 // It's safe to call Close multiple times.
 //
+// Close waits for client writer goroutines to exit. Events still queued
+// are discarded, and writes stuck on unresponsive clients are aborted by
+// closing their connections (see Conn.Close).
+//
 // Example:
 //
 //	defer hub.Close()
 func (h *Hub[T]) Close() error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	return h.shutdown(nil)
+}
+
+// CloseWithEvent sends a final event to all clients, then shuts down the
+// hub like Close.
+//
+// Use it to tell clients why the stream is ending, so they can show a
+// "server restarting" notice instead of silently reconnecting. Events still
+// queued are discarded; the final event is written after any in-flight
+// event. Clients that don't accept it within the hub's SlowClientTimeout
+// are closed without it.
+//
+// The final event is not recorded in history. If the hub is already
+// closed, CloseWithEvent does nothing.
+//
+// Example:
+//
+//	_ = hub.CloseWithEvent(sse.NewEvent("restarting").WithType("shutdown"))
+func (h *Hub[T]) CloseWithEvent(event *Event) error {
+	return h.shutdown(event)
+}
 
+// shutdown implements Close and CloseWithEvent. A nil final skips the
+// final event.
+func (h *Hub[T]) shutdown(final *Event) error {
+	h.mu.Lock()
 	if h.closed {
+		h.mu.Unlock()
 		return nil
 	}
 
 	h.closed = true
 	close(h.done)
 
-	// Close all client connections
-	for client := range h.clients {
-		_ = client.Close()
+	conns := make([]*Conn, 0, len(h.clients)+len(h.pending))
+	for conn := range h.clients {
+		conns = append(conns, conn)
+	}
+	registered := len(conns)
+	for conn := range h.pending {
+		conns = append(conns, conn)
+	}
+	onUnregister := h.onUnregister
+	h.clients = make(map[*Conn]*hubClient)
+	h.ids = make(map[uint64]*hubClient)
+	h.pending = make(map[*Conn]*hubClient)
+	h.topics = make(map[string]map[*Conn]struct{})
+	h.subscriptions = make(map[*Conn]map[string]struct{})
+	h.mu.Unlock()
+
+	// Writers stop at done; a final event is written after their in-flight
+	// sends (Conn serializes writes)
+	var finals sync.WaitGroup
+	if final != nil {
+		data := final.Bytes()
+		for _, conn := range conns[:registered] {
+			finals.Add(1)
+			go func() {
+				defer finals.Done()
+				if conn.sendEncoded(data) == nil {
+					h.wrote(len(data))
+				}
+			}()
+		}
+		h.waitFinal(&finals)
+	}
+
+	// Close all client connections, then wait for their writers.
+	// Closing aborts final events stuck on unresponsive clients.
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	h.writers.Wait()
+	finals.Wait()
+
+	for _, conn := range conns[:registered] {
+		h.metrics.ClientDisconnected(metrics.TransportSSE)
+		if onUnregister != nil {
+			onUnregister(conn)
+		}
 	}
-	h.clients = make(map[*Conn]bool)
 
 	return nil
 }
+
+// waitFinal waits up to slowClientTimeout for final events to be written.
+func (h *Hub[T]) waitFinal(finals *sync.WaitGroup) {
+	sent := make(chan struct{})
+	go func() {
+		finals.Wait()
+		close(sent)
+	}()
+
+	timer := time.NewTimer(h.slowClientTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-sent:
+	case <-timer.C:
+	}
+}
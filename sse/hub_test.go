@@ -1,11 +1,18 @@
 package sse
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -22,6 +29,49 @@ func createHubTestConn(tb testing.TB) *Conn {
 	return conn
 }
 
+// stalledWriter is a ResponseWriter whose writes block once stalled is set,
+// simulating a client that stopped reading. Like a net/http connection, a
+// write deadline aborts the blocked write.
+type stalledWriter struct {
+	header  http.Header
+	stalled atomic.Bool
+	release chan struct{}
+	once    sync.Once
+}
+
+func newStalledWriter() *stalledWriter {
+	return &stalledWriter{
+		header:  make(http.Header),
+		release: make(chan struct{}),
+	}
+}
+
+func (s *stalledWriter) Header() http.Header { return s.header }
+func (s *stalledWriter) WriteHeader(int)     {}
+func (s *stalledWriter) Flush()              {}
+
+func (s *stalledWriter) Write(b []byte) (int, error) {
+	if s.stalled.Load() {
+		<-s.release
+	}
+	return len(b), nil
+}
+
+// SetWriteDeadline unblocks pending writes, as Conn.Close sets an
+// immediate deadline.
+func (s *stalledWriter) SetWriteDeadline(time.Time) error {
+	s.unstall()
+	return nil
+}
+
+// unstall lets all pending and future writes through.
+func (s *stalledWriter) unstall() {
+	s.once.Do(func() {
+		s.stalled.Store(false)
+		close(s.release)
+	})
+}
+
 func TestNewHub(t *testing.T) {
 	hub := NewHub[string]()
 
@@ -130,6 +180,47 @@ func TestHub_RegisterMultipleClients(t *testing.T) {
 	}
 }
 
+func TestHub_MaxClients(t *testing.T) {
+	hub := NewHubWithOptions[string](&HubOptions{MaxClients: 2})
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	a, b, c := createHubTestConn(t), createHubTestConn(t), createHubTestConn(t)
+	for _, conn := range []*Conn{a, b} {
+		if err := hub.Register(conn); err != nil {
+			t.Fatalf("Register() error = %v, want nil", err)
+		}
+	}
+
+	if err := hub.Register(c); !errors.Is(err, ErrTooManyClients) {
+		t.Errorf("Register() past limit error = %v, want ErrTooManyClients", err)
+	}
+	if err := hub.Register(a); err != nil {
+		t.Errorf("re-Register() error = %v, want nil", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if got := hub.Clients(); got != 2 {
+		t.Errorf("Clients() = %d, want 2", got)
+	}
+	for i, conn := range []*Conn{a, b} {
+		select {
+		case <-conn.Done():
+			t.Errorf("client %d disconnected by rejected registration", i)
+		default:
+		}
+	}
+
+	// Unregistering frees a slot
+	if err := hub.Unregister(a); err != nil {
+		t.Fatalf("Unregister() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := hub.Register(c); err != nil {
+		t.Errorf("Register() after Unregister error = %v, want nil", err)
+	}
+}
+
 func TestHub_BroadcastString(t *testing.T) {
 	hub := NewHub[string]()
 	go hub.Run()
@@ -263,6 +354,173 @@ func TestHub_BroadcastJSON(t *testing.T) {
 	}
 }
 
+// captureLogger records logged messages as "LEVEL msg".
+type captureLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *captureLogger) log(level, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, level+" "+msg)
+}
+
+func (l *captureLogger) Debug(msg string, _ ...any) { l.log("DEBUG", msg) }
+func (l *captureLogger) Info(msg string, _ ...any)  { l.log("INFO", msg) }
+func (l *captureLogger) Warn(msg string, _ ...any)  { l.log("WARN", msg) }
+func (l *captureLogger) Error(msg string, _ ...any) { l.log("ERROR", msg) }
+
+// Messages returns a copy of the logged messages.
+func (l *captureLogger) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.msgs)
+}
+
+// TestHub_Logger tests that the hub logs a client dropped after a failed
+// write.
+func TestHub_Logger(t *testing.T) {
+	logger := &captureLogger{}
+	hub := NewHubWithOptions[string](&HubOptions{Logger: logger})
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	w := &failingWriter{header: make(http.Header)}
+	bad, err := Upgrade(w, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(bad)
+	time.Sleep(20 * time.Millisecond)
+
+	w.fail.Store(true)
+	if err := hub.Broadcast("hello"); err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+
+	want := "WARN sse: hub dropped client after failed write"
+	deadline := time.Now().Add(time.Second)
+	for !slices.Contains(logger.Messages(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("logged %q, want %q", logger.Messages(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// jsonOnly is a hub message type without a String method.
+type jsonOnly struct {
+	User string `json:"user"`
+	Text string `json:"text"`
+}
+
+func TestHub_BroadcastJSON_Typed(t *testing.T) {
+	hub := NewHub[jsonOnly]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	w := httptest.NewRecorder()
+	conn, err := Upgrade(w, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := hub.BroadcastJSON(jsonOnly{User: "alice", Text: "hi"}); err != nil {
+		t.Fatalf("BroadcastJSON() error = %v", err)
+	}
+	if err := hub.BroadcastJSON(func() {}); err == nil || errors.Is(err, ErrHubClosed) {
+		t.Errorf("BroadcastJSON(func) error = %v, want a marshal error", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	want := "data: {\"user\":\"alice\",\"text\":\"hi\"}\n\n"
+	if body := w.Body.String(); !strings.Contains(body, want) {
+		t.Errorf("body = %q, want it to contain %q", body, want)
+	}
+}
+
+func TestHub_BroadcastKeyed(t *testing.T) {
+	hub := NewHubWithOptions[int](&HubOptions{CoalesceInterval: 50 * time.Millisecond})
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	w := httptest.NewRecorder()
+	conn, err := Upgrade(w, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	// Many updates for one key within the window, one for another
+	for i := 1; i <= 100; i++ {
+		if err := hub.BroadcastKeyed("cpu", i); err != nil {
+			t.Fatalf("BroadcastKeyed() error = %v", err)
+		}
+	}
+	if err := hub.BroadcastKeyed("mem", -1); err != nil {
+		t.Fatalf("BroadcastKeyed() error = %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	body := w.Body.String()
+	if n := strings.Count(body, "data: "); n != 2 {
+		t.Errorf("got %d events, want 2 (one per key); body = %q", n, body)
+	}
+	for _, want := range []string{"data: 100\n\n", "data: -1\n\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q, want it to contain %q", body, want)
+		}
+	}
+
+	if err := hub.BroadcastKeyed("cpu", 0); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("BroadcastKeyed() after Close error = %v, want ErrHubClosed", err)
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub[int]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	w := httptest.NewRecorder()
+	conn, err := Upgrade(w, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	msgs := []any{42, "text", []byte("raw"), map[string]int{"n": 1}}
+	for _, msg := range msgs {
+		if err := hub.Publish(msg); err != nil {
+			t.Fatalf("Publish(%v) error = %v", msg, err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	want := "data: 42\n\ndata: text\n\nevent: binary\ndata: cmF3\n\ndata: {\"n\":1}\n\n"
+	if body := w.Body.String(); !strings.HasSuffix(body, want) {
+		t.Errorf("body = %q, want suffix %q", body, want)
+	}
+
+	if err := hub.Publish("late"); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Publish() after Close error = %v, want ErrHubClosed", err)
+	}
+}
+
 func TestHub_BroadcastClosed(t *testing.T) {
 	hub := NewHub[string]()
 	go hub.Run()
@@ -280,6 +538,111 @@ func TestHub_BroadcastClosed(t *testing.T) {
 	}
 }
 
+func TestHub_BroadcastQueueFull(t *testing.T) {
+	// Run isn't started, so nothing drains the queue
+	hub := NewHubWithOptions[string](&HubOptions{BroadcastQueueSize: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := hub.Broadcast("queued"); err != nil {
+			t.Fatalf("Broadcast() %d error = %v", i, err)
+		}
+	}
+
+	done := make(chan error, 3)
+	go func() {
+		done <- hub.Broadcast("overflow")
+		done <- hub.BroadcastTopic("news", "overflow")
+		done <- hub.Publish(42)
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrHubBusy) {
+				t.Errorf("send %d on full queue error = %v, want ErrHubBusy", i, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("send on full queue blocked")
+		}
+	}
+
+	// Draining the queue makes room again
+	go hub.Run()
+	defer hub.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := hub.Broadcast("after drain")
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrHubBusy) || time.Now().After(deadline) {
+			t.Fatalf("Broadcast() after drain error = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestHub_LazyStart tests that registering before Run starts the event
+// loop instead of blocking, and that a later Run doesn't start a second loop.
+func TestHub_LazyStart(t *testing.T) {
+	hub := NewHub[string]()
+
+	done := make(chan error, 1)
+	go func() {
+		// More than the register buffer holds, so it needs the event loop
+		for i := 0; i < 40; i++ {
+			if _, err := hub.RegisterWithID(createHubTestConn(t)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RegisterWithID() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RegisterWithID() blocked on a hub without Run")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for hub.Clients() != 40 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Clients() = %d, want 40", hub.Clients())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Run doesn't start another loop; it returns once the hub is closed
+	runDone := make(chan struct{})
+	go func() {
+		hub.Run()
+		close(runDone)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-runDone:
+		t.Fatal("Run() returned before Close")
+	default:
+	}
+
+	if err := hub.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	select {
+	case <-runDone:
+	case <-time.After(time.Second):
+		t.Error("Run() didn't return after Close")
+	}
+
+	// Run after Close returns immediately
+	hub.Run()
+}
+
 func TestHub_RegisterClosed(t *testing.T) {
 	hub := NewHub[string]()
 	go hub.Run()
@@ -377,6 +740,76 @@ func TestHub_CloseClosesAllClients(t *testing.T) {
 	}
 }
 
+func TestHub_CloseWithEvent(t *testing.T) {
+	hub := NewHubWithOptions[string](&HubOptions{
+		SlowClientTimeout: 50 * time.Millisecond,
+	})
+	go hub.Run()
+
+	numClients := 3
+	bodies := make(chan string, numClients)
+	for i := 0; i < numClients; i++ {
+		w := httptest.NewRecorder()
+		conn, err := Upgrade(w, httptest.NewRequest("GET", "/events", http.NoBody))
+		if err != nil {
+			t.Fatalf("Upgrade() error = %v", err)
+		}
+		if err := hub.Register(conn); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+
+		// Snapshot what the client saw when Done() closed
+		go func() {
+			<-conn.Done()
+			bodies <- w.Body.String()
+		}()
+	}
+
+	// Stalled client must not block the final event to others
+	slow := newStalledWriter()
+	slowConn, err := Upgrade(slow, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	slow.stalled.Store(true)
+	_ = hub.Register(slowConn)
+
+	time.Sleep(20 * time.Millisecond)
+	_ = hub.Broadcast("before")
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		_ = hub.CloseWithEvent(NewEvent("restarting").WithType("shutdown"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("CloseWithEvent() did not return")
+	}
+
+	want := "data: before\n\nevent: shutdown\ndata: restarting\n\n"
+	for i := 0; i < numClients; i++ {
+		select {
+		case body := <-bodies:
+			if !strings.HasSuffix(body, want) {
+				t.Errorf("client body = %q, want suffix %q", body, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Done() not closed after CloseWithEvent")
+		}
+	}
+
+	if err := hub.Broadcast("after"); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Broadcast() after CloseWithEvent error = %v, want ErrHubClosed", err)
+	}
+	if err := hub.CloseWithEvent(NewEvent("again")); err != nil {
+		t.Errorf("second CloseWithEvent() error = %v", err)
+	}
+}
+
 func TestHub_UnregisterNonExistentClient(t *testing.T) {
 	hub := NewHub[string]()
 	go hub.Run()
@@ -583,7 +1016,1021 @@ func TestHub_GenericTypes(t *testing.T) {
 	})
 }
 
-// Benchmarks
+// TestHub_BroadcastBinary tests that binary data sent with Broadcast or
+// Publish is base64-encoded and decodes on the client.
+func TestHub_BroadcastBinary(t *testing.T) {
+	hub := NewHub[[]byte]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	// Publish converts values that aren't a T itself
+	textHub := NewHub[string]()
+	go textHub.Run()
+	defer func() { _ = textHub.Close() }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		if hub.Register(conn) != nil || textHub.Register(conn) != nil {
+			return
+		}
+		<-conn.Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	// Wait for registration
+	deadline := time.Now().Add(2 * time.Second)
+	for (hub.Clients() == 0 || textHub.Clients() == 0) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	payload := []byte{0x00, 0xff, '\n', '\r', 0x80, 'a'} // Not valid as SSE text
+	sends := []struct {
+		name string
+		send func() error
+	}{
+		{"Broadcast", func() error { return hub.Broadcast(payload) }},
+		{"Publish []byte", func() error { return textHub.Publish(payload) }},
+		{"Publish BinaryMarshaler", func() error { return textHub.Publish(binaryPayload(payload)) }},
+	}
+	for _, tt := range sends {
+		if err := tt.send(); err != nil {
+			t.Fatalf("%s error = %v", tt.name, err)
+		}
+
+		select {
+		case event := <-client.Events():
+			if event.Type != BinaryEventType {
+				t.Errorf("%s: event type = %q, want %q", tt.name, event.Type, BinaryEventType)
+			}
+			got, err := base64.StdEncoding.DecodeString(event.Data)
+			if err != nil {
+				t.Fatalf("%s: decoding %q: %v", tt.name, event.Data, err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Errorf("%s: decoded data = %v, want %v", tt.name, got, payload)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: no event received", tt.name)
+		}
+	}
+}
+
+// binaryPayload is an encoding.BinaryMarshaler for hub tests.
+type binaryPayload []byte
+
+func (p binaryPayload) MarshalBinary() ([]byte, error) {
+	return p, nil
+}
+
+func TestHub_HistoryReplay(t *testing.T) {
+	hub := NewHubWithHistory[string](10)
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	for _, msg := range []string{"e1", "e2", "e3", "e4", "e5"} {
+		if err := hub.Broadcast(msg); err != nil {
+			t.Fatalf("Broadcast() error = %v", err)
+		}
+	}
+
+	// Wait for broadcasts to process
+	time.Sleep(20 * time.Millisecond)
+
+	// Reconnecting client that last saw event 2
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+	r.Header.Set("Last-Event-ID", "2")
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+
+	if err := hub.Register(conn); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// Wait for registration and replay
+	time.Sleep(20 * time.Millisecond)
+
+	if err := hub.Broadcast("e6"); err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+
+	// Wait for broadcast to process
+	time.Sleep(20 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	body := w.Body.String()
+	for _, missing := range []string{"data: e1\n", "data: e2\n"} {
+		if strings.Contains(body, missing) {
+			t.Errorf("body should not replay %q, got: %q", missing, body)
+		}
+	}
+
+	want := "id: 3\ndata: e3\n\nid: 4\ndata: e4\n\nid: 5\ndata: e5\n\nid: 6\ndata: e6\n\n"
+	if !strings.HasSuffix(body, want) {
+		t.Errorf("body = %q, want suffix %q", body, want)
+	}
+}
+
+func TestHub_HistoryLimit(t *testing.T) {
+	hub := NewHubWithHistory[string](2)
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	for _, msg := range []string{"e1", "e2", "e3", "e4"} {
+		_ = hub.Broadcast(msg)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+	r.Header.Set("Last-Event-ID", "0")
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	// Only the last 2 events are retained
+	want := ": connected\n\nid: 3\ndata: e3\n\nid: 4\ndata: e4\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestHub_HistoryFutureID(t *testing.T) {
+	hub := NewHubWithHistory[string](10)
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	_ = hub.Broadcast("e1")
+	_ = hub.Broadcast("e2")
+	time.Sleep(20 * time.Millisecond)
+
+	// ID from before a restart: the whole history is replayed
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+	r.Header.Set("Last-Event-ID", "500")
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	want := ": connected\n\nid: 1\ndata: e1\n\nid: 2\ndata: e2\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestHub_HistoryReplayDoesNotBlockHub(t *testing.T) {
+	hub := NewHubWithHistory[string](10)
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	_ = hub.Broadcast("e1")
+	time.Sleep(20 * time.Millisecond)
+
+	// Reconnecting client that stalls during replay
+	slow := newStalledWriter()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+	r.Header.Set("Last-Event-ID", "0")
+	slowConn, err := Upgrade(slow, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	slow.stalled.Store(true)
+	_ = hub.Register(slowConn)
+
+	fast := httptest.NewRecorder()
+	fastConn, err := Upgrade(fast, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(fastConn)
+	time.Sleep(20 * time.Millisecond)
+
+	_ = hub.Broadcast("e2")
+	time.Sleep(20 * time.Millisecond)
+
+	// Unblock the slow client, then stop Hub before reading (prevents race)
+	slow.unstall()
+	_ = hub.Close()
+
+	if !strings.Contains(fast.Body.String(), "data: e2\n") {
+		t.Errorf("fast client missed live event while another client replayed: %q", fast.Body.String())
+	}
+}
+
+func TestHub_HistoryNoLastEventID(t *testing.T) {
+	hub := NewHubWithHistory[string](10)
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	_ = hub.Broadcast("e1")
+	time.Sleep(20 * time.Millisecond)
+
+	// First-time client gets no replay
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	if got := w.Body.String(); got != ": connected\n\n" {
+		t.Errorf("body = %q, want no replayed events", got)
+	}
+}
+
+func TestHub_SendTo(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	const numClients = 3
+	writers := make([]*httptest.ResponseRecorder, numClients)
+	ids := make([]uint64, numClients)
+
+	for i := 0; i < numClients; i++ {
+		w := httptest.NewRecorder()
+		writers[i] = w
+		r := httptest.NewRequest("GET", "/events", http.NoBody)
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Fatalf("Upgrade() error = %v", err)
+		}
+		ids[i], err = hub.RegisterWithID(conn)
+		if err != nil {
+			t.Fatalf("RegisterWithID() error = %v", err)
+		}
+	}
+
+	if ids[0] == ids[1] || ids[1] == ids[2] || ids[0] == ids[2] {
+		t.Fatalf("client IDs not unique: %v", ids)
+	}
+
+	// Wait for registrations
+	time.Sleep(20 * time.Millisecond)
+
+	if err := hub.SendTo(ids[1], "only-for-you"); err != nil {
+		t.Fatalf("SendTo() error = %v", err)
+	}
+
+	// Wait for delivery
+	time.Sleep(20 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	for i, w := range writers {
+		got := strings.Contains(w.Body.String(), "data: only-for-you\n")
+		if want := i == 1; got != want {
+			t.Errorf("client %d received = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestHub_SendTo_NotFound(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	if err := hub.SendTo(42, "nobody"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("SendTo() error = %v, want ErrClientNotFound", err)
+	}
+
+	conn := createHubTestConn(t)
+	id, err := hub.RegisterWithID(conn)
+	if err != nil {
+		t.Fatalf("RegisterWithID() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	_ = hub.Unregister(conn)
+	time.Sleep(10 * time.Millisecond)
+
+	if err := hub.SendTo(id, "gone"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("SendTo() after Unregister error = %v, want ErrClientNotFound", err)
+	}
+}
+
+func TestHub_SendTo_Closed(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	_ = hub.Close()
+
+	if err := hub.SendTo(1, "data"); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("SendTo() error = %v, want ErrHubClosed", err)
+	}
+	if _, err := hub.RegisterWithID(createHubTestConn(t)); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("RegisterWithID() error = %v, want ErrHubClosed", err)
+	}
+}
+
+func TestHub_Topics(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	newClient := func(topic string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/events", http.NoBody)
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Fatalf("Upgrade() error = %v", err)
+		}
+		if err := hub.Register(conn); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		if err := hub.Subscribe(conn, topic); err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+		return w
+	}
+
+	wa := newClient("a")
+	wb := newClient("b")
+
+	// Wait for registrations
+	time.Sleep(20 * time.Millisecond)
+
+	_ = hub.BroadcastTopic("a", "for-a")
+	_ = hub.BroadcastTopic("b", "for-b")
+	_ = hub.Broadcast("for-all")
+
+	// Wait for broadcasts to process
+	time.Sleep(50 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	want := ": connected\n\ndata: for-a\n\ndata: for-all\n\n"
+	if got := wa.Body.String(); got != want {
+		t.Errorf("topic a body = %q, want %q", got, want)
+	}
+	want = ": connected\n\ndata: for-b\n\ndata: for-all\n\n"
+	if got := wb.Body.String(); got != want {
+		t.Errorf("topic b body = %q, want %q", got, want)
+	}
+}
+
+func TestHub_BroadcastFunc(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	newClient := func(locale string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/events", http.NoBody)
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Fatalf("Upgrade() error = %v", err)
+		}
+		if locale != "" {
+			conn.SetMetadata("locale", locale)
+		}
+		if err := hub.Register(conn); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		return w
+	}
+
+	de := newClient("de")
+	en := newClient("en")
+	none := newClient("")
+	time.Sleep(20 * time.Millisecond)
+
+	_ = hub.BroadcastFunc("hallo", func(c *Conn) bool { return c.Metadata("locale") == "de" })
+	_ = hub.BroadcastFunc("hello", func(c *Conn) bool { return c.Metadata("locale") == "en" })
+	_ = hub.BroadcastFunc("tagged", func(c *Conn) bool { return c.Metadata("locale") != nil })
+	_ = hub.Broadcast("all")
+	time.Sleep(50 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	tests := []struct {
+		name string
+		w    *httptest.ResponseRecorder
+		want string
+	}{
+		{"de", de, ": connected\n\ndata: hallo\n\ndata: tagged\n\ndata: all\n\n"},
+		{"en", en, ": connected\n\ndata: hello\n\ndata: tagged\n\ndata: all\n\n"},
+		{"untagged", none, ": connected\n\ndata: all\n\n"},
+	}
+	for _, tt := range tests {
+		if got := tt.w.Body.String(); got != tt.want {
+			t.Errorf("%s body = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if err := hub.BroadcastFunc("late", nil); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("BroadcastFunc() after Close error = %v, want ErrHubClosed", err)
+	}
+}
+
+func TestHub_UnsubscribeAndCleanup(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	conn1 := createHubTestConn(t)
+	conn2 := createHubTestConn(t)
+	_ = hub.Register(conn1)
+	_ = hub.Register(conn2)
+	_ = hub.Subscribe(conn1, "a")
+	_ = hub.Subscribe(conn1, "b")
+	_ = hub.Subscribe(conn2, "b")
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := hub.Unsubscribe(conn1, "a"); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+
+	hub.mu.RLock()
+	if _, ok := hub.topics["a"]; ok {
+		t.Error("empty topic a should be removed")
+	}
+	hub.mu.RUnlock()
+
+	// Unregister removes all remaining subscriptions
+	_ = hub.Unregister(conn1)
+	time.Sleep(20 * time.Millisecond)
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if _, ok := hub.topics["b"][conn1]; ok {
+		t.Error("unregistered client still subscribed to topic b")
+	}
+	if _, ok := hub.topics["b"][conn2]; !ok {
+		t.Error("conn2 should still be subscribed to topic b")
+	}
+	if _, ok := hub.subscriptions[conn1]; ok {
+		t.Error("unregistered client still has subscriptions")
+	}
+}
+
+func TestHub_SubscribeAfterUnregister(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	conn := createHubTestConn(t)
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	_ = hub.Unregister(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := hub.Subscribe(conn, "a"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Subscribe() after Unregister error = %v, want ErrClientNotFound", err)
+	}
+
+	// Never registered
+	stranger := createHubTestConn(t)
+	if err := hub.Subscribe(stranger, "a"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("Subscribe() unregistered error = %v, want ErrClientNotFound", err)
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if _, ok := hub.topics["a"]; ok {
+		t.Error("topic a should not exist")
+	}
+	if len(hub.subscriptions) != 0 {
+		t.Errorf("subscriptions = %d, want 0", len(hub.subscriptions))
+	}
+}
+
+func TestHub_TopicsUnregisteredSubscriber(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/events", http.NoBody)
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+
+	// Subscribed while pending; Unregister is processed before the
+	// queued registration completes
+	_ = hub.Register(conn)
+	_ = hub.Subscribe(conn, "a")
+	_ = hub.Unregister(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	_ = hub.BroadcastTopic("a", "secret")
+	time.Sleep(20 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Error("unregistered client received topic event")
+	}
+	if hub.Clients() != 0 {
+		t.Errorf("Clients() = %d, want 0", hub.Clients())
+	}
+}
+
+func TestHub_TopicsClosed(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	_ = hub.Close()
+
+	conn := createHubTestConn(t)
+	if err := hub.Subscribe(conn, "a"); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Subscribe() error = %v, want ErrHubClosed", err)
+	}
+	if err := hub.Unsubscribe(conn, "a"); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Unsubscribe() error = %v, want ErrHubClosed", err)
+	}
+	if err := hub.BroadcastTopic("a", "data"); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("BroadcastTopic() error = %v, want ErrHubClosed", err)
+	}
+}
+
+func TestHub_SlowClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      SlowClientPolicy
+		wantClients int
+	}{
+		{"DropEvents", SlowClientDropEvents, 2},
+		{"Disconnect", SlowClientDisconnect, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHubWithOptions[string](&HubOptions{
+				ClientBufferSize:  2,
+				SlowClientTimeout: 50 * time.Millisecond,
+				SlowClientPolicy:  tt.policy,
+			})
+			go hub.Run()
+			defer func() { _ = hub.Close() }()
+
+			// Slow client: stalls on the first write after upgrade
+			slow := newStalledWriter()
+			slowConn, err := Upgrade(slow, httptest.NewRequest("GET", "/events", http.NoBody))
+			if err != nil {
+				t.Fatalf("Upgrade() error = %v", err)
+			}
+			slow.stalled.Store(true)
+
+			fast := httptest.NewRecorder()
+			fastConn, err := Upgrade(fast, httptest.NewRequest("GET", "/events", http.NoBody))
+			if err != nil {
+				t.Fatalf("Upgrade() error = %v", err)
+			}
+
+			_ = hub.Register(slowConn)
+			_ = hub.Register(fastConn)
+			time.Sleep(20 * time.Millisecond)
+
+			// Burst larger than the client buffer
+			const numEvents = 10
+			for i := 0; i < numEvents; i++ {
+				if err := hub.Broadcast(fmt.Sprintf("event-%d", i)); err != nil {
+					t.Fatalf("Broadcast() error = %v", err)
+				}
+			}
+
+			// Wait for broadcasts to process (one slow-client timeout)
+			time.Sleep(200 * time.Millisecond)
+
+			if hub.Dropped() == 0 {
+				t.Error("Dropped() = 0, want > 0 for stalled client")
+			}
+			if got := hub.Clients(); got != tt.wantClients {
+				t.Errorf("Clients() = %d, want %d", got, tt.wantClients)
+			}
+
+			// Close must not hang on the stalled write.
+			// Stop Hub before reading (prevents race)
+			_ = hub.Close()
+
+			body := fast.Body.String()
+			for i := 0; i < numEvents; i++ {
+				if want := fmt.Sprintf("data: event-%d\n", i); !strings.Contains(body, want) {
+					t.Errorf("fast client missed %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestHub_SendTo_SlowClient(t *testing.T) {
+	hub := NewHubWithOptions[string](&HubOptions{
+		ClientBufferSize:  1,
+		SlowClientTimeout: 10 * time.Millisecond,
+	})
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	slow := newStalledWriter()
+	conn, err := Upgrade(slow, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	slow.stalled.Store(true)
+
+	id, _ := hub.RegisterWithID(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	// First event blocks the writer, second fills the buffer
+	_ = hub.SendTo(id, "e1")
+	time.Sleep(20 * time.Millisecond)
+	_ = hub.SendTo(id, "e2")
+
+	if err := hub.SendTo(id, "e3"); !errors.Is(err, ErrSlowClient) {
+		t.Errorf("SendTo() error = %v, want ErrSlowClient", err)
+	}
+	if got := hub.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+}
+
+func TestHub_Hooks(t *testing.T) {
+	hub := NewHub[string]()
+
+	var registered, unregistered atomic.Int32
+	var clientsOnRegister, clientsOnUnregister atomic.Int32
+	hub.OnRegister(func(*Conn) {
+		registered.Add(1)
+		clientsOnRegister.Store(int32(hub.Clients()))
+	})
+	hub.OnUnregister(func(*Conn) {
+		unregistered.Add(1)
+		clientsOnUnregister.Store(int32(hub.Clients()))
+	})
+
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	conn := createHubTestConn(t)
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	_ = hub.Unregister(conn)
+	_ = hub.Unregister(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	if got := registered.Load(); got != 1 {
+		t.Errorf("OnRegister calls = %d, want 1", got)
+	}
+	if got := unregistered.Load(); got != 1 {
+		t.Errorf("OnUnregister calls = %d, want 1", got)
+	}
+	if got := clientsOnRegister.Load(); got != 1 {
+		t.Errorf("Clients() in OnRegister = %d, want 1", got)
+	}
+	if got := clientsOnUnregister.Load(); got != 0 {
+		t.Errorf("Clients() in OnUnregister = %d, want 0", got)
+	}
+}
+
+func TestHub_OnDeliveryError(t *testing.T) {
+	hub := NewHub[string]()
+
+	type failure struct {
+		conn *Conn
+		err  error
+	}
+	failures := make(chan failure, 4)
+	hub.OnDeliveryError(func(conn *Conn, err error) {
+		failures <- failure{conn, err}
+	})
+
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	good := createHubTestConn(t)
+	w := &failingWriter{header: make(http.Header)}
+	bad, err := Upgrade(w, httptest.NewRequest("GET", "/events", http.NoBody))
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(good)
+	_ = hub.Register(bad)
+	time.Sleep(20 * time.Millisecond)
+
+	w.fail.Store(true)
+	if err := hub.Broadcast("hello"); err != nil {
+		t.Fatalf("Broadcast() error = %v", err)
+	}
+
+	select {
+	case f := <-failures:
+		if f.conn != bad {
+			t.Error("OnDeliveryError reported the wrong client")
+		}
+		if !errors.Is(f.err, context.Canceled) {
+			t.Errorf("OnDeliveryError error = %v, want the write error", f.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnDeliveryError not called for the failing client")
+	}
+
+	// The failing client was removed; the other still receives
+	if got := hub.Clients(); got != 1 {
+		t.Errorf("Clients() = %d, want 1", got)
+	}
+	_ = hub.Close()
+	select {
+	case f := <-failures:
+		t.Errorf("unexpected OnDeliveryError(%v)", f.err)
+	default:
+	}
+}
+
+func TestHub_Hooks_Close(t *testing.T) {
+	hub := NewHub[string]()
+
+	var unregistered atomic.Int32
+	hub.OnUnregister(func(*Conn) { unregistered.Add(1) })
+
+	go hub.Run()
+
+	for i := 0; i < 3; i++ {
+		_ = hub.Register(createHubTestConn(t))
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	_ = hub.Close()
+	_ = hub.Close()
+
+	if got := unregistered.Load(); got != 3 {
+		t.Errorf("OnUnregister calls = %d, want 3", got)
+	}
+}
+
+// Benchmarks
+
+// fakeMetricsSink counts hub events.
+type fakeMetricsSink struct {
+	connected    atomic.Int64
+	disconnected atomic.Int64
+	broadcasts   atomic.Int64
+	bytesWritten atomic.Int64
+	dropped      atomic.Int64
+}
+
+func (f *fakeMetricsSink) ClientConnected(string)       { f.connected.Add(1) }
+func (f *fakeMetricsSink) ClientDisconnected(string)    { f.disconnected.Add(1) }
+func (f *fakeMetricsSink) Broadcast(string)             { f.broadcasts.Add(1) }
+func (f *fakeMetricsSink) BytesWritten(_ string, n int) { f.bytesWritten.Add(int64(n)) }
+func (f *fakeMetricsSink) MessageDropped(string)        { f.dropped.Add(1) }
+
+func TestHub_Metrics(t *testing.T) {
+	sink := &fakeMetricsSink{}
+	hub := NewHubWithOptions[string](&HubOptions{Metrics: sink})
+	go hub.Run()
+
+	conn1 := createHubTestConn(t)
+	conn2 := createHubTestConn(t)
+	_ = hub.Register(conn1)
+	_ = hub.Register(conn2)
+	time.Sleep(20 * time.Millisecond)
+
+	if got := sink.connected.Load(); got != 2 {
+		t.Errorf("connected = %d, want 2", got)
+	}
+
+	_ = hub.Broadcast("hello")
+	_ = hub.Broadcast("world")
+	time.Sleep(50 * time.Millisecond)
+
+	if got := sink.broadcasts.Load(); got != 2 {
+		t.Errorf("broadcasts = %d, want 2", got)
+	}
+	// Two events of len("data: hello\n\n") to two clients
+	if got := sink.bytesWritten.Load(); got != 4*13 {
+		t.Errorf("bytesWritten = %d, want %d", got, 4*13)
+	}
+
+	_ = hub.Unregister(conn1)
+	time.Sleep(20 * time.Millisecond)
+
+	if got := sink.disconnected.Load(); got != 1 {
+		t.Errorf("disconnected = %d, want 1", got)
+	}
+
+	// Close disconnects the remaining client
+	_ = hub.Close()
+	if got := sink.disconnected.Load(); got != 2 {
+		t.Errorf("disconnected after Close = %d, want 2", got)
+	}
+	if got := sink.dropped.Load(); got != 0 {
+		t.Errorf("dropped = %d, want 0", got)
+	}
+}
+
+func TestHub_BroadcastOrder(t *testing.T) {
+	const numClients, numEvents = 10, 1000
+
+	hub := NewHubWithOptions[string](&HubOptions{
+		ClientBufferSize:   numEvents,
+		BroadcastQueueSize: numEvents,
+	})
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	recorders := make([]*httptest.ResponseRecorder, numClients)
+	for i := range recorders {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/events", http.NoBody)
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Fatalf("Upgrade() error = %v", err)
+		}
+		if err := hub.Register(conn); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		recorders[i] = w
+	}
+
+	// Wait for registrations
+	time.Sleep(20 * time.Millisecond)
+
+	var wantBytes uint64
+	for i := 1; i <= numEvents; i++ {
+		data := strconv.Itoa(i)
+		if err := hub.Broadcast(data); err != nil {
+			t.Fatalf("Broadcast(%d) error = %v", i, err)
+		}
+		wantBytes += uint64(numClients * len(NewEvent(data).Bytes()))
+	}
+
+	// Wait for delivery
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.Stats().BytesWritten < wantBytes && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	for i, w := range recorders {
+		last, count := 0, 0
+		for _, line := range strings.Split(w.Body.String(), "\n") {
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+			n, err := strconv.Atoi(data)
+			if err != nil {
+				t.Fatalf("client %d: bad event data %q", i, data)
+			}
+			if n <= last {
+				t.Fatalf("client %d: event %d after %d", i, n, last)
+			}
+			last = n
+			count++
+		}
+		if count != numEvents {
+			t.Errorf("client %d: received %d events, want %d", i, count, numEvents)
+		}
+	}
+}
+
+func TestHub_RetryPolicy(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	hub.SetRetryPolicy(time.Second, 10*time.Second, 2)
+
+	register := func(n int) {
+		for i := 0; i < n; i++ {
+			if err := hub.Register(createHubTestConn(t)); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events", http.NoBody)
+	conn, err := Upgrade(w, r)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	_ = hub.Register(conn)
+	time.Sleep(20 * time.Millisecond)
+
+	// 1 client: base
+	_ = hub.Broadcast("one")
+	time.Sleep(20 * time.Millisecond)
+
+	// 8 clients: three doublings
+	register(7)
+	_ = hub.Broadcast("eight")
+	time.Sleep(20 * time.Millisecond)
+
+	// 32 clients: capped at max
+	register(24)
+	_ = hub.Broadcast("thirty-two")
+	time.Sleep(50 * time.Millisecond)
+
+	// Removing the policy stops sending retry
+	hub.SetRetryPolicy(0, 0, 0)
+	_ = hub.Broadcast("none")
+	time.Sleep(50 * time.Millisecond)
+
+	// Stop Hub before reading (prevents race)
+	_ = hub.Close()
+
+	want := ": connected\n\n" +
+		"retry: 1000\ndata: one\n\n" +
+		"retry: 8000\ndata: eight\n\n" +
+		"retry: 10000\ndata: thirty-two\n\n" +
+		"data: none\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestHub_Stats(t *testing.T) {
+	hub := NewHub[string]()
+	go hub.Run()
+	defer func() { _ = hub.Close() }()
+
+	conn1 := createHubTestConn(t)
+	conn2 := createHubTestConn(t)
+	_ = hub.Register(conn1)
+	_ = hub.Register(conn2)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := hub.Subscribe(conn1, "news"); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	_ = hub.Subscribe(conn2, "news")
+	_ = hub.Subscribe(conn2, "sports")
+
+	_ = hub.Broadcast("hello")
+	_ = hub.Broadcast("world")
+	_ = hub.BroadcastTopic("sports", "goal!")
+	time.Sleep(50 * time.Millisecond)
+
+	stats := hub.Stats()
+	if stats.Clients != 2 {
+		t.Errorf("Clients = %d, want 2", stats.Clients)
+	}
+	if stats.Broadcasts != 3 {
+		t.Errorf("Broadcasts = %d, want 3", stats.Broadcasts)
+	}
+	// Two events of len("data: hello\n\n") to two clients, plus
+	// len("data: goal!\n\n") to one
+	if want := uint64(4*13 + 13); stats.BytesWritten != want {
+		t.Errorf("BytesWritten = %d, want %d", stats.BytesWritten, want)
+	}
+	if stats.Dropped != 0 {
+		t.Errorf("Dropped = %d, want 0", stats.Dropped)
+	}
+	if stats.Topics["news"] != 2 || stats.Topics["sports"] != 1 || len(stats.Topics) != 2 {
+		t.Errorf("Topics = %v, want map[news:2 sports:1]", stats.Topics)
+	}
+
+	_ = hub.Unregister(conn2)
+	time.Sleep(20 * time.Millisecond)
+
+	stats = hub.Stats()
+	if stats.Clients != 1 {
+		t.Errorf("Clients after Unregister = %d, want 1", stats.Clients)
+	}
+	if stats.Topics["news"] != 1 || len(stats.Topics) != 1 {
+		t.Errorf("Topics after Unregister = %v, want map[news:1]", stats.Topics)
+	}
+}
 
 func BenchmarkHub_Broadcast(b *testing.B) {
 	hub := NewHub[string]()
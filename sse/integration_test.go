@@ -212,6 +212,119 @@ func TestIntegration_RealHTTPServer(t *testing.T) {
 	}
 }
 
+// TestIntegration_CommentsIgnored tests that comments reach the wire but are not parsed as events.
+func TestIntegration_CommentsIgnored(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		_ = conn.SendComment("debug")
+		_ = conn.SendData("event1")
+		_ = conn.SendComment("another comment")
+		_ = conn.SendData("event2")
+
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("Wire", func(t *testing.T) {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("ReadAll failed: %v", err)
+		}
+
+		if !strings.Contains(string(body), ": debug\n\n") {
+			t.Errorf("body missing comment, got: %q", body)
+		}
+	})
+
+	t.Run("Parser", func(t *testing.T) {
+		client := newSSEClient(server.URL)
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := client.Connect(ctx); err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		var events []string
+		for event := range client.Events() {
+			events = append(events, event)
+		}
+
+		expected := []string{"event1", "event2"}
+		if len(events) != len(expected) {
+			t.Fatalf("Expected %d events, got %d: %v", len(expected), len(events), events)
+		}
+		for i, want := range expected {
+			if events[i] != want {
+				t.Errorf("Event[%d] = %q, want %q", i, events[i], want)
+			}
+		}
+	})
+}
+
+// TestIntegration_MultiLineData tests that multi-line payloads arrive as a single event.
+func TestIntegration_MultiLineData(t *testing.T) {
+	payloads := []string{
+		"line1\nline2",
+		"{\n  \"user\": \"Alice\"\n}",
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		for _, p := range payloads {
+			_ = conn.SendData(p)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := newSSEClient(server.URL)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	for i, want := range payloads {
+		select {
+		case event := <-client.Events():
+			if event != want {
+				t.Errorf("Event[%d] = %q, want %q", i, event, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for event %d", i)
+		}
+	}
+}
+
 // TestIntegration_MultipleClients tests broadcasting to multiple concurrent clients.
 func TestIntegration_MultipleClients(t *testing.T) {
 	const numClients = 10
@@ -1040,6 +1153,64 @@ func BenchmarkIntegration_HighFrequency(b *testing.B) {
 	time.Sleep(time.Duration(b.N) * time.Millisecond)
 }
 
+// BenchmarkIntegration_FlushPerEvent benchmarks streaming with a flush after every event.
+func BenchmarkIntegration_FlushPerEvent(b *testing.B) {
+	benchmarkIntegrationBatch(b, 1)
+}
+
+// BenchmarkIntegration_BatchedFlush benchmarks streaming with SendNoFlush and
+// one Flush per 100 events.
+func BenchmarkIntegration_BatchedFlush(b *testing.B) {
+	benchmarkIntegrationBatch(b, 100)
+}
+
+// benchmarkIntegrationBatch streams b.N events over a real connection,
+// flushing every batch events, and measures time until the client has read
+// them all.
+func benchmarkIntegrationBatch(b *testing.B, batch int) {
+	event := NewEvent("benchmark event payload").WithType("tick")
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		for i := 0; i < b.N; i++ {
+			if batch == 1 {
+				err = conn.Send(event)
+			} else {
+				err = conn.SendNoFlush(event)
+				if err == nil && (i+1)%batch == 0 {
+					err = conn.Flush()
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+		_ = conn.Flush()
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		b.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		b.Fatalf("read failed: %v", err)
+	}
+}
+
 // BenchmarkIntegration_LargeEvent benchmarks 1MB event transfer.
 func BenchmarkIntegration_LargeEvent(b *testing.B) {
 	const dataSize = 1 * 1024 * 1024 // 1 MB
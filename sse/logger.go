@@ -0,0 +1,30 @@
+package sse
+
+// Logger receives messages about problems the package handles internally
+// and can't return to the caller, such as a client dropped by a Hub.
+//
+// Its methods match *slog.Logger, so slog.Default() (or any *slog.Logger)
+// can be passed directly; args are alternating key-value pairs. Set it via
+// HubOptions.Logger or UpgradeOptions.Logger (default: discard).
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// nopLogger discards all messages.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// log returns the connection's logger (discarding if none is set).
+func (c *Conn) log() Logger {
+	if c.logger == nil {
+		return nopLogger{}
+	}
+	return c.logger
+}
@@ -0,0 +1,382 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxErrorBodySize limits how much of a failed handshake's response body
+// Dial reads for the caller.
+const maxErrorBodySize = 64 << 10
+
+// DialOptions configures a WebSocket client connection.
+//
+// All fields are optional. Zero values use sensible defaults.
+type DialOptions struct {
+	// Header adds headers to the handshake request (e.g. Authorization).
+	Header http.Header
+
+	// Subprotocols is the list of subprotocols requested, in preference
+	// order (Sec-WebSocket-Protocol). The server's choice is available via
+	// Conn.Subprotocol; Dial fails if the server selects one not listed.
+	Subprotocols []string
+
+	// HandshakeTimeout bounds connecting and the opening handshake
+	// (default: no limit beyond the context).
+	HandshakeTimeout time.Duration
+
+	// FollowRedirect follows a single 3xx redirect to the Location URL.
+	// http and https locations are mapped to ws and wss.
+	FollowRedirect bool
+
+	// TLSClientConfig is used for wss:// connections (default: zero config
+	// with ServerName set from the URL).
+	TLSClientConfig *tls.Config
+
+	// NetDial opens the TCP connection, e.g. to route through a proxy or
+	// a Unix socket (default: net.Dialer.DialContext). addr is the URL's
+	// host:port; for wss, TLS is layered on the returned connection.
+	NetDial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// ReadBufferSize sets size of read buffer (default: 4096).
+	ReadBufferSize int
+
+	// WriteBufferSize sets size of write buffer (default: 4096).
+	WriteBufferSize int
+
+	// MaxMessageSize limits the size of an assembled message in bytes,
+	// across all its fragments (default: 32 MB). See
+	// Conn.SetMaxMessageSize.
+	MaxMessageSize int64
+
+	// MaxHeaderBytes limits the size of the handshake response's status
+	// line and headers (default: 1 MB). Larger responses fail with
+	// ErrHandshakeTooLarge, so a hostile server can't exhaust memory with
+	// endless headers.
+	MaxHeaderBytes int
+
+	// Extensions lists the extensions offered, in preference order
+	// (Sec-WebSocket-Extensions). Those the server accepts are available
+	// via Conn.Extensions and implemented with ReadFrame and WriteFrame;
+	// Dial fails if the server selects one not listed. See Extension.
+	Extensions []Extension
+
+	// Lenient relaxes validations that noncompliant peers (or proxies in
+	// between) are known to trip, for compatibility (default: false, full
+	// RFC 6455 validation). When set:
+	//   - Frames with the wrong MASK bit are accepted (RFC 6455 Section
+	//     5.1 fails the connection with 1002).
+	//   - Close frames with a status code that must not be sent, such as
+	//     1005 or 5000, or a 1-byte payload are accepted; Read reports the
+	//     code as received and the close is answered with 1000
+	//     (Section 7.4 fails the connection with 1002 and
+	//     ErrInvalidCloseCode).
+	Lenient bool
+
+	// IdleTimeout closes the connection with 1001 (Going Away) after this
+	// long without a message read or written (default: 0, disabled). See
+	// Conn.SetIdleTimeout.
+	IdleTimeout time.Duration
+
+	// Logger receives errors the connection handles internally, such as
+	// a failed auto-pong (default: discard).
+	Logger Logger
+}
+
+// Dial connects to a WebSocket server and performs the opening handshake.
+//
+// Implements the client side of RFC 6455 Section 4.1. The URL scheme must
+// be ws or wss. ctx bounds connecting and the handshake; it doesn't affect
+// the returned connection.
+//
+// The handshake response is always returned when one was received. If the
+// server doesn't switch protocols, Dial returns an error wrapping
+// ErrBadHandshake along with the response, whose body (up to 64 KB) can
+// still be read, e.g. to see why authentication failed.
+//
+// Example:
+//
+//	conn, resp, err := websocket.Dial(ctx, "wss://example.com/ws", &websocket.DialOptions{
+//	    Header: http.Header{"Authorization": {"Bearer " + token}},
+//	})
+//	if err != nil {
+//	    if resp != nil {
+//	        body, _ := io.ReadAll(resp.Body)
+//	        log.Printf("handshake failed: %s: %s", resp.Status, body)
+//	    }
+//	    return err
+//	}
+//	defer conn.Close()
+func Dial(ctx context.Context, rawURL string, opts *DialOptions) (*Conn, *http.Response, error) {
+	if opts == nil {
+		opts = &DialOptions{}
+	}
+
+	if opts.HandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.HandshakeTimeout)
+		defer cancel()
+	}
+
+	u, err := parseWebSocketURL(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, resp, err := dial(ctx, u, opts)
+	if err == nil || !opts.FollowRedirect || !isRedirect(resp) {
+		return conn, resp, err
+	}
+
+	// Follow a single redirect (Location may be relative)
+	location, lerr := u.Parse(resp.Header.Get("Location"))
+	if lerr != nil {
+		return nil, resp, fmt.Errorf("%w: redirect: %w", ErrBadHandshake, lerr)
+	}
+	u, err = parseWebSocketURL(location.String())
+	if err != nil {
+		return nil, resp, fmt.Errorf("redirect: %w", err)
+	}
+	return dial(ctx, u, opts)
+}
+
+// parseWebSocketURL parses a ws or wss URL. http and https are accepted
+// as aliases (as sent in redirect locations).
+func parseWebSocketURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: invalid URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "ws", "wss":
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return nil, fmt.Errorf("websocket: invalid URL scheme %q (want ws or wss)", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("websocket: missing host in URL %q", rawURL)
+	}
+	return u, nil
+}
+
+// handshakeLimitReader fails reads with ErrHandshakeTooLarge once
+// remaining bytes have been read. A negative remaining means no limit.
+type handshakeLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *handshakeLimitReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return l.r.Read(p)
+	}
+	if l.remaining == 0 {
+		return 0, ErrHandshakeTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// isRedirect reports whether resp is a redirect with a Location.
+func isRedirect(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return resp.Header.Get("Location") != ""
+	}
+	return false
+}
+
+// dial connects to u and performs the handshake.
+//
+//nolint:gocyclo,cyclop // Handshake requires many validation steps per RFC 6455
+func dial(ctx context.Context, u *url.URL, opts *DialOptions) (*Conn, *http.Response, error) {
+	// Connect (default ports per RFC 6455 Section 3)
+	host := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "wss" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	netDial := opts.NetDial
+	if netDial == nil {
+		var d net.Dialer
+		netDial = d.DialContext
+	}
+	netConn, err := netDial(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket: dial: %w", err)
+	}
+
+	// Bound the handshake by ctx
+	stop := context.AfterFunc(ctx, func() {
+		_ = netConn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if u.Scheme == "wss" {
+		cfg := opts.TLSClientConfig
+		if cfg == nil {
+			cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(netConn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = netConn.Close()
+			return nil, nil, fmt.Errorf("websocket: TLS handshake: %w", err)
+		}
+		netConn = tlsConn
+	}
+
+	// Generate Sec-WebSocket-Key (RFC 6455 Section 4.1: 16 random bytes)
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		_ = netConn.Close()
+		return nil, nil, fmt.Errorf("websocket: generate key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	// Build handshake request
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	for name, values := range opts.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if protocols := FormatSubprotocols(opts.Subprotocols); protocols != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", protocols)
+	}
+	if extensions := formatExtensionNames(opts.Extensions); extensions != "" {
+		req.Header.Set("Sec-WebSocket-Extensions", extensions)
+	}
+
+	if err := req.Write(netConn); err != nil {
+		_ = netConn.Close()
+		return nil, nil, fmt.Errorf("websocket: write handshake: %w", err)
+	}
+
+	// Read response
+	readBufferSize := opts.ReadBufferSize
+	if readBufferSize <= 0 {
+		readBufferSize = defaultReadBufferSize
+	}
+	maxHeaderBytes := opts.MaxHeaderBytes
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = defaultMaxHeaderBytes
+	}
+	// The limit covers the headers only; the buffer may read a little
+	// further, so allow for a full buffer on top, as net/http does.
+	limiter := &handshakeLimitReader{r: netConn, remaining: int64(maxHeaderBytes + readBufferSize)}
+	reader := bufio.NewReaderSize(limiter, readBufferSize)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, nil, fmt.Errorf("websocket: read handshake response: %w", err)
+	}
+	limiter.remaining = -1 // Headers read; the body and frames are unlimited
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// Keep the body readable after the connection is closed
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		_ = netConn.Close()
+		return nil, resp, fmt.Errorf("%w: status %s", ErrBadHandshake, resp.Status)
+	}
+
+	// Verify response headers (RFC 6455 Section 4.1, client requirements)
+	subprotocol := strings.TrimSpace(resp.Header.Get("Sec-WebSocket-Protocol"))
+	extensions := parseExtensionNames(resp.Header.Values("Sec-WebSocket-Extensions"))
+	extensionRSV, extErr := acceptedExtensions(extensions, opts.Extensions)
+	switch {
+	case !headerContainsToken(resp.Header.Get("Upgrade"), "websocket"):
+		err = fmt.Errorf("%w: invalid Upgrade header %q", ErrBadHandshake, resp.Header.Get("Upgrade"))
+	case !headerContainsToken(resp.Header.Get("Connection"), "upgrade"):
+		err = fmt.Errorf("%w: invalid Connection header %q", ErrBadHandshake, resp.Header.Get("Connection"))
+	case resp.Header.Get("Sec-WebSocket-Accept") != ComputeAcceptKey(key):
+		err = fmt.Errorf("%w: invalid Sec-WebSocket-Accept", ErrBadHandshake)
+	case subprotocol != "" && !slices.Contains(opts.Subprotocols, subprotocol):
+		// RFC 6455 Section 4.1: Server may only select an offered subprotocol
+		err = fmt.Errorf("%w: server selected unrequested subprotocol %q", ErrBadHandshake, subprotocol)
+	case extErr != nil:
+		// RFC 6455 Section 4.1: Server may only select offered extensions
+		err = extErr
+	case len(extensions) == 0 && resp.Header.Get("Sec-WebSocket-Extensions") != "":
+		err = fmt.Errorf("%w: invalid Sec-WebSocket-Extensions header %q", ErrBadHandshake, resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+	if err != nil {
+		_ = netConn.Close()
+		return nil, resp, err
+	}
+
+	// Handshake done; ctx no longer applies
+	if !stop() {
+		_ = netConn.Close()
+		return nil, resp, fmt.Errorf("websocket: handshake: %w", context.Cause(ctx))
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	writeBufferSize := opts.WriteBufferSize
+	if writeBufferSize <= 0 {
+		writeBufferSize = defaultWriteBufferSize
+	}
+	writer := bufio.NewWriterSize(netConn, writeBufferSize)
+
+	// Create WebSocket connection (client-side)
+	conn := newConn(netConn, reader, writer, false)
+	conn.subprotocol = subprotocol
+	conn.extensions = extensions
+	conn.extensionRSV = extensionRSV
+	conn.logger = opts.Logger
+	conn.lenient = opts.Lenient
+	if opts.MaxMessageSize > 0 {
+		conn.SetMaxMessageSize(opts.MaxMessageSize)
+	}
+	if opts.IdleTimeout > 0 {
+		conn.SetIdleTimeout(opts.IdleTimeout)
+	}
+	return conn, resp, nil
+}
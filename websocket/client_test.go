@@ -1,160 +1,335 @@
 package websocket
 
 import (
-	"bufio"
 	"context"
-	"crypto/rand"
-	"encoding/base64"
-	"fmt"
+	"errors"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
 )
 
-// DialOptions contains options for WebSocket client connection.
-type DialOptions struct {
-	Header           http.Header
-	Subprotocols     []string
-	CheckOrigin      bool
-	HandshakeTimeout int // seconds
+// dialTestServer is a helper function for tests to dial a test server.
+func dialTestServer(tb interface {
+	Helper()
+	Fatalf(string, ...any)
+}, server *httptest.Server) *Conn {
+	tb.Helper()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, resp, err := Dial(context.Background(), wsURL, nil)
+	if err != nil {
+		tb.Fatalf("Dial error: %v", err)
+	}
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	return conn
+}
+
+// newTestServer is a helper to create test HTTP server with WebSocket handler.
+func newTestServer(tb interface{ Helper() }, handler func(*Conn)) *httptest.Server {
+	tb.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}))
+
+	return server
 }
 
-// Dial connects to a WebSocket server and performs the opening handshake.
-//
-// This is a simple client implementation for testing purposes.
-// For production use, consider using a full-featured WebSocket client library.
-func Dial(_ context.Context, url string, opts *DialOptions) (*Conn, *http.Response, error) {
-	if opts == nil {
-		opts = &DialOptions{}
+// TestDial_HandshakeErrorBody tests that a rejected handshake returns the
+// response with a readable body.
+func TestDial_HandshakeErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"error":"invalid token"}`)
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, resp, err := Dial(context.Background(), wsURL, nil)
+	if conn != nil {
+		conn.Close()
+		t.Fatal("Dial succeeded, want error")
+	}
+	if !errors.Is(err, ErrBadHandshake) {
+		t.Fatalf("Dial error = %v, want ErrBadHandshake", err)
 	}
+	if resp == nil {
+		t.Fatal("Dial returned nil response")
+	}
+	defer resp.Body.Close()
 
-	// Parse URL
-	if !strings.HasPrefix(url, "ws://") && !strings.HasPrefix(url, "wss://") {
-		return nil, nil, fmt.Errorf("invalid WebSocket URL scheme: %s", url)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("StatusCode = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
 	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if got := string(body); got != `{"error":"invalid token"}` {
+		t.Errorf("body = %q, want JSON error", got)
+	}
+}
 
-	// For testing, only support ws:// (not wss://)
-	if strings.HasPrefix(url, "wss://") {
-		return nil, nil, fmt.Errorf("wss:// not supported in test client")
+// TestDial_FollowRedirect tests that Dial follows a redirect only when
+// FollowRedirect is set.
+func TestDial_FollowRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ws", http.StatusFound)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteText("hello")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/old"
+
+	_, resp, err := Dial(context.Background(), wsURL, nil)
+	if !errors.Is(err, ErrBadHandshake) {
+		t.Fatalf("Dial without FollowRedirect error = %v, want ErrBadHandshake", err)
+	}
+	if resp == nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("response = %v, want 302", resp)
 	}
+	resp.Body.Close()
 
-	// Extract host and path
-	url = strings.TrimPrefix(url, "ws://")
-	parts := strings.SplitN(url, "/", 2)
-	host := parts[0]
-	path := "/"
-	if len(parts) > 1 {
-		path = "/" + parts[1]
+	conn, resp, err := Dial(context.Background(), wsURL, &DialOptions{FollowRedirect: true})
+	if err != nil {
+		t.Fatalf("Dial with FollowRedirect error: %v", err)
+	}
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("StatusCode = %d, want 101", resp.StatusCode)
 	}
 
-	// Connect to server
-	conn, err := net.Dial("tcp", host)
+	text, err := conn.ReadText()
 	if err != nil {
-		return nil, nil, fmt.Errorf("dial failed: %w", err)
+		t.Fatalf("ReadText error: %v", err)
+	}
+	if text != "hello" {
+		t.Errorf("ReadText = %q, want %q", text, "hello")
 	}
+}
 
-	// Generate WebSocket key
-	key := make([]byte, 16)
-	if _, err := rand.Read(key); err != nil {
-		conn.Close()
-		return nil, nil, fmt.Errorf("generate key: %w", err)
+// TestDial_InvalidScheme tests that non-WebSocket URLs are rejected.
+func TestDial_InvalidScheme(t *testing.T) {
+	_, _, err := Dial(context.Background(), "ftp://example.com/ws", nil)
+	if err == nil {
+		t.Fatal("Dial succeeded, want error")
 	}
-	wsKey := base64.StdEncoding.EncodeToString(key)
+}
+
+// TestDial_Subprotocol tests that the server's subprotocol choice is
+// available on both ends.
+func TestDial_Subprotocol(t *testing.T) {
+	serverProto := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, &UpgradeOptions{Subprotocols: []string{"chat.v2", "chat.v1"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serverProto <- conn.Subprotocol()
+	}))
+	defer server.Close()
 
-	// Build handshake request
-	req := fmt.Sprintf("GET %s HTTP/1.1\r\n", path)
-	req += fmt.Sprintf("Host: %s\r\n", host)
-	req += "Upgrade: websocket\r\n"
-	req += "Connection: Upgrade\r\n"
-	req += fmt.Sprintf("Sec-WebSocket-Key: %s\r\n", wsKey)
-	req += "Sec-WebSocket-Version: 13\r\n"
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := Dial(context.Background(), wsURL, &DialOptions{Subprotocols: []string{"chat.v1"}})
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer conn.Close()
 
-	if len(opts.Subprotocols) > 0 {
-		req += "Sec-WebSocket-Protocol: " + strings.Join(opts.Subprotocols, ", ") + "\r\n"
+	if got := conn.Subprotocol(); got != "chat.v1" {
+		t.Errorf("client Subprotocol() = %q, want %q", got, "chat.v1")
 	}
+	if got := <-serverProto; got != "chat.v1" {
+		t.Errorf("server Subprotocol() = %q, want %q", got, "chat.v1")
+	}
+}
 
-	// Add custom headers
-	if opts.Header != nil {
-		for key, values := range opts.Header {
-			for _, value := range values {
-				req += fmt.Sprintf("%s: %s\r\n", key, value)
-			}
-		}
+// TestDial_Options tests each DialOptions field against a TLS server.
+func TestDial_Options(t *testing.T) {
+	type handshake struct {
+		auth, proto string
 	}
+	got := make(chan handshake, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, &UpgradeOptions{Subprotocols: []string{"chat.v1"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		got <- handshake{auth: r.Header.Get("Authorization"), proto: conn.Subprotocol()}
 
-	req += "\r\n"
+		msgType, data, err := conn.Read()
+		if err == nil {
+			_ = conn.Write(msgType, data)
+		}
+	}))
+	defer server.Close()
 
-	// Send handshake
-	if _, err := conn.Write([]byte(req)); err != nil {
-		conn.Close()
-		return nil, nil, fmt.Errorf("write handshake: %w", err)
+	var dials atomic.Int32
+	opts := &DialOptions{
+		Header:           http.Header{"Authorization": {"Bearer token"}},
+		Subprotocols:     []string{"chat.v2", "chat.v1"},
+		HandshakeTimeout: 5 * time.Second,
+		TLSClientConfig:  server.Client().Transport.(*http.Transport).TLSClientConfig,
+		NetDial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials.Add(1)
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+		ReadBufferSize:  512,
+		WriteBufferSize: 512,
 	}
 
-	// Read response
-	reader := bufio.NewReader(conn)
-	resp, err := http.ReadResponse(reader, &http.Request{Method: "GET"})
+	wsURL := "wss" + strings.TrimPrefix(server.URL, "https")
+	conn, _, err := Dial(context.Background(), wsURL, opts)
 	if err != nil {
-		conn.Close()
-		return nil, nil, fmt.Errorf("read response: %w", err)
+		t.Fatalf("Dial error: %v", err)
 	}
-	defer resp.Body.Close()
+	defer conn.Close()
 
-	// Verify status code
-	if resp.StatusCode != http.StatusSwitchingProtocols {
-		conn.Close()
-		return nil, resp, fmt.Errorf("handshake failed: status %d", resp.StatusCode)
+	if n := dials.Load(); n != 1 {
+		t.Errorf("NetDial called %d times, want 1", n)
 	}
-
-	// Verify Upgrade header
-	if strings.ToLower(resp.Header.Get("Upgrade")) != "websocket" {
-		conn.Close()
-		return nil, resp, fmt.Errorf("invalid Upgrade header: %s", resp.Header.Get("Upgrade"))
+	if h := <-got; h.auth != "Bearer token" || h.proto != "chat.v1" {
+		t.Errorf("server saw Authorization %q, subprotocol %q, want %q, %q", h.auth, h.proto, "Bearer token", "chat.v1")
 	}
-
-	// Create WebSocket connection
-	wsConn := &Conn{
-		conn:     conn,
-		reader:   reader,
-		writer:   bufio.NewWriter(conn),
-		isServer: false, // This is a client connection
+	if proto := conn.Subprotocol(); proto != "chat.v1" {
+		t.Errorf("Subprotocol() = %q, want %q", proto, "chat.v1")
 	}
 
-	return wsConn, resp, nil
-}
+	// Larger than the buffers
+	msg := strings.Repeat("x", 2000)
+	if err := conn.WriteText(msg); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if reply, err := conn.ReadText(); err != nil || reply != msg {
+		t.Errorf("ReadText() = %d bytes, %v, want echo of %d bytes", len(reply), err, len(msg))
+	}
 
-// dialTestServer is a helper function for tests to dial a test server.
-func dialTestServer(tb interface {
-	Helper()
-	Fatalf(string, ...any)
-}, server *httptest.Server) *Conn {
-	tb.Helper()
+	// NetDial errors are returned
+	opts.NetDial = func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("no route")
+	}
+	if _, _, err := Dial(context.Background(), wsURL, opts); err == nil || !strings.Contains(err.Error(), "no route") {
+		t.Errorf("Dial() with failing NetDial error = %v, want it wrapped", err)
+	}
 
-	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
-	conn, resp, err := Dial(context.Background(), wsURL, nil)
+	// HandshakeTimeout bounds a server that never answers
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		tb.Fatalf("Dial error: %v", err)
+		t.Fatalf("Listen error: %v", err)
 	}
-	if resp != nil && resp.Body != nil {
-		defer resp.Body.Close()
+	defer silent.Close()
+	start := time.Now()
+	_, _, err = Dial(context.Background(), "ws://"+silent.Addr().String(), &DialOptions{HandshakeTimeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("Dial() to silent server succeeded, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Dial() returned after %v, want HandshakeTimeout", elapsed)
 	}
-
-	return conn
 }
 
-// newTestServer is a helper to create test HTTP server with WebSocket handler.
-func newTestServer(tb interface{ Helper() }, handler func(*Conn)) *httptest.Server {
-	tb.Helper()
+// TestDial_UnrequestedSubprotocol tests that the handshake fails when the
+// server selects a subprotocol or extension the client didn't offer.
+func TestDial_UnrequestedSubprotocol(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"subprotocol", "Sec-WebSocket-Protocol: other\r\n"},
+		{"extension", "Sec-WebSocket-Extensions: permessage-deflate\r\n"},
+	}
 
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				netConn, bufrw, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					return
+				}
+				defer netConn.Close()
+
+				_, _ = bufrw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+					"Upgrade: websocket\r\n" +
+					"Connection: Upgrade\r\n" +
+					"Sec-WebSocket-Accept: " + ComputeAcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n" +
+					tt.header + "\r\n")
+				_ = bufrw.Flush()
+			}))
+			defer server.Close()
+
+			wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+			conn, _, err := Dial(context.Background(), wsURL, &DialOptions{Subprotocols: []string{"chat"}})
+			if conn != nil {
+				conn.Close()
+			}
+			if !errors.Is(err, ErrBadHandshake) {
+				t.Errorf("Dial error = %v, want ErrBadHandshake", err)
+			}
+		})
+	}
+}
+
+// TestDial_HandshakeTooLarge tests that Dial stops reading a handshake
+// response whose headers exceed DialOptions.MaxHeaderBytes.
+func TestDial_HandshakeTooLarge(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		conn, err := Upgrade(w, r, nil)
+		netConn, bufrw, err := w.(http.Hijacker).Hijack()
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		defer conn.Close()
-		handler(conn)
+		defer netConn.Close()
+
+		// Headers that never end
+		_, _ = bufrw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		pad := "X-Pad: " + strings.Repeat("a", 1000) + "\r\n"
+		for range 1024 {
+			if _, err := bufrw.WriteString(pad); err != nil {
+				return
+			}
+		}
+		_ = bufrw.Flush()
 	}))
+	defer server.Close()
 
-	return server
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := Dial(context.Background(), wsURL, &DialOptions{MaxHeaderBytes: 16 << 10})
+	if conn != nil {
+		conn.Close()
+	}
+	if !errors.Is(err, ErrHandshakeTooLarge) {
+		t.Fatalf("Dial() error = %v, want ErrHandshakeTooLarge", err)
+	}
+
+	// The default limit still admits an ordinary handshake
+	ok := newTestServer(t, func(conn *Conn) {})
+	defer ok.Close()
+	conn = dialTestServer(t, ok)
+	conn.Close()
 }
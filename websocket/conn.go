@@ -3,9 +3,17 @@ package websocket
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unicode/utf8"
 )
 
@@ -41,6 +49,21 @@ type Conn struct {
 
 	isServer bool // Server-side connection (affects masking rules)
 
+	subprotocol string // Negotiated subprotocol ("" if none)
+	clientIP    string // Client's IP address (server side only)
+
+	// logger receives internal errors (nil = discard, see log)
+	logger Logger
+
+	// Negotiated extensions and the union of their RSV bits, which
+	// ReadFrame and WriteFrame accept (see Extension)
+	extensions   []string
+	extensionRSV byte
+
+	// lenient relaxes masking and close code validation (see
+	// UpgradeOptions.Lenient).
+	lenient bool
+
 	// Write synchronization (RFC 6455 Section 5.1)
 	// "An endpoint MUST NOT send a data frame while a fragmented message is being transmitted"
 	writeMu sync.Mutex
@@ -50,23 +73,158 @@ type Conn struct {
 	closed    bool
 	closeMu   sync.RWMutex
 
+	// stopContext stops closing on context cancellation (nil if the
+	// connection isn't tied to a context). Protected by closeMu.
+	stopContext func() bool
+
 	// Fragment reassembly state
 	fragmentBuf  bytes.Buffer // Accumulates fragmented message
 	fragmentType byte         // Opcode of first fragment (text/binary)
 	inFragment   bool         // Currently reading fragmented message
+
+	// activeReader is the message being streamed by NextReader (nil if
+	// none). Owned by the reader.
+	activeReader *messageReader
+
+	// messagesErr is the error that ended the last Messages loop (see
+	// Err). Owned by the reader.
+	messagesErr error
+
+	// Per-connection counters (see Stats)
+	stats connStats
+
+	// flushMode controls when data frames are flushed (protected by writeMu)
+	flushMode FlushMode
+
+	// readBudget caps the data payload bytes read over the connection's
+	// lifetime (0 = unlimited); readUsed is owned by the reader.
+	readBudget atomic.Int64
+	readUsed   int64
+
+	// maxMessageSize caps the assembled size of one data message
+	// (0 = unlimited)
+	maxMessageSize atomic.Int64
+
+	// fragmentTimeout bounds how long a fragmented message may take (a
+	// time.Duration, 0 = unlimited); fragmentDeadline is when the message
+	// in progress must be complete (zero if none). The deadline is owned
+	// by the reader.
+	fragmentTimeout  atomic.Int64
+	fragmentDeadline time.Time
+
+	// idleTimeout closes the connection after this long without a message
+	// read or written (a time.Duration, 0 = disabled); lastActivity is the
+	// time of the last one, in Unix nanoseconds. idleTimer is protected by
+	// idleMu (nil if disabled).
+	idleTimeout  atomic.Int64
+	lastActivity atomic.Int64
+	idleMu       sync.Mutex
+	idleTimer    *time.Timer
+
+	// tracer is called for every frame read or written (nil if none)
+	tracer atomic.Pointer[Tracer]
+
+	// masks generates masking keys for client frames (nil = crypto/rand)
+	masks maskSource
+
+	// Pending PingWithTimeout calls, keyed by ping payload (see ping.go)
+	pingMu  sync.Mutex
+	pings   map[string]chan struct{}
+	pingSeq atomic.Uint64
+
+	// Application values set with SetMetadata
+	metaMu   sync.RWMutex
+	metadata map[string]any
 }
 
+// maskSource generates masking keys for client-to-server frames.
+//
+// RFC 6455 Section 5.3: The masking key MUST be derived from a strong
+// source of entropy, so the default is crypto/rand. Tests substitute a
+// fixed source for reproducible frames.
+type maskSource interface {
+	newMask() [4]byte
+}
+
+// randMaskSource draws masking keys from crypto/rand.
+type randMaskSource struct{}
+
+func (randMaskSource) newMask() [4]byte {
+	var key [4]byte
+	_, _ = rand.Read(key[:]) // Never fails (crypto/rand.Read panics instead)
+	return key
+}
+
+// newMask returns a masking key for the next client frame.
+func (c *Conn) newMask() [4]byte {
+	if c.masks == nil {
+		return randMaskSource{}.newMask()
+	}
+	return c.masks.newMask()
+}
+
+// Connection is the message-level API of a WebSocket connection.
+//
+// *Conn implements it. Application code that accepts a Connection instead
+// of *Conn can be unit-tested with a fake, without a network connection.
+// Upgrade and Dial still return *Conn.
+//
+// Example:
+//
+//	func serveEcho(conn websocket.Connection) error {
+//	    for {
+//	        msgType, data, err := conn.Read()
+//	        if err != nil {
+//	            return err
+//	        }
+//	        if err := conn.Write(msgType, data); err != nil {
+//	            return err
+//	        }
+//	    }
+//	}
+type Connection interface {
+	Read() (MessageType, []byte, error)
+	Write(messageType MessageType, data []byte) error
+	WriteText(text string) error
+	WriteJSON(v any) error
+	Ping(data []byte) error
+	Pong(data []byte) error
+	Close() error
+	CloseWithCode(code CloseCode, reason string) error
+}
+
+// Compile-time check that *Conn implements Connection.
+var _ Connection = (*Conn)(nil)
+
+// FlushMode controls when a Conn flushes data messages to the network.
+type FlushMode int
+
+const (
+	// FlushImmediate flushes after every Write (default).
+	// Best for latency.
+	FlushImmediate FlushMode = iota
+
+	// FlushManual buffers Writes until Flush is called or the write buffer
+	// fills. Best for throughput when writing bursts of small messages.
+	// Control frames (ping, pong, close) are always flushed immediately,
+	// along with any buffered messages.
+	FlushManual
+)
+
 // newConn creates a new WebSocket connection (internal constructor).
 //
 // Called by Upgrade() after successful handshake.
 // Not exported - users should call Upgrade() to create connections.
 func newConn(netConn net.Conn, reader *bufio.Reader, writer *bufio.Writer, isServer bool) *Conn {
-	return &Conn{
+	c := &Conn{
 		conn:     netConn,
 		reader:   reader,
 		writer:   writer,
 		isServer: isServer,
 	}
+	c.stats.connectedAt = time.Now()
+	c.maxMessageSize.Store(defaultMaxMessageSize)
+	return c
 }
 
 // Read reads the next complete message from the connection.
@@ -79,7 +237,7 @@ func newConn(netConn net.Conn, reader *bufio.Reader, writer *bufio.Writer, isSer
 // Returns:
 //   - MessageType: TextMessage or BinaryMessage
 //   - []byte: Complete message payload
-//   - error: ErrClosed if connection closed, protocol errors, network errors
+//   - error: *CloseError if peer closed, ErrClosed if already closed, protocol errors, network errors
 //
 // Thread-Safety: Safe for concurrent reads (each goroutine gets separate message).
 //
@@ -87,9 +245,39 @@ func newConn(netConn net.Conn, reader *bufio.Reader, writer *bufio.Writer, isSer
 // the FIN bit clear and an opcode other than 0, followed by zero or more frames
 // with the FIN bit clear and the opcode set to 0, and terminated by a single
 // frame with the FIN bit set and an opcode of 0."
+func (c *Conn) Read() (MessageType, []byte, error) {
+	return c.read(context.Background())
+}
+
+// ReadContext reads the next complete message, like Read, but returns
+// ctx.Err() if ctx is canceled while waiting for a frame.
+//
+// Cancellation takes effect between frames: ReadContext waits for the next
+// frame with a read deadline tied to ctx, and a frame that has started to
+// arrive is read to completion. The connection stays usable after a
+// canceled ReadContext; a fragmented message in progress is kept, and a
+// later Read or ReadContext resumes it.
+//
+// Example:
+//
+//	for {
+//	    msgType, data, err := conn.ReadContext(ctx)
+//	    if errors.Is(err, context.Canceled) {
+//	        return // Shutting down
+//	    }
+//	    if err != nil {
+//	        return
+//	    }
+//	    _ = conn.Write(msgType, data)
+//	}
+func (c *Conn) ReadContext(ctx context.Context) (MessageType, []byte, error) {
+	return c.read(ctx)
+}
+
+// read implements Read and ReadContext.
 //
 //nolint:gocyclo,cyclop,gocognit // Complex fragmentation+control frame handling per RFC 6455
-func (c *Conn) Read() (MessageType, []byte, error) {
+func (c *Conn) read(ctx context.Context) (MessageType, []byte, error) {
 	c.closeMu.RLock()
 	if c.closed {
 		c.closeMu.RUnlock()
@@ -97,39 +285,24 @@ func (c *Conn) Read() (MessageType, []byte, error) {
 	}
 	c.closeMu.RUnlock()
 
+	// Drop the rest of a message left unread by NextReader
+	if err := c.discardMessage(); err != nil {
+		return 0, nil, err
+	}
+
 	for {
-		// Read next frame
-		f, err := readFrame(c.reader)
+		// Read next data frame (control frames are handled in between)
+		f, err := c.nextFragment(ctx)
 		if err != nil {
 			return 0, nil, err
 		}
 
-		// Handle control frames (RFC 6455 Section 5.5)
-		// Control frames MAY be injected in the middle of a fragmented message
-		switch f.opcode {
-		case opcodePing:
-			// Auto-respond to Ping with Pong (echo application data)
-			if err := c.Pong(f.payload); err != nil {
-				return 0, nil, err
-			}
-			continue // Continue reading data frames
-
-		case opcodePong:
-			// Pong received (unsolicited or response to our Ping)
-			// No action needed, just continue
-			continue
-
-		case opcodeClose:
-			// Close frame received
-			// RFC 6455 Section 5.5.1: Parse status code + reason
-			c.handleCloseFrame(f.payload)
-			return 0, nil, ErrClosed
-		}
-
-		// Data frames: Text, Binary, Continuation
 		switch f.opcode {
 		case opcodeText, opcodeBinary:
 			// First frame of message (or unfragmented message)
+			if err := c.checkMessageSize(len(f.payload)); err != nil {
+				return 0, nil, err
+			}
 			if f.fin {
 				// Unfragmented message - return immediately
 				msgType := MessageType(f.opcode)
@@ -140,10 +313,13 @@ func (c *Conn) Read() (MessageType, []byte, error) {
 					return 0, nil, ErrInvalidUTF8
 				}
 
+				c.stats.recordRead(len(f.payload))
+				c.touch()
 				return msgType, f.payload, nil
 			}
 
 			// Start of fragmented message (FIN=0)
+			c.startFragments()
 			c.inFragment = true
 			c.fragmentType = f.opcode
 			c.fragmentBuf.Reset()
@@ -157,12 +333,19 @@ func (c *Conn) Read() (MessageType, []byte, error) {
 				return 0, nil, ErrUnexpectedContinuation
 			}
 
+			// Checked before appending, so the buffer never grows past
+			// the limit
+			if err := c.checkMessageSize(c.fragmentBuf.Len() + len(f.payload)); err != nil {
+				return 0, nil, err
+			}
+
 			// Append to fragment buffer
 			c.fragmentBuf.Write(f.payload)
 
 			if f.fin {
 				// Final fragment - assemble and return
 				c.inFragment = false
+				c.fragmentDeadline = time.Time{}
 				msgType := MessageType(c.fragmentType)
 				payload := c.fragmentBuf.Bytes()
 
@@ -175,16 +358,187 @@ func (c *Conn) Read() (MessageType, []byte, error) {
 				// Return copy (fragmentBuf will be reused)
 				result := make([]byte, len(payload))
 				copy(result, payload)
+				c.stats.recordRead(len(result))
+				c.touch()
 				return msgType, result, nil
 			}
 		}
 
-		// Loop continues for:
-		// - Control frames (already handled and continued)
-		// - Non-final fragments (FIN=0, continue accumulating)
+		// Loop continues for non-final fragments (FIN=0, continue accumulating)
+	}
+}
+
+// nextDataFrame reads frames until a data frame (text, binary, or
+// continuation) arrives, handling control frames in between.
+//
+// Errors that violate the protocol fail the connection with the matching
+// close code first. A connection that drops without a close frame returns
+// a *CloseError with CloseAbnormalClosure.
+func (c *Conn) nextDataFrame(ctx context.Context) (*frame, error) {
+	for {
+		f, err := c.nextFrame(ctx)
+		if err != nil {
+			// Connection dropped without a close frame
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, &CloseError{Code: CloseAbnormalClosure, Err: err}
+			}
+
+			// RFC 6455 Section 7.1.7: Fail the connection with a Close frame
+			if code, ok := frameErrorCloseCode(err); ok {
+				c.log().Info("websocket: closing connection on invalid frame", "code", code, "err", err)
+				_ = c.CloseWithCode(code, "")
+			}
+			return nil, err
+		}
+
+		// RFC 6455 Section 5.1: Client frames are masked, server frames aren't
+		if err := validateMasking(f, c.isServer); err != nil && !c.lenient {
+			c.log().Info("websocket: closing connection on invalid masking", "err", err)
+			_ = c.CloseWithCode(CloseProtocolError, "invalid masking")
+			return nil, err
+		}
+
+		// Handle control frames (RFC 6455 Section 5.5)
+		// Control frames MAY be injected in the middle of a fragmented message
+		switch f.opcode {
+		case opcodePing:
+			c.stats.pingsReceived.Add(1)
+
+			// Auto-respond to Ping with Pong (echo application data)
+			if err := c.Pong(f.payload); err != nil {
+				c.log().Warn("websocket: auto-pong failed", "err", err)
+				return nil, err
+			}
+			continue // Continue reading data frames
+
+		case opcodePong:
+			// Pong received (unsolicited or response to our Ping)
+			c.stats.pongsReceived.Add(1)
+			c.pongReceived(f.payload)
+			continue
+
+		case opcodeClose:
+			// Close frame received
+			// RFC 6455 Section 5.5.1: Parse status code + reason
+			return nil, c.handleCloseFrame(f.payload)
+		}
+
+		// Data frames: Text, Binary, Continuation
+		// Charged per frame, so an endless fragmented message trips too
+		if c.chargeReadBudget(len(f.payload)) {
+			_ = c.CloseWithCode(CloseMessageTooBig, "read budget exceeded")
+			return nil, ErrReadBudgetExceeded
+		}
+		return f, nil
 	}
 }
 
+// nextFragment reads the next data frame like nextDataFrame. While a
+// fragmented message is in progress, its fragment deadline bounds the
+// wait: if the rest of the message doesn't start arriving in time, the
+// connection is closed with 1008 (Policy Violation) and
+// ErrFragmentTimeout is returned.
+func (c *Conn) nextFragment(ctx context.Context) (*frame, error) {
+	if c.fragmentDeadline.IsZero() {
+		return c.nextDataFrame(ctx)
+	}
+
+	fragCtx, cancel := context.WithDeadline(ctx, c.fragmentDeadline)
+	defer cancel()
+
+	f, err := c.nextDataFrame(fragCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		_ = c.CloseWithCode(ClosePolicyViolation, "fragment timeout")
+		return nil, ErrFragmentTimeout
+	}
+	return f, err
+}
+
+// startFragments starts the fragment deadline for a fragmented message,
+// if a fragment timeout is set.
+func (c *Conn) startFragments() {
+	c.fragmentDeadline = time.Time{}
+	if timeout := time.Duration(c.fragmentTimeout.Load()); timeout > 0 {
+		c.fragmentDeadline = time.Now().Add(timeout)
+	}
+}
+
+// checkMessageSize fails the connection with 1009 (Message Too Big) and
+// returns ErrMessageTooLarge if a message of n bytes exceeds the message
+// size limit, dropping any partly assembled message.
+func (c *Conn) checkMessageSize(n int) error {
+	limit := c.maxMessageSize.Load()
+	if limit <= 0 || int64(n) <= limit {
+		return nil
+	}
+
+	c.inFragment = false
+	c.fragmentBuf.Reset()
+	c.fragmentDeadline = time.Time{}
+	_ = c.CloseWithCode(CloseMessageTooBig, "message too big")
+	return ErrMessageTooLarge
+}
+
+// chargeReadBudget adds n data bytes to the read total and reports whether
+// the read budget is now exceeded.
+func (c *Conn) chargeReadBudget(n int) bool {
+	c.readUsed += int64(n)
+	budget := c.readBudget.Load()
+	return budget > 0 && c.readUsed > budget
+}
+
+// nextFrame reads the next frame, returning ctx.Err() if ctx is canceled
+// before the frame starts to arrive.
+func (c *Conn) nextFrame(ctx context.Context) (*frame, error) {
+	if ctx.Done() == nil {
+		return c.recvFrame()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Wait for the first byte with a deadline tied to ctx. Peek consumes
+	// nothing, so an interrupted wait leaves the stream intact.
+	fired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(fired)
+		_ = c.conn.SetReadDeadline(time.Now())
+	})
+
+	_, err := c.reader.Peek(1)
+
+	if !stop() {
+		// Deadline was (or is being) set; wait so clearing it wins
+		<-fired
+		_ = c.conn.SetReadDeadline(time.Time{})
+		if ctxErr := ctx.Err(); ctxErr != nil && (err == nil || errors.Is(err, os.ErrDeadlineExceeded)) {
+			return nil, ctxErr
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	return c.recvFrame()
+}
+
+// frameErrorCloseCode returns the close code to send for a frame parsing
+// error, or false for errors that aren't protocol violations (I/O errors,
+// context cancellation).
+func frameErrorCloseCode(err error) (CloseCode, bool) {
+	switch {
+	case errors.Is(err, ErrInvalidOpcode),
+		errors.Is(err, ErrReservedBits),
+		errors.Is(err, ErrProtocolError),
+		errors.Is(err, ErrControlFragmented),
+		errors.Is(err, ErrControlTooLarge):
+		return CloseProtocolError, true
+	case errors.Is(err, ErrFrameTooLarge):
+		return CloseMessageTooBig, true
+	}
+	return 0, false
+}
+
 // ReadText reads the next text message.
 //
 // Convenience wrapper around Read() that:
@@ -205,6 +559,35 @@ func (c *Conn) ReadText() (string, error) {
 	return string(data), nil
 }
 
+// ReadBinary reads the next binary message.
+//
+// Convenience wrapper around Read() that:
+//   - Ensures message is BinaryMessage (returns error otherwise)
+//   - Returns the payload directly
+//
+// Returns ErrInvalidMessageType if message is not binary.
+//
+// Example:
+//
+//	data, err := conn.ReadBinary()
+//	if err != nil {
+//	    return err
+//	}
+//	var msg pb.Update
+//	err = proto.Unmarshal(data, &msg)
+func (c *Conn) ReadBinary() ([]byte, error) {
+	msgType, data, err := c.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if msgType != BinaryMessage {
+		return nil, ErrInvalidMessageType
+	}
+
+	return data, nil
+}
+
 // ReadJSON reads the next message as JSON.
 //
 // Convenience wrapper around Read() that:
@@ -232,6 +615,10 @@ func (c *Conn) ReadJSON(v any) error {
 //   - Masking: Server frames NOT masked, client frames masked (RFC 6455 Section 5.1)
 //   - Flushing: Ensures data sent immediately
 //
+// A write error (e.g. the peer went away mid-frame) closes the connection,
+// since the stream can't continue after a partial frame; later writes
+// return ErrClosed.
+//
 // Thread-Safety: Safe for concurrent writes (serialized by mutex).
 //
 // Note: Currently does NOT fragment large messages (sends as single frame).
@@ -244,28 +631,93 @@ func (c *Conn) Write(messageType MessageType, data []byte) error {
 	}
 	c.closeMu.RUnlock()
 
+	opcode, err := messageOpcode(messageType, data)
+	if err != nil {
+		return err
+	}
+
 	// Lock write mutex (prevent concurrent writes per RFC 6455 Section 5.1)
 	c.writeMu.Lock()
 	defer c.writeMu.Unlock()
 
-	// Build frame
-	var opcode byte
+	return c.writeMessage(opcode, data, c.flushMode != FlushManual)
+}
+
+// WriteMessages writes several messages as one uninterrupted batch.
+//
+// The write lock is held for the whole batch, so messages written
+// concurrently by other goroutines land entirely before or after it, and
+// the batch is flushed once at the end instead of once per message. In
+// FlushManual mode the batch is buffered until Flush, like Write.
+//
+// All messages are validated before anything is written: an invalid
+// message type or text that isn't valid UTF-8 fails the whole batch with
+// ErrInvalidMessageType or ErrInvalidUTF8 and nothing is sent. A write
+// error may leave the batch partially sent.
+//
+// Example:
+//
+//	err := conn.WriteMessages([]websocket.Message{
+//	    {Type: websocket.TextMessage, Data: []byte(`{"type":"begin"}`)},
+//	    {Type: websocket.BinaryMessage, Data: chunk},
+//	    {Type: websocket.TextMessage, Data: []byte(`{"type":"end"}`)},
+//	})
+//
+// Thread-Safety: Safe for concurrent writes (serialized by mutex).
+func (c *Conn) WriteMessages(msgs []Message) error {
+	c.closeMu.RLock()
+	if c.closed {
+		c.closeMu.RUnlock()
+		return ErrClosed
+	}
+	c.closeMu.RUnlock()
+
+	opcodes := make([]byte, len(msgs))
+	for i, msg := range msgs {
+		opcode, err := messageOpcode(msg.Type, msg.Data)
+		if err != nil {
+			return err
+		}
+		opcodes[i] = opcode
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	for i, msg := range msgs {
+		if err := c.writeMessage(opcodes[i], msg.Data, false); err != nil {
+			return err
+		}
+	}
+
+	if c.flushMode == FlushManual {
+		return nil
+	}
+	return c.flush()
+}
+
+// messageOpcode returns the frame opcode for a data message, validating
+// its type and, for text, its UTF-8 encoding.
+func messageOpcode(messageType MessageType, data []byte) (byte, error) {
 	switch messageType {
 	case TextMessage:
-		opcode = opcodeText
-
 		// Validate UTF-8 (RFC 6455 Section 8.1)
 		if !utf8.Valid(data) {
-			return ErrInvalidUTF8
+			return 0, ErrInvalidUTF8
 		}
+		return opcodeText, nil
 
 	case BinaryMessage:
-		opcode = opcodeBinary
+		return opcodeBinary, nil
 
 	default:
-		return ErrInvalidMessageType
+		return 0, ErrInvalidMessageType
 	}
+}
 
+// writeMessage writes a data message as a single frame, flushing it if
+// flush is set. Caller must hold writeMu.
+func (c *Conn) writeMessage(opcode byte, data []byte, flush bool) error {
 	f := &frame{
 		fin:     true, // Single frame (no fragmentation yet)
 		opcode:  opcode,
@@ -274,14 +726,194 @@ func (c *Conn) Write(messageType MessageType, data []byte) error {
 	}
 
 	if f.masked {
-		// Client frame - apply random mask
-		// Note: This is only for client connections (not used in stream library currently)
+		// Client frame - apply random mask (RFC 6455 Section 5.3)
 		// Server connections (c.isServer=true) never mask
-		f.mask = [4]byte{0x12, 0x34, 0x56, 0x78} // TODO: Use crypto/rand for production
+		f.mask = c.newMask()
+	}
+
+	if err := c.sendFrame(f, flush); err != nil {
+		return err
+	}
+
+	c.stats.messagesWritten.Add(1)
+	c.touch()
+	c.stats.bytesWritten.Add(uint64(len(data)))
+	return nil
+}
+
+// writePrepared writes a prepared data frame like Write, without encoding
+// it again. Only for server connections, whose frames are unmasked.
+func (c *Conn) writePrepared(p *preparedFrame) error {
+	c.closeMu.RLock()
+	if c.closed {
+		c.closeMu.RUnlock()
+		return ErrClosed
+	}
+	c.closeMu.RUnlock()
+
+	data, err := p.encoded()
+	if err != nil {
+		return err
 	}
 
-	// Write frame
-	return writeFrame(c.writer, f)
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.writer.Write(data); err != nil {
+		return c.writeFailed(fmt.Errorf("write: %w", err))
+	}
+	c.trace(DirectionWrite, p.f)
+	c.stats.messagesWritten.Add(1)
+	c.touch()
+	c.stats.bytesWritten.Add(uint64(len(p.f.payload)))
+
+	if c.flushMode == FlushManual {
+		return nil
+	}
+	return c.flush()
+}
+
+// flush writes the write buffer to the network, failing the connection
+// on error. Caller must hold writeMu.
+func (c *Conn) flush() error {
+	if err := c.writer.Flush(); err != nil {
+		return c.writeFailed(fmt.Errorf("flush: %w", err))
+	}
+	return nil
+}
+
+// writeFailed marks the connection closed after a write or flush error
+// and closes the network connection, returning err. Caller must hold
+// writeMu.
+//
+// The error may have cut a frame off partway, so anything written after
+// it would be parsed by the peer as part of that frame. Closing makes
+// later writes fail with ErrClosed instead of corrupting the stream.
+func (c *Conn) writeFailed(err error) error {
+	c.closeMu.Lock()
+	c.closed = true
+	c.closeMu.Unlock()
+
+	c.log().Debug("websocket: closing connection after failed write", "err", err)
+
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+	return err
+}
+
+// Flush writes any buffered messages to the network.
+//
+// Only needed in FlushManual mode; in FlushImmediate mode every Write is
+// already flushed and Flush has nothing to do.
+//
+// Example:
+//
+//	conn.SetFlushMode(websocket.FlushManual)
+//	for _, update := range updates {
+//	    _ = conn.WriteText(update)
+//	}
+//	err := conn.Flush()
+//
+// Thread-Safety: Safe to call concurrently with Write (serialized by mutex).
+func (c *Conn) Flush() error {
+	c.closeMu.RLock()
+	if c.closed {
+		c.closeMu.RUnlock()
+		return ErrClosed
+	}
+	c.closeMu.RUnlock()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.flush()
+}
+
+// SetFlushMode sets when data messages are flushed to the network.
+//
+// In FlushManual mode, Write buffers messages and the application calls
+// Flush after a batch, replacing a flush per message with one per batch.
+// Switching back to FlushImmediate flushes anything still buffered.
+//
+// Thread-Safety: Safe to call concurrently with Write (serialized by mutex).
+func (c *Conn) SetFlushMode(mode FlushMode) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.flushMode = mode
+	if mode == FlushImmediate && c.writer.Buffered() > 0 {
+		return c.flush()
+	}
+	return nil
+}
+
+// SetReadBudget limits the total data payload bytes the connection accepts
+// over its lifetime.
+//
+// Unlike a per-message size limit, the budget covers all messages
+// combined, so a peer can't keep a connection busy by streaming endless
+// small messages. Bytes are counted per frame as they arrive (control
+// frames excluded), including bytes read before the budget was set. Once
+// the total exceeds bytes, Read closes the connection with 1009 (Message
+// Too Big) and returns ErrReadBudgetExceeded.
+//
+// A budget of 0 or less means unlimited (default).
+//
+// Example:
+//
+//	conn.SetReadBudget(64 << 20) // 64 MB per connection
+//
+// Thread-Safety: Safe to call concurrently with Read.
+func (c *Conn) SetReadBudget(bytes int64) {
+	c.readBudget.Store(max(bytes, 0))
+}
+
+// SetMaxMessageSize limits the size of an assembled data message, in
+// bytes.
+//
+// The frame limit (32 MB) caps each frame's payload as it's parsed, but a
+// fragmented message is the sum of its frames, so a peer could otherwise
+// assemble an arbitrarily large message from many legal frames. The
+// message limit applies to the total: once the fragments of a message add
+// up to more than bytes, Read (or the NextReader reader) closes the
+// connection with 1009 (Message Too Big) and returns ErrMessageTooLarge.
+// Single-frame messages are checked against it too, so the limit may be
+// set below the frame limit.
+//
+// The default is 32 MB (see UpgradeOptions.MaxMessageSize and
+// DialOptions.MaxMessageSize). A limit of 0 or less means unlimited,
+// leaving only the frame limit and any read budget.
+//
+// Example:
+//
+//	conn.SetMaxMessageSize(1 << 20) // 1 MB messages
+//
+// Thread-Safety: Safe to call concurrently with Read.
+func (c *Conn) SetMaxMessageSize(bytes int64) {
+	c.maxMessageSize.Store(max(bytes, 0))
+}
+
+// SetFragmentTimeout limits how long a fragmented message may take to
+// arrive, measured from its first fragment.
+//
+// A peer that sends the first fragment of a message and then stalls holds
+// the reader (and the partial message) indefinitely. With a timeout set,
+// the remaining fragments must start arriving before it expires, or Read
+// closes the connection with 1008 (Policy Violation) and returns
+// ErrFragmentTimeout. A frame that has started to arrive is read to
+// completion. Control frames between fragments don't extend the deadline.
+//
+// A timeout of 0 or less means unlimited (default). The timeout applies
+// to messages whose first fragment arrives after the call.
+//
+// Example:
+//
+//	conn.SetFragmentTimeout(10 * time.Second)
+//
+// Thread-Safety: Safe to call concurrently with Read.
+func (c *Conn) SetFragmentTimeout(timeout time.Duration) {
+	c.fragmentTimeout.Store(int64(max(timeout, 0)))
 }
 
 // WriteText writes a text message.
@@ -300,29 +932,65 @@ func (c *Conn) WriteText(text string) error {
 //   - Sends as TextMessage
 //
 // Returns json.MarshalError if marshaling fails.
+//
+// Encoding buffers are pooled and reused across calls, so repeated sends
+// don't allocate a fresh buffer each time.
 func (c *Conn) WriteJSON(v any) error {
-	data, err := json.Marshal(v)
-	if err != nil {
+	buf := jsonBuffers.Get().(*bytes.Buffer)
+	defer putJSONBuffer(buf)
+
+	buf.Reset()
+	if err := json.MarshalWrite(buf, v); err != nil {
 		return err
 	}
 
-	return c.Write(TextMessage, data)
+	// Write copies the payload into the connection's buffer before
+	// returning, so buf can be reused afterwards
+	return c.Write(TextMessage, buf.Bytes())
 }
 
-// Ping sends a ping frame (for keep-alive).
+// WriteJSONContext writes a value as a JSON text message, like WriteJSON,
+// but gives up if ctx is done before the message is sent.
 //
-// Application data is optional (max 125 bytes per RFC 6455 Section 5.5).
-// Peer should respond with Pong containing same application data.
+// The write runs under a write deadline derived from ctx: its deadline, if
+// any, and the moment it's canceled. Returns ctx.Err() if ctx is already
+// done or ends while the write is blocked (e.g. on a slow client). A write
+// cut short may leave a partial frame on the wire, so the connection is
+// closed in that case.
 //
-// Use case: Heartbeat to detect dead connections.
+// Example:
 //
-//	ticker := time.NewTicker(30 * time.Second)
-//	go func() {
-//	    for range ticker.C {
-//	        conn.Ping(nil)
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//	    ...
+//	    if err := conn.WriteJSONContext(r.Context(), update); err != nil {
+//	        return // Request canceled or client gone
 //	    }
-//	}()
-func (c *Conn) Ping(data []byte) error {
+//	}
+//
+// Thread-Safety: Safe for concurrent writes (serialized by mutex).
+func (c *Conn) WriteJSONContext(ctx context.Context, v any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	buf := jsonBuffers.Get().(*bytes.Buffer)
+	defer putJSONBuffer(buf)
+
+	buf.Reset()
+	if err := json.MarshalWrite(buf, v); err != nil {
+		return err
+	}
+
+	return c.writeContext(ctx, TextMessage, buf.Bytes())
+}
+
+// writeContext writes a data message with a write deadline tied to ctx,
+// returning ctx.Err() if the write is interrupted by it.
+func (c *Conn) writeContext(ctx context.Context, messageType MessageType, data []byte) error {
+	if ctx.Done() == nil || c.conn == nil {
+		return c.Write(messageType, data)
+	}
+
 	c.closeMu.RLock()
 	if c.closed {
 		c.closeMu.RUnlock()
@@ -330,26 +998,80 @@ func (c *Conn) Ping(data []byte) error {
 	}
 	c.closeMu.RUnlock()
 
-	// RFC 6455 Section 5.5: Control frame payload max 125 bytes
-	if len(data) > 125 {
-		return ErrControlTooLarge
+	opcode, err := messageOpcode(messageType, data)
+	if err != nil {
+		return err
 	}
 
 	c.writeMu.Lock()
-	defer c.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		c.writeMu.Unlock()
+		return err
+	}
 
-	f := &frame{
-		fin:     true, // Control frames must have FIN=1
-		opcode:  opcodePing,
-		masked:  !c.isServer,
-		payload: data,
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.conn.SetWriteDeadline(deadline)
 	}
+	fired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(fired)
+		_ = c.conn.SetWriteDeadline(time.Now())
+	})
 
-	if f.masked {
-		f.mask = [4]byte{0x12, 0x34, 0x56, 0x78} // TODO: crypto/rand
+	err = c.writeMessage(opcode, data, c.flushMode != FlushManual)
+
+	if !stop() {
+		// Deadline was (or is being) set; wait so clearing it wins
+		<-fired
+	}
+	_ = c.conn.SetWriteDeadline(time.Time{})
+	c.writeMu.Unlock()
+
+	if err != nil && errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() != nil {
+		// The frame may be half-written, so the stream can't continue
+		_ = c.CloseGoingAway("")
+		_ = c.conn.Close()
+		return ctx.Err()
+	}
+	return err
+}
+
+// maxPooledJSONBuffer is the largest buffer WriteJSON returns to the pool,
+// so an occasional huge message doesn't pin its memory.
+const maxPooledJSONBuffer = 64 << 10
+
+// jsonBuffers pools WriteJSON encoding buffers.
+var jsonBuffers = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// putJSONBuffer returns buf to the pool unless it grew too large.
+func putJSONBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledJSONBuffer {
+		jsonBuffers.Put(buf)
+	}
+}
+
+// Ping sends a ping frame (for keep-alive).
+//
+// Application data is optional (max 125 bytes per RFC 6455 Section 5.5).
+// Peer should respond with Pong containing same application data.
+//
+// Use case: Heartbeat to detect dead connections.
+//
+//	ticker := time.NewTicker(30 * time.Second)
+//	go func() {
+//	    for range ticker.C {
+//	        conn.Ping(nil)
+//	    }
+//	}()
+func (c *Conn) Ping(data []byte) error {
+	if err := c.writeControl(opcodePing, data); err != nil {
+		return err
 	}
 
-	return writeFrame(c.writer, f)
+	c.stats.pingsSent.Add(1)
+	return nil
 }
 
 // Pong sends a pong frame (response to ping or unsolicited).
@@ -359,6 +1081,35 @@ func (c *Conn) Ping(data []byte) error {
 //
 // Note: Read() automatically responds to Ping frames, so manual Pong usually not needed.
 func (c *Conn) Pong(data []byte) error {
+	if err := c.writeControl(opcodePong, data); err != nil {
+		return err
+	}
+
+	c.stats.pongsSent.Add(1)
+	return nil
+}
+
+// WritePing sends a ping frame. It's an alias for Ping, for code written
+// against the WriteX naming of the data message methods.
+//
+// Returns ErrControlTooLarge if data exceeds 125 bytes.
+func (c *Conn) WritePing(data []byte) error {
+	return c.Ping(data)
+}
+
+// WritePong sends a pong frame. It's an alias for Pong, for code written
+// against the WriteX naming of the data message methods.
+//
+// Returns ErrControlTooLarge if data exceeds 125 bytes.
+func (c *Conn) WritePong(data []byte) error {
+	return c.Pong(data)
+}
+
+// writeControl writes and flushes a ping or pong frame.
+//
+// RFC 6455 Section 5.5: Control frames must not be fragmented and carry at
+// most 125 bytes of payload; larger data returns ErrControlTooLarge.
+func (c *Conn) writeControl(opcode byte, data []byte) error {
 	c.closeMu.RLock()
 	if c.closed {
 		c.closeMu.RUnlock()
@@ -366,7 +1117,7 @@ func (c *Conn) Pong(data []byte) error {
 	}
 	c.closeMu.RUnlock()
 
-	if len(data) > 125 {
+	if len(data) > maxControlPayload {
 		return ErrControlTooLarge
 	}
 
@@ -374,17 +1125,85 @@ func (c *Conn) Pong(data []byte) error {
 	defer c.writeMu.Unlock()
 
 	f := &frame{
-		fin:     true,
-		opcode:  opcodePong,
+		fin:     true, // Control frames must have FIN=1
+		opcode:  opcode,
 		masked:  !c.isServer,
 		payload: data,
 	}
 
 	if f.masked {
-		f.mask = [4]byte{0x12, 0x34, 0x56, 0x78} // TODO: crypto/rand
+		f.mask = c.newMask()
 	}
 
-	return writeFrame(c.writer, f)
+	return c.sendFrame(f, true)
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake
+// (Sec-WebSocket-Protocol), or "" if none was selected.
+//
+// Example:
+//
+//	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+//	    Subprotocols: []string{"chat.v2", "chat.v1"},
+//	})
+//	if err == nil && conn.Subprotocol() == "chat.v1" {
+//	    // Fall back to the old message format
+//	}
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// ClientIP returns the IP address of the client, as recorded by Upgrade.
+//
+// By default it's the host of the request's RemoteAddr. Behind a reverse
+// proxy, set UpgradeOptions.TrustedProxyHeader to take it from a header
+// such as X-Forwarded-For instead. Returns "" for connections created by
+// Dial.
+//
+// Example:
+//
+//	conn, err := websocket.Upgrade(w, r, &websocket.UpgradeOptions{
+//	    TrustedProxyHeader: "X-Forwarded-For",
+//	})
+//	if err == nil {
+//	    log.Printf("Client connected from %s", conn.ClientIP())
+//	}
+func (c *Conn) ClientIP() string {
+	return c.clientIP
+}
+
+// SetMetadata stores an application value on the connection, such as the
+// user's locale or role. Hub.BroadcastFunc predicates can read it with
+// Metadata to pick recipients. A nil value removes the key.
+//
+// Example:
+//
+//	conn.SetMetadata("role", "admin")
+//	hub.Register(conn)
+//
+// Thread-safe: can be called concurrently with Metadata and broadcasts.
+func (c *Conn) SetMetadata(key string, value any) {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+
+	if value == nil {
+		delete(c.metadata, key)
+		return
+	}
+	if c.metadata == nil {
+		c.metadata = make(map[string]any)
+	}
+	c.metadata[key] = value
+}
+
+// Metadata returns the value stored with SetMetadata, or nil if key
+// isn't set.
+//
+// Thread-safe: can be called from multiple goroutines.
+func (c *Conn) Metadata(key string) any {
+	c.metaMu.RLock()
+	defer c.metaMu.RUnlock()
+	return c.metadata[key]
 }
 
 // Close sends close frame and closes connection.
@@ -398,6 +1217,18 @@ func (c *Conn) Close() error {
 	return c.CloseWithCode(CloseNormalClosure, "")
 }
 
+// CloseGoingAway closes the connection with CloseGoingAway (1001), the
+// code for an endpoint that is going away, e.g. a server shutting down.
+//
+// Shorthand for CloseWithCode(CloseGoingAway, reason).
+//
+// Example:
+//
+//	_ = conn.CloseGoingAway("server restarting")
+func (c *Conn) CloseGoingAway(reason string) error {
+	return c.CloseWithCode(CloseGoingAway, reason)
+}
+
 // CloseWithCode sends close frame with specific status code and reason.
 //
 // Status codes defined in RFC 6455 Section 7.4.
@@ -408,6 +1239,9 @@ func (c *Conn) Close() error {
 //  2. Peer responds with Close frame
 //  3. Close TCP connection
 //
+// A reason longer than 123 bytes (the 125-byte control frame limit minus
+// the status code) is truncated to fit, on a UTF-8 character boundary.
+//
 // Idempotent - safe to call multiple times.
 func (c *Conn) CloseWithCode(code CloseCode, reason string) error {
 	var err error
@@ -416,19 +1250,26 @@ func (c *Conn) CloseWithCode(code CloseCode, reason string) error {
 		// Mark as closed
 		c.closeMu.Lock()
 		c.closed = true
+		stopContext := c.stopContext
 		c.closeMu.Unlock()
 
-		// Build close frame payload: 2 bytes status code + optional reason
-		payload := make([]byte, 2+len(reason))
-		payload[0] = byte(code >> 8)
-		payload[1] = byte(code & 0xFF)
-		copy(payload[2:], reason)
+		if stopContext != nil {
+			stopContext()
+		}
+		c.stopIdleTimer()
 
 		// Validate reason is valid UTF-8
 		if reason != "" && !utf8.ValidString(reason) {
 			err = ErrInvalidUTF8
 			return
 		}
+		reason = truncateCloseReason(reason)
+
+		// Build close frame payload: 2 bytes status code + optional reason
+		payload := make([]byte, 2+len(reason))
+		payload[0] = byte(code >> 8)
+		payload[1] = byte(code & 0xFF)
+		copy(payload[2:], reason)
 
 		// Send close frame
 		c.writeMu.Lock()
@@ -440,10 +1281,10 @@ func (c *Conn) CloseWithCode(code CloseCode, reason string) error {
 		}
 
 		if f.masked {
-			f.mask = [4]byte{0x12, 0x34, 0x56, 0x78} // TODO: crypto/rand
+			f.mask = c.newMask()
 		}
 
-		writeErr := writeFrame(c.writer, f)
+		writeErr := c.sendFrame(f, true)
 		c.writeMu.Unlock()
 
 		if writeErr != nil {
@@ -462,26 +1303,79 @@ func (c *Conn) CloseWithCode(code CloseCode, reason string) error {
 	return err
 }
 
+// maxCloseReason is the longest close reason that fits in a close frame
+// after the 2-byte status code (RFC 6455 Section 5.5).
+const maxCloseReason = maxControlPayload - 2
+
+// truncateCloseReason shortens a valid UTF-8 reason to maxCloseReason
+// bytes without splitting a character.
+func truncateCloseReason(reason string) string {
+	if len(reason) <= maxCloseReason {
+		return reason
+	}
+
+	cut := maxCloseReason
+	for cut > 0 && !utf8.RuneStart(reason[cut]) {
+		cut--
+	}
+	return reason[:cut]
+}
+
 // handleCloseFrame processes received close frame.
 //
 // RFC 6455 Section 5.5.1:
 //   - Close frame MAY contain status code (2 bytes) + reason
 //   - Peer should respond with Close frame
-func (c *Conn) handleCloseFrame(payload []byte) {
+//
+// Returns the CloseError reported by Read.
+func (c *Conn) handleCloseFrame(payload []byte) *CloseError {
 	// Mark as closed
 	c.closeMu.Lock()
 	c.closed = true
 	c.closeMu.Unlock()
 
-	// Parse close code if present
-	var code CloseCode
+	// Parse close code and reason if present
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	if len(payload) == 1 && !c.lenient {
+		_ = c.CloseWithCode(CloseProtocolError, "invalid close code")
+		closeErr.Err = ErrInvalidCloseCode
+		return closeErr
+	}
 	if len(payload) >= 2 {
-		code = CloseCode(uint16(payload[0])<<8 | uint16(payload[1]))
-	} else {
-		code = CloseNoStatusReceived
+		closeErr.Code = CloseCode(uint16(payload[0])<<8 | uint16(payload[1]))
+
+		// RFC 6455 Section 7.4: Codes reserved for local use or
+		// unassigned must not be sent
+		if !closeErr.Code.isValidWire() {
+			if !c.lenient {
+				_ = c.CloseWithCode(CloseProtocolError, "invalid close code")
+				closeErr.Err = ErrInvalidCloseCode
+				return closeErr
+			}
+			closeErr.Reason = string(payload[2:])
+			_ = c.CloseWithCode(CloseNormalClosure, "")
+			return closeErr
+		}
+
+		// RFC 6455 Section 5.5.1: The reason MUST be valid UTF-8;
+		// otherwise fail the connection with 1007 instead of echoing
+		reason := payload[2:]
+		if !utf8.Valid(reason) {
+			_ = c.CloseWithCode(CloseInvalidFramePayloadData, "invalid UTF-8")
+			closeErr.Err = ErrInvalidUTF8
+			return closeErr
+		}
+		closeErr.Reason = string(reason)
 	}
 
-	// Respond with close frame (echo status code)
+	// Respond with close frame (echo status code). 1005 must not be sent,
+	// so a close without a status is answered with 1000.
 	// Ignore error - connection closing anyway
-	_ = c.CloseWithCode(code, "")
+	reply := closeErr.Code
+	if reply == CloseNoStatusReceived {
+		reply = CloseNormalClosure
+	}
+	_ = c.CloseWithCode(reply, "")
+
+	return closeErr
 }
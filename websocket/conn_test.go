@@ -3,15 +3,25 @@ package websocket
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/json/v2"
 	"errors"
+	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 )
 
 // mockConn creates a mock connection with pre-written frames.
+//
+// Frames come from the peer, so they're masked for server-side
+// connections (RFC 6455 Section 5.1).
 func mockConn(t *testing.T, frames []*frame, isServer bool) *Conn {
 	t.Helper()
 
@@ -19,6 +29,7 @@ func mockConn(t *testing.T, frames []*frame, isServer bool) *Conn {
 	var buf bytes.Buffer
 	w := bufio.NewWriter(&buf)
 	for _, f := range frames {
+		maskFromPeer(f, isServer)
 		if err := writeFrame(w, f); err != nil {
 			t.Fatalf("mockConn writeFrame error: %v", err)
 		}
@@ -41,6 +52,7 @@ func mockConnNoValidation(t *testing.T, frames []*frame, isServer bool) *Conn {
 	var buf bytes.Buffer
 	w := bufio.NewWriter(&buf)
 	for _, f := range frames {
+		maskFromPeer(f, isServer)
 		if err := writeFrameNoValidation(w, f); err != nil {
 			t.Fatalf("mockConnNoValidation writeFrame error: %v", err)
 		}
@@ -53,6 +65,14 @@ func mockConnNoValidation(t *testing.T, frames []*frame, isServer bool) *Conn {
 	return newConn(nil, reader, writer, isServer)
 }
 
+// maskFromPeer masks f if it's sent by a client to a server-side connection.
+func maskFromPeer(f *frame, isServer bool) {
+	if isServer && !f.masked {
+		f.masked = true
+		f.mask = [4]byte{0xA1, 0xB2, 0xC3, 0xD4}
+	}
+}
+
 // mockConnWriter creates a mock connection that captures writes.
 //
 // Always creates server-side connection (isServer=true, no masking).
@@ -186,6 +206,492 @@ func TestConn_ReadControlDuringFragmentation(t *testing.T) {
 	}
 }
 
+// TestConn_ReadContext tests canceling a blocked read mid-message and resuming.
+func TestConn_ReadContext(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	defer serverSide.Close()
+	defer clientSide.Close()
+
+	conn := newConn(serverSide, bufio.NewReader(serverSide), bufio.NewWriter(serverSide), true)
+
+	// writeFrames sends frames from the client end (net.Pipe is synchronous)
+	writeFrames := func(frames ...*frame) {
+		go func() {
+			w := bufio.NewWriter(clientSide)
+			for _, f := range frames {
+				maskFromPeer(f, true)
+				_ = writeFrame(w, f)
+			}
+			_ = w.Flush()
+		}()
+	}
+
+	// First fragment arrives, then the read blocks waiting for the rest
+	writeFrames(&frame{fin: false, opcode: opcodeText, payload: []byte("Hel")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err := conn.ReadContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ReadContext() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ReadContext() returned after %v, want prompt return on cancel", elapsed)
+	}
+
+	// Already-canceled context returns immediately
+	if _, _, err := conn.ReadContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadContext() with canceled context error = %v, want context.Canceled", err)
+	}
+
+	// A later Read resumes the fragmented message
+	writeFrames(&frame{fin: true, opcode: opcodeContinuation, payload: []byte("lo")})
+
+	msgType, payload, err := conn.Read()
+	if err != nil {
+		t.Fatalf("Read() after cancel error = %v", err)
+	}
+	if msgType != TextMessage || string(payload) != "Hello" {
+		t.Errorf("Read() = %v %q, want TextMessage %q", msgType, payload, "Hello")
+	}
+}
+
+// TestConn_ReadMasking tests that frames with the wrong MASK bit for the
+// direction are rejected with a 1002 close.
+func TestConn_ReadMasking(t *testing.T) {
+	tests := []struct {
+		name     string
+		isServer bool
+		masked   bool
+		wantErr  error
+	}{
+		{"server rejects unmasked client frame", true, false, ErrMaskRequired},
+		{"client rejects masked server frame", false, true, ErrMaskUnexpected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in bytes.Buffer
+			w := bufio.NewWriter(&in)
+			f := &frame{fin: true, opcode: opcodeText, masked: tt.masked, payload: []byte("hi")}
+			if tt.masked {
+				f.mask = [4]byte{1, 2, 3, 4}
+			}
+			if err := writeFrame(w, f); err != nil {
+				t.Fatalf("writeFrame() error = %v", err)
+			}
+
+			var out bytes.Buffer
+			conn := newConn(nil, bufio.NewReader(&in), bufio.NewWriter(&out), tt.isServer)
+
+			if _, _, err := conn.Read(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// Close frame with 1002 sent to the peer
+			closeFrame, err := readFrame(bufio.NewReader(&out))
+			if err != nil {
+				t.Fatalf("readFrame() error = %v", err)
+			}
+			if closeFrame.opcode != opcodeClose {
+				t.Fatalf("opcode = 0x%X, want close", closeFrame.opcode)
+			}
+			if code := CloseCode(binary.BigEndian.Uint16(closeFrame.payload)); code != CloseProtocolError {
+				t.Errorf("close code = %d, want %d", code, CloseProtocolError)
+			}
+		})
+	}
+}
+
+// TestConn_WriteMasking tests that client frames are masked with the key
+// from the connection's mask source.
+func TestConn_WriteMasking(t *testing.T) {
+	var out bytes.Buffer
+	conn := newConn(nil, bufio.NewReader(bytes.NewReader(nil)), bufio.NewWriter(&out), false)
+	SetMaskForTest(conn, [4]byte{0x12, 0x34, 0x56, 0x78})
+
+	if err := conn.WriteText("Hi"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	want := []byte{
+		0x81, 0x82, // FIN + text, MASK + length 2
+		0x12, 0x34, 0x56, 0x78, // Masking key
+		'H' ^ 0x12, 'i' ^ 0x34, // Masked payload (0x5A, 0x5D)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("wire bytes = % X, want % X", out.Bytes(), want)
+	}
+}
+
+// TestConn_Lenient_Masking tests that an unmasked client frame fails a
+// strict connection and is accepted by a lenient one.
+func TestConn_Lenient_Masking(t *testing.T) {
+	for _, lenient := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lenient=%v", lenient), func(t *testing.T) {
+			var in, out bytes.Buffer
+			w := bufio.NewWriter(&in)
+			_ = writeFrame(w, &frame{fin: true, opcode: opcodeText, payload: []byte("hi")}) // Unmasked
+			_ = w.Flush()
+
+			conn := newConn(nil, bufio.NewReader(&in), bufio.NewWriter(&out), true)
+			conn.lenient = lenient
+
+			_, data, err := conn.Read()
+			if lenient {
+				if err != nil || string(data) != "hi" {
+					t.Errorf("Read() = %q, %v, want %q", data, err, "hi")
+				}
+				return
+			}
+			if !errors.Is(err, ErrMaskRequired) {
+				t.Errorf("Read() error = %v, want ErrMaskRequired", err)
+			}
+		})
+	}
+}
+
+// TestConn_Lenient_CloseCode tests the close code validation of strict and
+// lenient connections, and the code each answers with.
+func TestConn_Lenient_CloseCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   []byte
+		lenient   bool
+		wantCode  CloseCode // Reported by Read
+		wantErr   error     // CloseError.Err
+		wantReply CloseCode
+	}{
+		{"valid strict", []byte{0x0F, 0xA0}, false, 4000, nil, 4000},
+		{"no status", nil, false, CloseNoStatusReceived, nil, CloseNormalClosure},
+		{"reserved strict", []byte{0x03, 0xED}, false, CloseNoStatusReceived, ErrInvalidCloseCode, CloseProtocolError},
+		{"reserved lenient", []byte{0x03, 0xED}, true, CloseNoStatusReceived, nil, CloseNormalClosure},
+		{"unassigned strict", []byte{0x13, 0x88}, false, 5000, ErrInvalidCloseCode, CloseProtocolError},
+		{"unassigned lenient", []byte{0x13, 0x88}, true, 5000, nil, CloseNormalClosure},
+		{"1 byte strict", []byte{0x03}, false, CloseNoStatusReceived, ErrInvalidCloseCode, CloseProtocolError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in, out bytes.Buffer
+			w := bufio.NewWriter(&in)
+			_ = writeFrame(w, &frame{fin: true, opcode: opcodeClose, masked: true, mask: [4]byte{1, 2, 3, 4}, payload: tt.payload})
+			_ = w.Flush()
+
+			conn := newConn(nil, bufio.NewReader(&in), bufio.NewWriter(&out), true)
+			conn.lenient = tt.lenient
+
+			_, _, err := conn.Read()
+			var ce *CloseError
+			if !errors.As(err, &ce) {
+				t.Fatalf("Read() error = %v, want *CloseError", err)
+			}
+			if ce.Code != tt.wantCode || !errors.Is(ce.Err, tt.wantErr) {
+				t.Errorf("CloseError = (%d, %v), want (%d, %v)", ce.Code, ce.Err, tt.wantCode, tt.wantErr)
+			}
+
+			reply, err := readFrame(bufio.NewReader(&out))
+			if err != nil {
+				t.Fatalf("reading reply: %v", err)
+			}
+			if code := CloseCode(binary.BigEndian.Uint16(reply.payload)); code != tt.wantReply {
+				t.Errorf("reply code = %d, want %d", code, tt.wantReply)
+			}
+		})
+	}
+}
+
+// TestConn_ReadInvalidFrame tests that malformed frames are rejected with
+// a close frame carrying the matching status code.
+func TestConn_ReadInvalidFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte // Raw frame header from the peer
+		wantErr  error
+		wantCode CloseCode
+	}{
+		{"invalid opcode", []byte{0x83, 0x00}, ErrInvalidOpcode, CloseProtocolError},
+		{"reserved bit", []byte{0xC1, 0x00}, ErrReservedBits, CloseProtocolError},
+		{"fragmented ping", []byte{0x09, 0x00}, ErrControlFragmented, CloseProtocolError},
+		{"64-bit length MSB", []byte{0x82, 0x7F, 0x80, 0, 0, 0, 0, 0, 0, 0}, ErrProtocolError, CloseProtocolError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			conn := newConn(nil, bufio.NewReader(bytes.NewReader(tt.header)), bufio.NewWriter(&out), false)
+
+			if _, _, err := conn.Read(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+
+			closeFrame, err := readFrame(bufio.NewReader(&out))
+			if err != nil {
+				t.Fatalf("readFrame() error = %v", err)
+			}
+			if closeFrame.opcode != opcodeClose {
+				t.Fatalf("opcode = 0x%X, want close", closeFrame.opcode)
+			}
+			if code := CloseCode(binary.BigEndian.Uint16(closeFrame.payload)); code != tt.wantCode {
+				t.Errorf("close code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestConn_FragmentTimeout tests that a peer stalling after the first
+// fragment of a message times out and is closed with 1008.
+func TestConn_FragmentTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(*Conn) error
+	}{
+		{
+			name: "Read",
+			read: func(conn *Conn) error {
+				_, _, err := conn.Read()
+				return err
+			},
+		},
+		{
+			name: "NextReader",
+			read: func(conn *Conn) error {
+				_, r, err := conn.NextReader()
+				if err != nil {
+					return err
+				}
+				_, err = io.ReadAll(r)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverSide, clientSide := net.Pipe()
+			defer serverSide.Close()
+			defer clientSide.Close()
+
+			conn := newConn(serverSide, bufio.NewReader(serverSide), bufio.NewWriter(serverSide), true)
+			conn.SetFragmentTimeout(50 * time.Millisecond)
+
+			// The peer sends the first fragment and a ping, then stalls;
+			// it reads whatever the server sends back
+			replies := make(chan *frame, 2)
+			go func() {
+				w := bufio.NewWriter(clientSide)
+				for _, f := range []*frame{
+					{fin: false, opcode: opcodeText, payload: []byte("Hel")},
+					{fin: true, opcode: opcodePing, payload: []byte("ping")},
+				} {
+					maskFromPeer(f, true)
+					_ = writeFrame(w, f)
+				}
+				r := bufio.NewReader(clientSide)
+				for {
+					f, err := readFrame(r)
+					if err != nil {
+						close(replies)
+						return
+					}
+					replies <- f
+				}
+			}()
+
+			start := time.Now()
+			err := tt.read(conn)
+			if !errors.Is(err, ErrFragmentTimeout) {
+				t.Fatalf("read error = %v, want ErrFragmentTimeout", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("read returned after %v, want prompt timeout", elapsed)
+			}
+
+			// Pong for the ping, then the close frame
+			var closeFrame *frame
+			for f := range replies {
+				if f.opcode == opcodeClose {
+					closeFrame = f
+					break
+				}
+			}
+			if closeFrame == nil {
+				t.Fatal("no close frame sent")
+			}
+			if code := CloseCode(binary.BigEndian.Uint16(closeFrame.payload)); code != ClosePolicyViolation {
+				t.Errorf("close code = %d, want %d", code, ClosePolicyViolation)
+			}
+		})
+	}
+}
+
+// TestConn_IdleTimeout tests that a connection without message traffic is
+// closed with 1001, and that a message postpones the close.
+func TestConn_IdleTimeout(t *testing.T) {
+	const timeout = 150 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, &UpgradeOptions{IdleTimeout: timeout})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := Dial(context.Background(), wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	time.Sleep(timeout / 2)
+	if err := conn.WriteText("still here"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	_, _, err = conn.Read()
+	var ce *CloseError
+	if !errors.As(err, &ce) || ce.Code != CloseGoingAway {
+		t.Fatalf("Read() error = %v, want close 1001", err)
+	}
+	if elapsed := time.Since(start); elapsed < timeout*3/2 {
+		t.Errorf("closed after %v, want at least %v", elapsed, timeout*3/2)
+	}
+}
+
+// TestConn_ReadBudget tests that exceeding the read budget closes the
+// connection with 1009.
+func TestConn_ReadBudget(t *testing.T) {
+	var in bytes.Buffer
+	w := bufio.NewWriter(&in)
+	for _, f := range []*frame{
+		{fin: true, opcode: opcodeText, payload: []byte("hello")},
+		{fin: true, opcode: opcodePing, payload: []byte("not counted")},
+		{fin: true, opcode: opcodeText, payload: []byte("world")},
+		{fin: true, opcode: opcodeText, payload: []byte("again")},
+	} {
+		if err := writeFrame(w, f); err != nil {
+			t.Fatalf("writeFrame() error = %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	conn := newConn(nil, bufio.NewReader(&in), bufio.NewWriter(&out), false)
+	conn.SetReadBudget(10)
+
+	// Two messages use exactly the budget
+	for _, want := range []string{"hello", "world"} {
+		if text, err := conn.ReadText(); err != nil || text != want {
+			t.Fatalf("ReadText() = %q, %v, want %q", text, err, want)
+		}
+	}
+
+	if _, _, err := conn.Read(); !errors.Is(err, ErrReadBudgetExceeded) {
+		t.Fatalf("Read() error = %v, want ErrReadBudgetExceeded", err)
+	}
+
+	// Pong for the ping, then the close frame
+	r := bufio.NewReader(&out)
+	if _, err := readFrame(r); err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	closeFrame, err := readFrame(r)
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if closeFrame.opcode != opcodeClose {
+		t.Fatalf("opcode = 0x%X, want close", closeFrame.opcode)
+	}
+	if code := CloseCode(binary.BigEndian.Uint16(closeFrame.payload)); code != CloseMessageTooBig {
+		t.Errorf("close code = %d, want %d", code, CloseMessageTooBig)
+	}
+}
+
+// TestConn_MaxMessageSize tests that a message assembled from frames under
+// the frame limit is rejected with 1009 once its total exceeds the message
+// limit.
+func TestConn_MaxMessageSize(t *testing.T) {
+	// 8 fragments of 1 KB: each frame is legal, the message is 8 KB
+	fragments := func() []*frame {
+		chunk := bytes.Repeat([]byte("x"), 1024)
+		frames := []*frame{{opcode: opcodeBinary, payload: chunk}}
+		for i := 1; i < 8; i++ {
+			frames = append(frames, &frame{fin: i == 7, opcode: opcodeContinuation, payload: chunk})
+		}
+		return frames
+	}
+
+	tests := []struct {
+		name string
+		read func(*Conn) error
+	}{
+		{
+			name: "Read",
+			read: func(conn *Conn) error {
+				_, _, err := conn.Read()
+				return err
+			},
+		},
+		{
+			name: "NextReader",
+			read: func(conn *Conn) error {
+				_, r, err := conn.NextReader()
+				if err != nil {
+					return err
+				}
+				_, err = io.Copy(io.Discard, r)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in bytes.Buffer
+			w := bufio.NewWriter(&in)
+			// A message within the limit, then one over it
+			for _, f := range append([]*frame{{fin: true, opcode: opcodeText, payload: []byte("small")}}, fragments()...) {
+				if err := writeFrame(w, f); err != nil {
+					t.Fatalf("writeFrame() error = %v", err)
+				}
+			}
+
+			var out bytes.Buffer
+			conn := newConn(nil, bufio.NewReader(&in), bufio.NewWriter(&out), false)
+			conn.SetMaxMessageSize(4096)
+
+			if text, err := conn.ReadText(); err != nil || text != "small" {
+				t.Fatalf("ReadText() = %q, %v, want %q", text, err, "small")
+			}
+
+			if err := tt.read(conn); !errors.Is(err, ErrMessageTooLarge) {
+				t.Fatalf("%s error = %v, want ErrMessageTooLarge", tt.name, err)
+			}
+
+			closeFrame, err := readFrame(bufio.NewReader(&out))
+			if err != nil {
+				t.Fatalf("readFrame() error = %v", err)
+			}
+			if closeFrame.opcode != opcodeClose {
+				t.Fatalf("opcode = 0x%X, want close", closeFrame.opcode)
+			}
+			if code := CloseCode(binary.BigEndian.Uint16(closeFrame.payload)); code != CloseMessageTooBig {
+				t.Errorf("close code = %d, want %d", code, CloseMessageTooBig)
+			}
+		})
+	}
+}
+
 // TestConn_ReadText tests ReadText convenience method.
 func TestConn_ReadText(t *testing.T) {
 	tests := []struct {
@@ -234,6 +740,54 @@ func TestConn_ReadText(t *testing.T) {
 	}
 }
 
+// TestConn_ReadBinary tests ReadBinary convenience method.
+func TestConn_ReadBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   []*frame
+		wantData []byte
+		wantErr  error
+	}{
+		{
+			name: "binary message",
+			frames: []*frame{
+				{fin: true, opcode: opcodeBinary, payload: []byte{0x08, 0x96, 0x01}},
+			},
+			wantData: []byte{0x08, 0x96, 0x01},
+		},
+		{
+			name: "text message (error)",
+			frames: []*frame{
+				{fin: true, opcode: opcodeText, payload: []byte("Hello")},
+			},
+			wantErr: ErrInvalidMessageType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := mockConn(t, tt.frames, false)
+
+			data, err := conn.ReadBinary()
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ReadBinary() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ReadBinary() error = %v", err)
+			}
+
+			if !bytes.Equal(data, tt.wantData) {
+				t.Errorf("ReadBinary() = %v, want %v", data, tt.wantData)
+			}
+		})
+	}
+}
+
 // TestConn_ReadJSON tests ReadJSON convenience method.
 func TestConn_ReadJSON(t *testing.T) {
 	type Message struct {
@@ -391,6 +945,51 @@ func TestConn_WriteText(t *testing.T) {
 	}
 }
 
+// partialWriter accepts up to n bytes, then fails, like a connection that
+// drops in the middle of a frame.
+type partialWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *partialWriter) Write(p []byte) (int, error) {
+	if room := w.n - w.Len(); len(p) > room {
+		w.Buffer.Write(p[:max(room, 0)])
+		return max(room, 0), errors.New("connection reset")
+	}
+	return w.Buffer.Write(p)
+}
+
+// TestConn_WritePartialFrame tests that a write failing mid-frame closes
+// the connection, so nothing more is appended to the truncated frame.
+func TestConn_WritePartialFrame(t *testing.T) {
+	w := &partialWriter{n: 100}
+	conn := newConn(nil, nil, bufio.NewWriter(w), true)
+
+	if err := conn.WriteText("ok"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	// The header and part of the payload get through
+	if err := conn.WriteText(strings.Repeat("x", 1000)); err == nil {
+		t.Fatal("WriteText() error = nil, want the write error")
+	}
+	if !conn.isClosed() {
+		t.Fatal("connection not marked closed after a partial write")
+	}
+	sent := w.Len()
+
+	if err := conn.WriteText("more"); !errors.Is(err, ErrClosed) {
+		t.Errorf("WriteText() after failure error = %v, want ErrClosed", err)
+	}
+	if err := conn.Ping(nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Ping() after failure error = %v, want ErrClosed", err)
+	}
+	if w.Len() != sent {
+		t.Errorf("%d bytes written after the failure, want 0", w.Len()-sent)
+	}
+}
+
 // TestConn_WriteJSON tests WriteJSON convenience method.
 func TestConn_WriteJSON(t *testing.T) {
 	type Message struct {
@@ -426,6 +1025,221 @@ func TestConn_WriteJSON(t *testing.T) {
 	}
 }
 
+// TestConn_WriteJSONContext tests that canceling the context aborts a
+// write blocked on an unresponsive peer.
+func TestConn_WriteJSONContext(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	defer serverSide.Close()
+	defer clientSide.Close()
+
+	// Nobody reads clientSide, so the write blocks (net.Pipe is synchronous)
+	conn := newConn(serverSide, bufio.NewReader(serverSide), bufio.NewWriter(serverSide), true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := conn.WriteJSONContext(ctx, map[string]string{"status": "ok"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WriteJSONContext() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WriteJSONContext() returned after %v, want prompt return on cancel", elapsed)
+	}
+
+	// The interrupted frame may be incomplete, so the connection is closed
+	if err := conn.WriteText("after"); !errors.Is(err, ErrClosed) {
+		t.Errorf("WriteText() after cancel error = %v, want ErrClosed", err)
+	}
+
+	// Already-canceled context fails before writing
+	if err := conn.WriteJSONContext(ctx, "x"); !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteJSONContext(canceled) error = %v, want context.Canceled", err)
+	}
+}
+
+// TestConn_WriteJSONContext_Delivered tests that a write completing in
+// time is sent normally.
+func TestConn_WriteJSONContext_Delivered(t *testing.T) {
+	conn, writeBuf := mockConnWriter(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := conn.WriteJSONContext(ctx, map[string]int{"n": 1}); err != nil {
+		t.Fatalf("WriteJSONContext() error = %v", err)
+	}
+
+	f, err := readFrame(bufio.NewReader(writeBuf))
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if f.opcode != opcodeText || string(f.payload) != `{"n":1}` {
+		t.Errorf("frame = 0x%X %q, want text %q", f.opcode, f.payload, `{"n":1}`)
+	}
+}
+
+// TestConn_FlushManual tests that manual flush mode buffers writes until Flush.
+func TestConn_FlushManual(t *testing.T) {
+	conn, buf := mockConnWriter(t)
+
+	if err := conn.SetFlushMode(FlushManual); err != nil {
+		t.Fatalf("SetFlushMode() error = %v", err)
+	}
+
+	for _, msg := range []string{"one", "two"} {
+		if err := conn.WriteText(msg); err != nil {
+			t.Fatalf("WriteText() error = %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("%d bytes written before Flush, want 0", buf.Len())
+	}
+
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	// Two frames: 2-byte header + 3-byte payload each
+	if buf.Len() != 10 {
+		t.Errorf("%d bytes written after Flush, want 10", buf.Len())
+	}
+
+	// Control frames flush buffered messages with them
+	_ = conn.WriteText("three")
+	if err := conn.Ping(nil); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if buf.Len() != 10+7+2 {
+		t.Errorf("%d bytes written after Ping, want %d", buf.Len(), 10+7+2)
+	}
+
+	// Switching back to immediate mode flushes pending writes
+	_ = conn.WriteText("four")
+	if err := conn.SetFlushMode(FlushImmediate); err != nil {
+		t.Fatalf("SetFlushMode() error = %v", err)
+	}
+	if buf.Len() != 19+6 {
+		t.Errorf("%d bytes written after SetFlushMode(FlushImmediate), want %d", buf.Len(), 19+6)
+	}
+}
+
+// countingWriter counts Write calls to the underlying buffer.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+// TestConn_WriteMessages tests that a batch is written in order with a
+// single flush.
+func TestConn_WriteMessages(t *testing.T) {
+	var out countingWriter
+	conn := newConn(nil, bufio.NewReader(bytes.NewReader(nil)), bufio.NewWriter(&out), true)
+
+	msgs := []Message{
+		{Type: TextMessage, Data: []byte("one")},
+		{Type: BinaryMessage, Data: []byte{0x00, 0xFF}},
+		{Type: TextMessage, Data: []byte("three")},
+	}
+	if err := conn.WriteMessages(msgs); err != nil {
+		t.Fatalf("WriteMessages() error = %v", err)
+	}
+	if out.writes != 1 {
+		t.Errorf("underlying writes = %d, want 1", out.writes)
+	}
+
+	r := bufio.NewReader(&out.Buffer)
+	for i, want := range msgs {
+		f, err := readFrame(r)
+		if err != nil {
+			t.Fatalf("readFrame(%d) error = %v", i, err)
+		}
+		opcode, _ := messageOpcode(want.Type, want.Data)
+		if f.opcode != opcode || !bytes.Equal(f.payload, want.Data) {
+			t.Errorf("frame %d = opcode 0x%X %q, want 0x%X %q", i, f.opcode, f.payload, opcode, want.Data)
+		}
+	}
+
+	if stats := conn.Stats(); stats.MessagesWritten != 3 || stats.BytesWritten != 10 {
+		t.Errorf("Stats() = %d messages, %d bytes, want 3, 10", stats.MessagesWritten, stats.BytesWritten)
+	}
+}
+
+// TestConn_WriteMessages_Invalid tests that an invalid message fails the
+// whole batch before anything is written.
+func TestConn_WriteMessages_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		wantErr error
+	}{
+		{"invalid UTF-8", Message{Type: TextMessage, Data: []byte{0xFF, 0xFE}}, ErrInvalidUTF8},
+		{"invalid type", Message{Type: MessageType(99), Data: []byte("x")}, ErrInvalidMessageType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, buf := mockConnWriter(t)
+
+			err := conn.WriteMessages([]Message{{Type: TextMessage, Data: []byte("ok")}, tt.msg})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("WriteMessages() error = %v, want %v", err, tt.wantErr)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("%d bytes written, want 0", buf.Len())
+			}
+		})
+	}
+}
+
+// TestConn_WriteMessages_Concurrent tests that concurrent writes never
+// split a batch.
+func TestConn_WriteMessages_Concurrent(t *testing.T) {
+	conn, buf := mockConnWriter(t)
+
+	const batchSize, singles = 50, 50
+	batch := make([]Message, batchSize)
+	for i := range batch {
+		batch[i] = Message{Type: TextMessage, Data: []byte("batch")}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < singles; i++ {
+			_ = conn.WriteText("single")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := conn.WriteMessages(batch); err != nil {
+			t.Errorf("WriteMessages() error = %v", err)
+		}
+	}()
+	wg.Wait()
+
+	r := bufio.NewReader(buf)
+	var seq []byte // 'b' for batch, 's' for single
+	for i := 0; i < batchSize+singles; i++ {
+		f, err := readFrame(r)
+		if err != nil {
+			t.Fatalf("readFrame(%d) error = %v", i, err)
+		}
+		seq = append(seq, f.payload[0])
+	}
+
+	first := bytes.IndexByte(seq, 'b')
+	last := bytes.LastIndexByte(seq, 'b')
+	if last-first+1 != batchSize {
+		t.Errorf("batch split by concurrent writes: %s", seq)
+	}
+}
+
 // TestConn_Ping tests Ping frame sending.
 func TestConn_Ping(t *testing.T) {
 	conn, writeBuf := mockConnWriter(t)
@@ -484,6 +1298,44 @@ func TestConn_Pong(t *testing.T) {
 	}
 }
 
+// TestConn_WritePingPong tests the WritePing and WritePong aliases.
+func TestConn_WritePingPong(t *testing.T) {
+	tests := []struct {
+		name   string
+		write  func(*Conn, []byte) error
+		opcode byte
+	}{
+		{"WritePing", (*Conn).WritePing, opcodePing},
+		{"WritePong", (*Conn).WritePong, opcodePong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, writeBuf := mockConnWriter(t)
+
+			if err := tt.write(conn, []byte("data")); err != nil {
+				t.Fatalf("%s() error = %v", tt.name, err)
+			}
+			f, err := readFrame(bufio.NewReader(writeBuf))
+			if err != nil {
+				t.Fatalf("readFrame() error = %v", err)
+			}
+			if f.opcode != tt.opcode || !f.fin || string(f.payload) != "data" {
+				t.Errorf("frame = opcode 0x%X fin %v %q, want opcode 0x%X fin true %q",
+					f.opcode, f.fin, f.payload, tt.opcode, "data")
+			}
+
+			// 125 bytes is the limit
+			if err := tt.write(conn, make([]byte, 125)); err != nil {
+				t.Errorf("%s(125 bytes) error = %v, want nil", tt.name, err)
+			}
+			if err := tt.write(conn, make([]byte, 126)); !errors.Is(err, ErrControlTooLarge) {
+				t.Errorf("%s(126 bytes) error = %v, want ErrControlTooLarge", tt.name, err)
+			}
+		})
+	}
+}
+
 // TestConn_Close tests normal close.
 func TestConn_Close(t *testing.T) {
 	conn, writeBuf := mockConnWriter(t)
@@ -666,20 +1518,26 @@ func TestConn_ReadAfterClose(t *testing.T) {
 // TestConn_ReceiveCloseFrame tests receiving close frame from peer.
 func TestConn_ReceiveCloseFrame(t *testing.T) {
 	tests := []struct {
-		name    string
-		payload []byte // Close frame payload (status code + reason)
+		name       string
+		payload    []byte // Close frame payload (status code + reason)
+		wantCode   CloseCode
+		wantReason string
 	}{
 		{
-			name:    "close with status and reason",
-			payload: []byte{0x03, 0xE8, 'N', 'o', 'r', 'm', 'a', 'l'}, // 1000 + "Normal"
+			name:       "close with status and reason",
+			payload:    []byte{0x03, 0xE8, 'N', 'o', 'r', 'm', 'a', 'l'}, // 1000 + "Normal"
+			wantCode:   CloseNormalClosure,
+			wantReason: "Normal",
 		},
 		{
-			name:    "close with status only",
-			payload: []byte{0x03, 0xE9}, // 1001 (Going Away)
+			name:     "close with status only",
+			payload:  []byte{0x03, 0xE9}, // 1001 (Going Away)
+			wantCode: CloseGoingAway,
 		},
 		{
-			name:    "close without status",
-			payload: []byte{}, // No status code
+			name:     "close without status",
+			payload:  []byte{}, // No status code
+			wantCode: CloseNoStatusReceived,
 		},
 	}
 
@@ -690,12 +1548,29 @@ func TestConn_ReceiveCloseFrame(t *testing.T) {
 			}
 			conn := mockConn(t, frames, false)
 
-			// Read should return ErrClosed after receiving close frame
+			// Read should return a CloseError matching ErrClosed
 			_, _, err := conn.Read()
-			if !errors.Is(err, ErrClosed) {
+			if !errors.Is(err, ErrClosed) || !IsCloseError(err) {
 				t.Errorf("Read() after close frame error = %v, want ErrClosed", err)
 			}
 
+			var ce *CloseError
+			if !errors.As(err, &ce) {
+				t.Fatalf("Read() error = %T, want *CloseError", err)
+			}
+			if ce.Code != tt.wantCode {
+				t.Errorf("CloseError.Code = %d, want %d", ce.Code, tt.wantCode)
+			}
+			if ce.Reason != tt.wantReason {
+				t.Errorf("CloseError.Reason = %q, want %q", ce.Reason, tt.wantReason)
+			}
+			if !IsCloseErrorCode(err, CloseProtocolError, tt.wantCode) {
+				t.Errorf("IsCloseErrorCode(err, %d) = false, want true", tt.wantCode)
+			}
+			if IsCloseErrorCode(err, CloseAbnormalClosure) {
+				t.Error("IsCloseErrorCode(err, CloseAbnormalClosure) = true, want false")
+			}
+
 			// Connection should be marked as closed
 			conn.closeMu.RLock()
 			if !conn.closed {
@@ -706,6 +1581,84 @@ func TestConn_ReceiveCloseFrame(t *testing.T) {
 	}
 }
 
+// TestConn_ReceiveCloseFrame_InvalidUTF8 tests that a close frame with an
+// invalid UTF-8 reason is answered with 1007 instead of an echo.
+func TestConn_ReceiveCloseFrame_InvalidUTF8(t *testing.T) {
+	var in bytes.Buffer
+	w := bufio.NewWriter(&in)
+	f := &frame{fin: true, opcode: opcodeClose, payload: []byte{0x03, 0xE8, 0xFF, 0xFE}} // 1000 + invalid
+	if err := writeFrame(w, f); err != nil {
+		t.Fatalf("writeFrame() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	conn := newConn(nil, bufio.NewReader(&in), bufio.NewWriter(&out), false)
+
+	_, _, err := conn.Read()
+	if !errors.Is(err, ErrInvalidUTF8) {
+		t.Fatalf("Read() error = %v, want ErrInvalidUTF8", err)
+	}
+	var ce *CloseError
+	if !errors.As(err, &ce) || ce.Reason != "" {
+		t.Errorf("Read() error = %#v, want *CloseError without the reason", err)
+	}
+
+	closeFrame, err := readFrame(bufio.NewReader(&out))
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if closeFrame.opcode != opcodeClose {
+		t.Fatalf("opcode = 0x%X, want close", closeFrame.opcode)
+	}
+	if code := CloseCode(binary.BigEndian.Uint16(closeFrame.payload)); code != CloseInvalidFramePayloadData {
+		t.Errorf("close code = %d, want %d", code, CloseInvalidFramePayloadData)
+	}
+}
+
+// TestConn_ReadAbnormalClosure tests that a connection dropped without a
+// close frame reports CloseAbnormalClosure.
+func TestConn_ReadAbnormalClosure(t *testing.T) {
+	conn := mockConn(t, nil, false) // EOF before any frame
+
+	_, _, err := conn.Read()
+	if !IsCloseErrorCode(err, CloseAbnormalClosure) {
+		t.Fatalf("Read() error = %v, want CloseAbnormalClosure", err)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("Read() error = %v, want wrapped io.EOF", err)
+	}
+	if !IsCloseError(err) {
+		t.Error("IsCloseError() = false for abnormal closure, want true")
+	}
+	if errors.Is(err, ErrClosed) {
+		t.Error("errors.Is(err, ErrClosed) = true for abnormal closure, want false (no close frame)")
+	}
+}
+
+// TestConn_ReadTruncatedFrame tests that a stream cut off in the middle of
+// a frame is reported as an abnormal closure.
+func TestConn_ReadTruncatedFrame(t *testing.T) {
+	var in bytes.Buffer
+	w := bufio.NewWriter(&in)
+	if err := writeFrame(w, &frame{fin: true, opcode: opcodeText, payload: []byte("hello, world")}); err != nil {
+		t.Fatalf("writeFrame() error = %v", err)
+	}
+	in.Truncate(in.Len() - 5) // Drop the end of the payload
+
+	conn := newConn(nil, bufio.NewReader(&in), bufio.NewWriter(io.Discard), false)
+
+	_, _, err := conn.Read()
+	if !IsCloseErrorCode(err, CloseAbnormalClosure) {
+		t.Fatalf("Read() error = %v, want a CloseError with code 1006", err)
+	}
+	if !IsCloseError(err) {
+		t.Error("IsCloseError() = false for a truncated frame, want true")
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read() error = %v, want wrapped io.ErrUnexpectedEOF", err)
+	}
+}
+
 // TestConn_PingTooLarge tests Ping with payload > 125 bytes.
 func TestConn_PingTooLarge(t *testing.T) {
 	conn, _ := mockConnWriter(t)
@@ -745,6 +1698,43 @@ func TestConn_CloseWithInvalidUTF8Reason(t *testing.T) {
 	}
 }
 
+// TestConn_CloseWithLongReason tests that an over-long close reason is
+// truncated to fit the close frame on a UTF-8 boundary.
+func TestConn_CloseWithLongReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+		want   string
+	}{
+		{"fits", strings.Repeat("a", 123), strings.Repeat("a", 123)},
+		{"ASCII", strings.Repeat("a", 200), strings.Repeat("a", 123)},
+		// "€" is 3 bytes: "a" + 40 of them is 121 bytes, and the 41st
+		// would cross the 123-byte limit
+		{"multi-byte", "a" + strings.Repeat("€", 50), "a" + strings.Repeat("€", 40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, writeBuf := mockConnWriter(t)
+
+			if err := conn.CloseWithCode(CloseGoingAway, tt.reason); err != nil {
+				t.Fatalf("CloseWithCode() error = %v", err)
+			}
+
+			f, err := readFrame(bufio.NewReader(writeBuf))
+			if err != nil {
+				t.Fatalf("readFrame() error = %v", err)
+			}
+			if code := CloseCode(binary.BigEndian.Uint16(f.payload)); code != CloseGoingAway {
+				t.Errorf("close code = %d, want %d", code, CloseGoingAway)
+			}
+			if got := string(f.payload[2:]); got != tt.want {
+				t.Errorf("reason = %q (%d bytes), want %q (%d bytes)", got, len(got), tt.want, len(tt.want))
+			}
+		})
+	}
+}
+
 // TestConn_WriteJSONMarshalError tests WriteJSON with non-marshalable value.
 func TestConn_WriteJSONMarshalError(t *testing.T) {
 	conn, _ := mockConnWriter(t)
@@ -852,3 +1842,144 @@ func TestConn_WriteError(t *testing.T) {
 		t.Errorf("Write() after close error = %v, want ErrClosed", err)
 	}
 }
+
+// BenchmarkConn_WriteJSON benchmarks repeated JSON sends.
+func BenchmarkConn_WriteJSON(b *testing.B) {
+	conn := newConn(nil, bufio.NewReader(bytes.NewReader(nil)), bufio.NewWriter(io.Discard), true)
+
+	data := map[string]any{
+		"user":   "Alice",
+		"action": "login",
+		"count":  42,
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_ = conn.WriteJSON(data)
+	}
+}
+
+// BenchmarkConn_WriteBurst_FlushImmediate benchmarks bursts of small
+// messages over TCP with a flush per message.
+func BenchmarkConn_WriteBurst_FlushImmediate(b *testing.B) {
+	benchmarkConnWriteBurst(b, FlushImmediate)
+}
+
+// BenchmarkConn_WriteBurst_FlushManual benchmarks bursts of small messages
+// over TCP with one flush per burst.
+func BenchmarkConn_WriteBurst_FlushManual(b *testing.B) {
+	benchmarkConnWriteBurst(b, FlushManual)
+}
+
+// benchmarkConnWriteBurst writes bursts of 64 small messages to a TCP peer
+// that discards them. Each op is one burst.
+func benchmarkConnWriteBurst(b *testing.B, mode FlushMode) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatalf("Listen error: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		peer, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer peer.Close()
+		_, _ = io.Copy(io.Discard, peer)
+	}()
+
+	netConn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		b.Fatalf("Dial error: %v", err)
+	}
+	defer netConn.Close()
+
+	conn := newConn(netConn, bufio.NewReader(netConn), bufio.NewWriter(netConn), true)
+	if err := conn.SetFlushMode(mode); err != nil {
+		b.Fatalf("SetFlushMode error: %v", err)
+	}
+
+	const burst = 64
+	msg := []byte("tick")
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < burst; j++ {
+			if err := conn.Write(BinaryMessage, msg); err != nil {
+				b.Fatalf("Write error: %v", err)
+			}
+		}
+		if err := conn.Flush(); err != nil {
+			b.Fatalf("Flush error: %v", err)
+		}
+	}
+}
+
+// mockConnection is a minimal Connection fake that records written text.
+type mockConnection struct {
+	incoming []string
+	written  []string
+	closed   CloseCode
+}
+
+func (m *mockConnection) Read() (MessageType, []byte, error) {
+	if len(m.incoming) == 0 {
+		return 0, nil, &CloseError{Code: CloseNormalClosure}
+	}
+	msg := m.incoming[0]
+	m.incoming = m.incoming[1:]
+	return TextMessage, []byte(msg), nil
+}
+
+func (m *mockConnection) Write(_ MessageType, data []byte) error {
+	m.written = append(m.written, string(data))
+	return nil
+}
+
+func (m *mockConnection) WriteText(text string) error {
+	return m.Write(TextMessage, []byte(text))
+}
+
+func (m *mockConnection) WriteJSON(any) error { return nil }
+func (m *mockConnection) Ping([]byte) error   { return nil }
+func (m *mockConnection) Pong([]byte) error   { return nil }
+func (m *mockConnection) Close() error        { return m.CloseWithCode(CloseNormalClosure, "") }
+
+func (m *mockConnection) CloseWithCode(code CloseCode, _ string) error {
+	m.closed = code
+	return nil
+}
+
+// TestConnection_Mock tests that application code written against
+// Connection runs with a fake.
+func TestConnection_Mock(t *testing.T) {
+	echo := func(conn Connection) error {
+		defer conn.Close()
+		for {
+			msgType, data, err := conn.Read()
+			if err != nil {
+				return err
+			}
+			if err := conn.Write(msgType, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	mock := &mockConnection{incoming: []string{"one", "two"}}
+	if err := echo(mock); !IsCloseErrorCode(err, CloseNormalClosure) {
+		t.Fatalf("echo() error = %v, want CloseNormalClosure", err)
+	}
+
+	if len(mock.written) != 2 || mock.written[0] != "one" || mock.written[1] != "two" {
+		t.Errorf("written = %q, want [one two]", mock.written)
+	}
+	if mock.closed != CloseNormalClosure {
+		t.Errorf("closed = %d, want %d", mock.closed, CloseNormalClosure)
+	}
+}
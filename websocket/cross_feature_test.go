@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coregx/stream"
 	"github.com/coregx/stream/sse"
 )
 
@@ -261,11 +262,8 @@ func TestIntegration_BroadcastToAll(t *testing.T) {
 	go wsHub.Run()
 	defer wsHub.Close()
 
-	// Unified broadcast function
-	broadcastToAll := func(msg string) {
-		sseHub.Broadcast(msg)
-		wsHub.BroadcastText(msg)
-	}
+	// Unified broadcaster over both transports
+	all := stream.NewMultiBroadcaster(sseHub, wsHub)
 
 	// Setup server
 	mux := http.NewServeMux()
@@ -379,7 +377,9 @@ func TestIntegration_BroadcastToAll(t *testing.T) {
 
 	// Broadcast 5 messages to all clients
 	for i := 1; i <= 5; i++ {
-		broadcastToAll(fmt.Sprintf("broadcast-%d", i))
+		if err := all.Publish(fmt.Sprintf("broadcast-%d", i)); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
 		time.Sleep(50 * time.Millisecond)
 	}
 
@@ -595,8 +595,8 @@ func TestIntegration_WebSocket_To_SSE_Relay(t *testing.T) {
 	t.Logf("Relay test completed: %d messages sent, SSE clients received successfully", sent)
 }
 
-// Message represents a test message for JSON serialization.
-type Message struct {
+// jsonMessage represents a test message for JSON serialization.
+type jsonMessage struct {
 	ID   int    `json:"id"`
 	Text string `json:"text"`
 }
@@ -630,7 +630,7 @@ func TestIntegration_JSON_Communication(t *testing.T) {
 			}
 
 			// Parse and rebroadcast
-			var msg Message
+			var msg jsonMessage
 			if err := json.Unmarshal(data, &msg); err == nil {
 				wsHub.Broadcast(data)
 			}
@@ -648,7 +648,7 @@ func TestIntegration_JSON_Communication(t *testing.T) {
 
 		// Send JSON events
 		for i := 1; i <= 5; i++ {
-			msg := Message{ID: i, Text: fmt.Sprintf("SSE message %d", i)}
+			msg := jsonMessage{ID: i, Text: fmt.Sprintf("SSE message %d", i)}
 			if err := conn.SendJSON(msg); err != nil {
 				return
 			}
@@ -677,7 +677,7 @@ func TestIntegration_JSON_Communication(t *testing.T) {
 				data := strings.TrimPrefix(line, "data:")
 				data = strings.TrimSpace(data)
 
-				var msg Message
+				var msg jsonMessage
 				if err := json.Unmarshal([]byte(data), &msg); err != nil {
 					t.Errorf("JSON unmarshal error: %v", err)
 					continue
@@ -726,7 +726,7 @@ func TestIntegration_JSON_Communication(t *testing.T) {
 		time.Sleep(100 * time.Millisecond)
 
 		// First client sends JSON message
-		msg := Message{ID: 100, Text: "Broadcast test"}
+		msg := jsonMessage{ID: 100, Text: "Broadcast test"}
 		data, _ := json.Marshal(msg)
 
 		if err := clients[0].Write(TextMessage, data); err != nil {
@@ -743,7 +743,7 @@ func TestIntegration_JSON_Communication(t *testing.T) {
 				continue
 			}
 
-			var receivedMsg Message
+			var receivedMsg jsonMessage
 			if err := json.Unmarshal(received, &receivedMsg); err != nil {
 				t.Errorf("Client %d unmarshal error: %v", i, err)
 				continue
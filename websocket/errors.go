@@ -62,6 +62,13 @@ var (
 	// Status code 1002 (protocol error).
 	ErrMaskUnexpected = errors.New("websocket: server frames must not be masked")
 
+	// ErrInvalidCloseCode indicates a close frame with a status code that
+	// must not be sent (reserved or unassigned), or a 1-byte payload.
+	// RFC 6455 Section 7.4: Status code 1002 (protocol error).
+	// Not checked by lenient connections (UpgradeOptions.Lenient,
+	// DialOptions.Lenient).
+	ErrInvalidCloseCode = errors.New("websocket: invalid close code")
+
 	// Handshake error types (RFC 6455 Section 4).
 
 	// ErrInvalidMethod indicates HTTP method is not GET.
@@ -80,6 +87,10 @@ var (
 	// RFC 6455 Section 4.2.1: Required for handshake.
 	ErrMissingSecKey = errors.New("websocket: missing Sec-WebSocket-Key header")
 
+	// ErrInvalidSecKey indicates a malformed Sec-WebSocket-Key header.
+	// RFC 6455 Section 4.2.1: Must be a base64-encoded 16-byte value.
+	ErrInvalidSecKey = errors.New("websocket: invalid Sec-WebSocket-Key header")
+
 	// ErrInvalidVersion indicates unsupported WebSocket version.
 	// RFC 6455 Section 4.4: Only version 13 is supported.
 	ErrInvalidVersion = errors.New("websocket: unsupported WebSocket version")
@@ -92,6 +103,18 @@ var (
 	// Required for upgrading to WebSocket protocol.
 	ErrHijackFailed = errors.New("websocket: cannot hijack connection")
 
+	// ErrBadHandshake indicates the server rejected or botched the opening
+	// handshake (client side).
+	// RFC 6455 Section 4.1: Server must respond 101 with valid headers.
+	// Dial returns the response alongside it for inspection.
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+
+	// ErrHandshakeTooLarge indicates the handshake request or response
+	// headers exceed the maximum size.
+	// Configurable via UpgradeOptions.MaxHeaderBytes and
+	// DialOptions.MaxHeaderBytes (default: 1 MB).
+	ErrHandshakeTooLarge = errors.New("websocket: handshake headers too large")
+
 	// Connection error types (runtime errors).
 
 	// ErrClosed indicates connection is already closed.
@@ -102,8 +125,50 @@ var (
 	// For example, calling ReadText() on binary message.
 	ErrInvalidMessageType = errors.New("websocket: invalid message type")
 
-	// ErrMessageTooLarge indicates message exceeds maximum size.
-	// Configurable via UpgradeOptions.MaxMessageSize (default: 32 MB).
+	// ErrTooManyClients indicates a Hub is at its client limit.
+	// Configurable via HubOptions.MaxClients and HubOptions.MaxPerOrigin
+	// (default: unlimited).
+	ErrTooManyClients = errors.New("websocket: too many clients")
+
+	// ErrPermanentFailure indicates a ReconnectingConn stopped because a
+	// new connection wouldn't help: the server closed the session on
+	// purpose or rejected the handshake with a 4xx status. The cause is
+	// wrapped.
+	ErrPermanentFailure = errors.New("websocket: permanent failure")
+
+	// ErrReconnectFailed indicates a ReconnectingConn gave up after
+	// failing to connect repeatedly. The last attempt's error is wrapped.
+	// Configurable via ReconnectOptions.MaxAttempts (default: unlimited).
+	ErrReconnectFailed = errors.New("websocket: reconnect failed")
+
+	// ErrHubClosed indicates a Hub was used after Close.
+	ErrHubClosed = errors.New("websocket: hub closed")
+
+	// ErrPoolClosed indicates a ClientPool was used after Close.
+	ErrPoolClosed = errors.New("websocket: client pool closed")
+
+	// ErrMessageTooLarge indicates an assembled message exceeds the
+	// maximum message size.
+	// Configurable via UpgradeOptions.MaxMessageSize, DialOptions.MaxMessageSize,
+	// or Conn.SetMaxMessageSize (default: 32 MB).
 	// Status code 1009 (message too big).
 	ErrMessageTooLarge = errors.New("websocket: message too large")
+
+	// ErrReadBudgetExceeded indicates the peer sent more data than the
+	// connection's lifetime read budget.
+	// Configurable via Conn.SetReadBudget (default: unlimited).
+	// Status code 1009 (message too big).
+	ErrReadBudgetExceeded = errors.New("websocket: read budget exceeded")
+
+	// ErrFragmentTimeout indicates a fragmented message wasn't completed
+	// within the connection's fragment timeout.
+	// Configurable via Conn.SetFragmentTimeout (default: unlimited).
+	// Status code 1008 (policy violation).
+	ErrFragmentTimeout = errors.New("websocket: fragmented message timed out")
+
+	// ErrMessageInterrupted indicates a message streamed by NextReader
+	// couldn't be completed (connection failure, close frame, or protocol
+	// violation mid-message). It wraps the underlying cause, and is returned
+	// instead of io.EOF so a truncated message isn't mistaken for a whole one.
+	ErrMessageInterrupted = errors.New("websocket: message interrupted")
 )
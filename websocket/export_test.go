@@ -107,6 +107,21 @@ const (
 	OpcodePongForTest         = opcodePong
 )
 
+// SetMaskForTest makes conn mask every client frame with key instead of a
+// random key (exported for testing).
+//
+// Use this to assert exact masked bytes on the wire.
+func SetMaskForTest(conn *Conn, key [4]byte) {
+	conn.masks = fixedMaskSource(key)
+}
+
+// fixedMaskSource returns the same masking key for every frame.
+type fixedMaskSource [4]byte
+
+func (m fixedMaskSource) newMask() [4]byte {
+	return m
+}
+
 // NewConnForTest creates a Conn from a raw net.Conn for testing.
 //
 // This is used by test clients that perform manual WebSocket handshakes.
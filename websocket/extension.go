@@ -0,0 +1,245 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Reserved bits of the frame header (RFC 6455 Section 5.2), for
+// Extension.RSV and Frame.RSV.
+const (
+	RSV1 byte = 0x40
+	RSV2 byte = 0x20
+	RSV3 byte = 0x10
+)
+
+// Extension is a WebSocket extension (RFC 6455 Section 9) implemented by
+// the application on top of ReadFrame and WriteFrame.
+//
+// Extensions are negotiated by name only: parameters in the client's
+// offer are ignored and none are sent back. Once negotiated, frames may
+// carry the extension's RSV bits; without a negotiated extension, frames
+// with RSV bits set fail the connection.
+//
+// Example:
+//
+//	ext := websocket.Extension{Name: "x-acme-tag", RSV: websocket.RSV1}
+//	conn, err := websocket.Upgrade(w, r, &websocket.UpgradeOptions{
+//	    Extensions: []websocket.Extension{ext},
+//	})
+type Extension struct {
+	// Name is the extension token sent in Sec-WebSocket-Extensions.
+	Name string
+
+	// RSV is the reserved bits the extension uses: RSV1, RSV2, RSV3, or
+	// a combination. Two negotiated extensions can't share a bit.
+	RSV byte
+}
+
+// Frame is a single WebSocket frame, for extensions that work below the
+// message level. See Conn.ReadFrame and Conn.WriteFrame.
+type Frame struct {
+	Fin     bool   // Final fragment of a message
+	RSV     byte   // Reserved bits set (RSV1, RSV2, RSV3)
+	Opcode  byte   // Frame opcode (0x0 continuation, 0x1 text, 0x2 binary, 0x8 close, ...)
+	Payload []byte // Unmasked payload
+}
+
+// Extensions returns the names of the extensions negotiated during the
+// handshake (Sec-WebSocket-Extensions), in the order of the server's
+// response, or nil if none.
+func (c *Conn) Extensions() []string {
+	return slices.Clone(c.extensions)
+}
+
+// ReadFrame reads the next frame from the peer, for extensions that
+// transform frames (RFC 6455 Section 9).
+//
+// The frame is unmasked but otherwise passed on as received: control
+// frames aren't answered, fragments aren't reassembled, and the read
+// limits of Read don't apply. RSV bits of negotiated extensions are
+// accepted, and such frames aren't checked for valid UTF-8; any other
+// RSV bit fails the connection with 1002 (Protocol Error) and
+// ErrReservedBits.
+//
+// ReadFrame must not be mixed with Read, NextReader, or Serve on the same
+// connection. Conversely, those reject frames with RSV bits set, so a
+// connection with negotiated extensions must be read with ReadFrame.
+//
+// Example:
+//
+//	f, err := conn.ReadFrame()
+//	if err != nil {
+//	    return err
+//	}
+//	if f.RSV&websocket.RSV1 != 0 {
+//	    f.Payload = decode(f.Payload)
+//	}
+func (c *Conn) ReadFrame() (*Frame, error) {
+	c.closeMu.RLock()
+	if c.closed {
+		c.closeMu.RUnlock()
+		return nil, ErrClosed
+	}
+	c.closeMu.RUnlock()
+
+	f, err := readExtensionFrame(c.reader, c.extensionRSV)
+	if err != nil {
+		// Connection dropped without a close frame
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, &CloseError{Code: CloseAbnormalClosure, Err: err}
+		}
+
+		// RFC 6455 Section 7.1.7: Fail the connection with a Close frame
+		if code, ok := frameErrorCloseCode(err); ok {
+			_ = c.CloseWithCode(code, "")
+		}
+		return nil, err
+	}
+	c.trace(DirectionRead, f)
+
+	// RFC 6455 Section 5.1: Client frames are masked, server frames aren't
+	if err := validateMasking(f, c.isServer); err != nil && !c.lenient {
+		_ = c.CloseWithCode(CloseProtocolError, "invalid masking")
+		return nil, err
+	}
+
+	return &Frame{
+		Fin:     f.fin,
+		RSV:     frameRSV(f),
+		Opcode:  f.opcode,
+		Payload: f.payload,
+	}, nil
+}
+
+// WriteFrame writes a single frame, for extensions that transform frames
+// (RFC 6455 Section 9).
+//
+// Client frames are masked automatically. RSV bits are only allowed if a
+// negotiated extension uses them (ErrReservedBits otherwise), and the
+// frame must be valid on its own: a known opcode, control frames
+// unfragmented with at most 125 bytes, and text frames without RSV bits
+// valid UTF-8. Fragmentation is the caller's job.
+//
+// Thread-Safety: Safe for concurrent writes (serialized by mutex).
+//
+// Example:
+//
+//	err := conn.WriteFrame(&websocket.Frame{
+//	    Fin:     true,
+//	    RSV:     websocket.RSV1,
+//	    Opcode:  0x2, // Binary
+//	    Payload: encode(data),
+//	})
+func (c *Conn) WriteFrame(f *Frame) error {
+	c.closeMu.RLock()
+	if c.closed {
+		c.closeMu.RUnlock()
+		return ErrClosed
+	}
+	c.closeMu.RUnlock()
+
+	if f.RSV&^c.extensionRSV != 0 {
+		return ErrReservedBits
+	}
+
+	fr := &frame{
+		fin:     f.Fin,
+		rsv1:    f.RSV&RSV1 != 0,
+		rsv2:    f.RSV&RSV2 != 0,
+		rsv3:    f.RSV&RSV3 != 0,
+		opcode:  f.Opcode,
+		masked:  !c.isServer,
+		payload: f.Payload,
+	}
+	if fr.masked {
+		fr.mask = c.newMask()
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.sendFrame(fr, c.flushMode != FlushManual)
+}
+
+// frameRSV returns f's reserved bits as an RSV1|RSV2|RSV3 mask.
+func frameRSV(f *frame) byte {
+	var rsv byte
+	if f.rsv1 {
+		rsv |= RSV1
+	}
+	if f.rsv2 {
+		rsv |= RSV2
+	}
+	if f.rsv3 {
+		rsv |= RSV3
+	}
+	return rsv
+}
+
+// parseExtensionNames returns the extension names in Sec-WebSocket-Extensions
+// header values, in order, without their parameters.
+//
+// RFC 6455 Section 9.1: extension-list = 1#extension, where
+// extension = extension-token *( ";" extension-param ).
+func parseExtensionNames(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(element, ";")
+			name = strings.Trim(name, " \t")
+			if isToken(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// negotiateExtensions selects the offered extensions the server supports,
+// in offer order. Each is accepted once, and one whose RSV bits are
+// already taken is skipped.
+//
+// Returns the accepted names and the union of their RSV bits.
+func negotiateExtensions(offered []string, supported []Extension) ([]string, byte) {
+	var accepted []string
+	var rsv byte
+	for _, name := range offered {
+		i := slices.IndexFunc(supported, func(e Extension) bool { return e.Name == name })
+		if i < 0 || slices.Contains(accepted, name) || supported[i].RSV&rsv != 0 {
+			continue
+		}
+		accepted = append(accepted, name)
+		rsv |= supported[i].RSV
+	}
+	return accepted, rsv
+}
+
+// acceptedExtensions checks the extensions a server selected against the
+// client's offer.
+//
+// RFC 6455 Section 4.1: The server may only select extensions the client
+// offered. Returns the union of their RSV bits.
+func acceptedExtensions(selected []string, offered []Extension) (byte, error) {
+	var rsv byte
+	for i, name := range selected {
+		j := slices.IndexFunc(offered, func(e Extension) bool { return e.Name == name })
+		if j < 0 || slices.Contains(selected[:i], name) {
+			return 0, fmt.Errorf("%w: server selected unrequested extension %q", ErrBadHandshake, name)
+		}
+		rsv |= offered[j].RSV
+	}
+	return rsv, nil
+}
+
+// formatExtensionNames builds a Sec-WebSocket-Extensions header value.
+func formatExtensionNames(extensions []Extension) string {
+	names := make([]string, len(extensions))
+	for i, e := range extensions {
+		names[i] = e.Name
+	}
+	return FormatSubprotocols(names) // Same token list syntax
+}
@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestConn_WriteFrame_RSV tests that a negotiated extension's RSV bits
+// round-trip through WriteFrame and ReadFrame in both directions.
+func TestConn_WriteFrame_RSV(t *testing.T) {
+	ext := Extension{Name: "x-test", RSV: RSV1}
+	errc := make(chan error, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, &UpgradeOptions{Extensions: []Extension{ext}})
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+
+		// Echo one frame, RSV bits included
+		f, err := conn.ReadFrame()
+		if err != nil {
+			errc <- err
+			return
+		}
+		errc <- conn.WriteFrame(f)
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := Dial(context.Background(), wsURL, &DialOptions{
+		// The server doesn't implement x-other, so only x-test is accepted
+		Extensions: []Extension{{Name: "x-other", RSV: RSV2}, ext},
+	})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if got := conn.Extensions(); !slices.Equal(got, []string{"x-test"}) {
+		t.Fatalf("Extensions() = %q, want [x-test]", got)
+	}
+
+	// RSV2 belongs to an extension that wasn't negotiated
+	if err := conn.WriteFrame(&Frame{Fin: true, RSV: RSV2, Opcode: opcodeBinary}); !errors.Is(err, ErrReservedBits) {
+		t.Errorf("WriteFrame(RSV2) error = %v, want ErrReservedBits", err)
+	}
+
+	// Not valid UTF-8, as an extension's encoded text may be
+	payload := []byte{0xff, 0xfe, 0x01}
+	if err := conn.WriteFrame(&Frame{Fin: true, RSV: RSV1, Opcode: opcodeText, Payload: payload}); err != nil {
+		t.Fatalf("WriteFrame(RSV1) error = %v", err)
+	}
+
+	f, err := conn.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() error = %v", err)
+	}
+	if !f.Fin || f.RSV != RSV1 || f.Opcode != opcodeText || string(f.Payload) != string(payload) {
+		t.Errorf("ReadFrame() = %+v, want text frame with RSV1 and payload %v", f, payload)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("server error = %v", err)
+	}
+}
+
+// TestConn_WriteFrame_NoExtension tests that RSV bits are rejected on a
+// connection without negotiated extensions.
+func TestConn_WriteFrame_NoExtension(t *testing.T) {
+	client, server := Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	if got := client.Extensions(); got != nil {
+		t.Errorf("Extensions() = %q, want nil", got)
+	}
+	if err := client.WriteFrame(&Frame{Fin: true, RSV: RSV1, Opcode: opcodeBinary}); !errors.Is(err, ErrReservedBits) {
+		t.Errorf("WriteFrame(RSV1) error = %v, want ErrReservedBits", err)
+	}
+
+	// Plain frames pass through the frame-level API
+	errc := make(chan error, 1)
+	go func() {
+		errc <- client.WriteFrame(&Frame{Fin: false, Opcode: opcodeBinary, Payload: []byte("ab")})
+	}()
+	f, err := server.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() error = %v", err)
+	}
+	if f.Fin || f.RSV != 0 || f.Opcode != opcodeBinary || string(f.Payload) != "ab" {
+		t.Errorf("ReadFrame() = %+v, want unfinished binary frame %q", f, "ab")
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("WriteFrame() error = %v", err)
+	}
+
+	// Control frames are passed on, not handled
+	go func() { errc <- client.Close() }()
+	f, err = server.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() error = %v", err)
+	}
+	if f.Opcode != opcodeClose {
+		t.Errorf("ReadFrame() opcode = %#x, want close", f.Opcode)
+	}
+	<-errc
+}
+
+// TestNegotiateExtensions tests server-side extension selection.
+func TestNegotiateExtensions(t *testing.T) {
+	supported := []Extension{
+		{Name: "x-a", RSV: RSV1},
+		{Name: "x-b", RSV: RSV1}, // Conflicts with x-a
+		{Name: "x-c", RSV: RSV2},
+	}
+
+	tests := []struct {
+		name    string
+		header  []string
+		want    []string
+		wantRSV byte
+	}{
+		{"none offered", nil, nil, 0},
+		{"unsupported", []string{"permessage-deflate"}, nil, 0},
+		{"params ignored", []string{"x-c; level=1, x-a"}, []string{"x-c", "x-a"}, RSV1 | RSV2},
+		{"several headers", []string{"x-a", "x-c"}, []string{"x-a", "x-c"}, RSV1 | RSV2},
+		{"duplicate", []string{"x-a, x-a"}, []string{"x-a"}, RSV1},
+		{"RSV conflict", []string{"x-b, x-a"}, []string{"x-b"}, RSV1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rsv := negotiateExtensions(parseExtensionNames(tt.header), supported)
+			if !slices.Equal(got, tt.want) || rsv != tt.wantRSV {
+				t.Errorf("negotiateExtensions() = %q, %#x, want %q, %#x", got, rsv, tt.want, tt.wantRSV)
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package websocket
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"sync"
 	"unicode/utf8"
 )
 
@@ -18,6 +20,11 @@ const (
 	// Default: 32 MB (configurable in production).
 	maxFramePayload = 32 * 1024 * 1024
 
+	// defaultMaxMessageSize is the default limit on an assembled message
+	// (see Conn.SetMaxMessageSize). It matches the frame limit, so by
+	// default fragmenting a message doesn't raise the size it can reach.
+	defaultMaxMessageSize = 32 * 1024 * 1024
+
 	// Payload length encoding thresholds (RFC 6455 Section 5.2).
 	payloadLen7Bit  = 125 // 0-125: stored in 7 bits
 	payloadLen16Bit = 126 // 126: followed by 16-bit length
@@ -90,6 +97,13 @@ type frame struct {
 //   - frame: parsed frame structure
 //   - error: validation or I/O error
 func readFrame(r *bufio.Reader) (*frame, error) {
+	return readExtensionFrame(r, 0)
+}
+
+// readExtensionFrame reads a frame like readFrame, accepting the RSV bits
+// in rsv (see Extension). The payload of a frame with RSV bits set belongs
+// to the extension, so it isn't checked for valid UTF-8.
+func readExtensionFrame(r *bufio.Reader, rsv byte) (*frame, error) {
 	// Step 1: Read 2-byte header.
 	// Byte 0: FIN(1) RSV(3) Opcode(4)
 	// Byte 1: MASK(1) PayloadLen(7)
@@ -114,7 +128,7 @@ func readFrame(r *bufio.Reader) (*frame, error) {
 
 	// Validate reserved bits (must be 0 unless extension negotiated).
 	// RFC 6455 Section 5.2: RSV bits reserved for extensions.
-	if f.rsv1 || f.rsv2 || f.rsv3 {
+	if header[0]&0x70&^rsv != 0 {
 		return nil, ErrReservedBits
 	}
 
@@ -183,13 +197,38 @@ func readFrame(r *bufio.Reader) (*frame, error) {
 
 	// Step 6: Validate UTF-8 for text frames.
 	// RFC 6455 Section 8.1: Text frames must contain valid UTF-8.
-	if f.opcode == opcodeText && !utf8.Valid(f.payload) {
+	if f.opcode == opcodeText && !f.hasRSV() && !utf8.Valid(f.payload) {
 		return nil, ErrInvalidUTF8
 	}
 
 	return f, nil
 }
 
+// hasRSV reports whether any reserved bit is set, i.e. the frame was
+// transformed by an extension.
+func (f *frame) hasRSV() bool {
+	return f.rsv1 || f.rsv2 || f.rsv3
+}
+
+// validateMasking checks a received frame's MASK bit.
+//
+// RFC 6455 Section 5.1: A client MUST mask all frames it sends to the
+// server, and a server MUST NOT mask any frames it sends to the client.
+// The receiving endpoint closes the connection with 1002 (protocol error)
+// on violation.
+//
+// isServer is the receiving side: true if f was read by a server (so it
+// must be masked), false if read by a client (so it must not be).
+func validateMasking(f *frame, isServer bool) error {
+	if isServer && !f.masked {
+		return ErrMaskRequired
+	}
+	if !isServer && f.masked {
+		return ErrMaskUnexpected
+	}
+	return nil
+}
+
 // writeFrame writes a WebSocket frame to the buffered writer.
 //
 // RFC 6455 Section 5.2: Base Framing Protocol.
@@ -205,6 +244,23 @@ func readFrame(r *bufio.Reader) (*frame, error) {
 // Returns:
 //   - error: validation or I/O error
 func writeFrame(w *bufio.Writer, f *frame) error {
+	if err := bufferFrame(w, f); err != nil {
+		return err
+	}
+
+	// Step 6: Flush buffer.
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+// bufferFrame writes a WebSocket frame to the buffered writer without
+// flushing it (steps 1-5 of writeFrame).
+//
+// The frame reaches the network when w is flushed or its buffer fills.
+func bufferFrame(w *bufio.Writer, f *frame) error {
 	// Validate opcode.
 	if !isValidOpcode(f.opcode) {
 		return fmt.Errorf("%w: 0x%X", ErrInvalidOpcode, f.opcode)
@@ -220,8 +276,8 @@ func writeFrame(w *bufio.Writer, f *frame) error {
 		}
 	}
 
-	// Validate UTF-8 for text frames.
-	if f.opcode == opcodeText && !utf8.Valid(f.payload) {
+	// Validate UTF-8 for text frames (unless transformed by an extension).
+	if f.opcode == opcodeText && !f.hasRSV() && !utf8.Valid(f.payload) {
 		return ErrInvalidUTF8
 	}
 
@@ -312,11 +368,6 @@ func writeFrame(w *bufio.Writer, f *frame) error {
 		}
 	}
 
-	// Step 6: Flush buffer.
-	if err := w.Flush(); err != nil {
-		return fmt.Errorf("flush: %w", err)
-	}
-
 	return nil
 }
 
@@ -447,3 +498,33 @@ func applyMask(data []byte, mask [4]byte) {
 		data[i] ^= mask[i%4]
 	}
 }
+
+// preparedFrame is a frame encoded once and written as is to many
+// connections, e.g. a Hub broadcast.
+//
+// Only unmasked (server-to-client) frames can be shared: client frames
+// need a fresh masking key each (RFC 6455 Section 5.3).
+type preparedFrame struct {
+	f *frame
+
+	once sync.Once
+	data []byte // Encoded frame
+	err  error  // Encoding error (invalid frame)
+}
+
+// newPreparedFrame prepares an unfragmented, unmasked data frame.
+func newPreparedFrame(opcode byte, payload []byte) *preparedFrame {
+	return &preparedFrame{f: &frame{fin: true, opcode: opcode, payload: payload}}
+}
+
+// encoded returns the encoded frame, encoding it on first use.
+//
+// Safe for concurrent use.
+func (p *preparedFrame) encoded() ([]byte, error) {
+	p.once.Do(func() {
+		var buf bytes.Buffer
+		p.err = writeFrame(bufio.NewWriter(&buf), p.f)
+		p.data = buf.Bytes()
+	})
+	return p.data, p.err
+}
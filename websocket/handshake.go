@@ -2,10 +2,14 @@ package websocket
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha1" // #nosec G505 - SHA-1 required by RFC 6455 Section 1.3
 	"encoding/base64"
+	"net"
 	"net/http"
+	"net/netip"
 	"strings"
+	"time"
 )
 
 // Magic GUID from RFC 6455 Section 1.3.
@@ -18,6 +22,10 @@ const (
 	defaultWriteBufferSize = 4096
 )
 
+// defaultMaxHeaderBytes limits the size of a handshake's request or
+// status line and headers, matching http.DefaultMaxHeaderBytes (1 MB).
+const defaultMaxHeaderBytes = http.DefaultMaxHeaderBytes
+
 // UpgradeOptions configures WebSocket upgrade behavior.
 //
 // All fields are optional. Zero values use sensible defaults.
@@ -45,6 +53,54 @@ type UpgradeOptions struct {
 	// WriteBufferSize sets size of write buffer (default: 4096).
 	// Larger buffers reduce syscalls for large messages.
 	WriteBufferSize int
+
+	// TrustedProxyHeader names the header a reverse proxy sets to the
+	// client's address, e.g. "X-Forwarded-For" or "X-Real-IP".
+	// Empty = use r.RemoteAddr (default).
+	//
+	// Only set this when every request passes through a proxy that sets
+	// the header, or clients can spoof their address. For X-Forwarded-For
+	// the last address is used: the one added by the nearest proxy.
+	// See Conn.ClientIP.
+	TrustedProxyHeader string
+
+	// MaxMessageSize limits the size of an assembled message in bytes,
+	// across all its fragments (default: 32 MB). See
+	// Conn.SetMaxMessageSize.
+	MaxMessageSize int64
+
+	// MaxHeaderBytes limits the size of the handshake request's request
+	// line and headers (default: 1 MB). Larger handshakes fail with
+	// ErrHandshakeTooLarge. net/http has already read the headers by the
+	// time Upgrade runs, so the memory it may use is bounded by
+	// http.Server.MaxHeaderBytes; this limit is a stricter policy on top.
+	MaxHeaderBytes int
+
+	// Extensions lists the extensions the server implements with
+	// ReadFrame and WriteFrame. Those the client offers are accepted, in
+	// the client's order. Empty = no extension negotiation. See Extension.
+	Extensions []Extension
+
+	// Lenient relaxes validations that noncompliant peers (or proxies in
+	// between) are known to trip, for compatibility (default: false, full
+	// RFC 6455 validation). When set:
+	//   - Frames with the wrong MASK bit are accepted (RFC 6455 Section
+	//     5.1 fails the connection with 1002).
+	//   - Close frames with a status code that must not be sent, such as
+	//     1005 or 5000, or a 1-byte payload are accepted; Read reports the
+	//     code as received and the close is answered with 1000
+	//     (Section 7.4 fails the connection with 1002 and
+	//     ErrInvalidCloseCode).
+	Lenient bool
+
+	// IdleTimeout closes the connection with 1001 (Going Away) after this
+	// long without a message read or written (default: 0, disabled). See
+	// Conn.SetIdleTimeout.
+	IdleTimeout time.Duration
+
+	// Logger receives errors the connection handles internally, such as
+	// a failed auto-pong (default: discard).
+	Logger Logger
 }
 
 // Upgrade upgrades an HTTP connection to the WebSocket protocol.
@@ -56,9 +112,9 @@ type UpgradeOptions struct {
 //  2. Check Upgrade: websocket header
 //  3. Check Connection: Upgrade header
 //  4. Verify Sec-WebSocket-Version: 13
-//  5. Get Sec-WebSocket-Key
+//  5. Validate Sec-WebSocket-Key (base64 of 16 bytes)
 //  6. Check origin (if configured)
-//  7. Negotiate subprotocol (if configured)
+//  7. Negotiate subprotocol and extensions (if configured)
 //  8. Compute Sec-WebSocket-Accept
 //  9. Send 101 Switching Protocols response
 //  10. Hijack connection
@@ -93,6 +149,15 @@ func Upgrade(w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Con
 	if opts.WriteBufferSize == 0 {
 		opts.WriteBufferSize = defaultWriteBufferSize
 	}
+	maxHeaderBytes := opts.MaxHeaderBytes
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = defaultMaxHeaderBytes
+	}
+
+	// Bound the handshake size before looking at any header
+	if requestHeaderSize(r) > maxHeaderBytes {
+		return nil, ErrHandshakeTooLarge
+	}
 
 	// 1. Verify HTTP method (RFC 6455 Section 4.1)
 	if r.Method != http.MethodGet {
@@ -115,11 +180,14 @@ func Upgrade(w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Con
 		return nil, ErrInvalidVersion
 	}
 
-	// 5. Get Sec-WebSocket-Key (RFC 6455 Section 4.2.1, item 5)
+	// 5. Validate Sec-WebSocket-Key (RFC 6455 Section 4.2.1, item 5)
 	key := r.Header.Get("Sec-WebSocket-Key")
 	if key == "" {
 		return nil, ErrMissingSecKey
 	}
+	if !validSecKey(key) {
+		return nil, ErrInvalidSecKey
+	}
 
 	// 6. Check origin (application-level security)
 	if opts.CheckOrigin != nil && !opts.CheckOrigin(r) {
@@ -129,8 +197,12 @@ func Upgrade(w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Con
 	// 7. Negotiate subprotocol (RFC 6455 Section 4.2.2, item 5)
 	subprotocol := negotiateSubprotocol(r, opts.Subprotocols)
 
+	// RFC 6455 Section 9.1: Server selects from client's offered extensions
+	extensions, extensionRSV := negotiateExtensions(
+		parseExtensionNames(r.Header.Values("Sec-WebSocket-Extensions")), opts.Extensions)
+
 	// 8. Compute Sec-WebSocket-Accept (RFC 6455 Section 4.2.2, item 4)
-	accept := computeAcceptKey(key)
+	accept := ComputeAcceptKey(key)
 
 	// 9. Send 101 Switching Protocols response
 	w.Header().Set("Upgrade", "websocket")
@@ -139,6 +211,9 @@ func Upgrade(w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Con
 	if subprotocol != "" {
 		w.Header().Set("Sec-WebSocket-Protocol", subprotocol)
 	}
+	if len(extensions) > 0 {
+		w.Header().Set("Sec-WebSocket-Extensions", strings.Join(extensions, ", "))
+	}
 	w.WriteHeader(http.StatusSwitchingProtocols)
 
 	// 10. Hijack connection (take over TCP socket)
@@ -172,11 +247,67 @@ func Upgrade(w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Con
 
 	// 12. Create WebSocket connection (server-side)
 	conn := newConn(netConn, reader, writer, true)
+	conn.subprotocol = subprotocol
+	conn.extensions = extensions
+	conn.extensionRSV = extensionRSV
+	conn.logger = opts.Logger
+	conn.lenient = opts.Lenient
+	conn.clientIP = clientIP(r, opts.TrustedProxyHeader)
+	if opts.MaxMessageSize > 0 {
+		conn.SetMaxMessageSize(opts.MaxMessageSize)
+	}
+	if opts.IdleTimeout > 0 {
+		conn.SetIdleTimeout(opts.IdleTimeout)
+	}
+
+	return conn, nil
+}
+
+// UpgradeWithContext upgrades an HTTP connection to the WebSocket protocol
+// like Upgrade, and ties the connection's lifetime to ctx.
+//
+// When ctx is canceled, the connection is closed with 1001 (Going Away),
+// so the peer sees an orderly close instead of a dropped TCP connection.
+// Pass a server-wide context to close every connection on shutdown.
+//
+// The request's context (r.Context()) is canceled when the handler
+// returns, so only pass it if the handler keeps running for the life of
+// the connection.
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//
+//	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+//	    conn, err := websocket.UpgradeWithContext(ctx, w, r, nil)
+//	    if err != nil {
+//	        http.Error(w, err.Error(), http.StatusBadRequest)
+//	        return
+//	    }
+//	    go conn.Serve(handler) // Ends with 1001 on Ctrl+C
+//	})
+func UpgradeWithContext(ctx context.Context, w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Conn, error) {
+	conn, err := Upgrade(w, r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.closeMu.Lock()
+	conn.stopContext = context.AfterFunc(ctx, func() {
+		_ = conn.CloseGoingAway("")
+	})
+	conn.closeMu.Unlock()
 
 	return conn, nil
 }
 
-// computeAcceptKey computes Sec-WebSocket-Accept from client key.
+// ComputeAcceptKey computes Sec-WebSocket-Accept from client key.
+//
+// A server proves it understood the handshake by returning this value for
+// the client's Sec-WebSocket-Key. Client implementations and compliance
+// harnesses can use it to verify a server's response; Dial and Upgrade
+// already use it internally.
 //
 // RFC 6455 Section 1.3:
 //
@@ -187,9 +318,9 @@ func Upgrade(w http.ResponseWriter, r *http.Request, opts *UpgradeOptions) (*Con
 // Example:
 //
 //	key := "dGhlIHNhbXBsZSBub25jZQ=="
-//	accept := computeAcceptKey(key)
+//	accept := ComputeAcceptKey(key)
 //	// accept = "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
-func computeAcceptKey(key string) string {
+func ComputeAcceptKey(key string) string {
 	// #nosec G401 - SHA-1 required by RFC 6455 Section 1.3 (not for cryptographic security)
 	h := sha1.New()
 	h.Write([]byte(key))
@@ -197,6 +328,26 @@ func computeAcceptKey(key string) string {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
+// validSecKey reports whether key is a base64-encoded 16-byte nonce, as
+// RFC 6455 Section 4.2.1 requires of Sec-WebSocket-Key.
+func validSecKey(key string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(decoded) == 16
+}
+
+// requestHeaderSize returns the size of r's request line and headers as
+// sent on the wire.
+func requestHeaderSize(r *http.Request) int {
+	// "METHOD URI PROTO\r\n"
+	n := len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4
+	for name, values := range r.Header {
+		for _, v := range values {
+			n += len(name) + len(v) + 4 // "Name: value\r\n"
+		}
+	}
+	return n
+}
+
 // negotiateSubprotocol selects first match from client's requested subprotocols.
 //
 // RFC 6455 Section 1.9: Server selects ONE subprotocol from client's list.
@@ -207,9 +358,9 @@ func negotiateSubprotocol(r *http.Request, serverProtos []string) string {
 		return ""
 	}
 
-	clientProtos := strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")
+	// The offer may be split across several header lines
+	clientProtos := ParseSubprotocols(strings.Join(r.Header.Values("Sec-WebSocket-Protocol"), ","))
 	for _, clientProto := range clientProtos {
-		clientProto = strings.TrimSpace(clientProto)
 		for _, serverProto := range serverProtos {
 			if clientProto == serverProto {
 				return clientProto
@@ -220,6 +371,25 @@ func negotiateSubprotocol(r *http.Request, serverProtos []string) string {
 	return ""
 }
 
+// clientIP returns the client's IP address for r.
+//
+// If header is set and holds a valid IP address, it's used (the last one
+// of a comma-separated list); otherwise the host of r.RemoteAddr.
+func clientIP(r *http.Request, header string) string {
+	if header != "" {
+		values := strings.Split(r.Header.Get(header), ",")
+		if addr, err := netip.ParseAddr(strings.TrimSpace(values[len(values)-1])); err == nil {
+			return addr.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // headerContainsToken checks if header value contains token (case-insensitive).
 //
 // RFC 6455 Section 4.2.1: Header tokens are case-insensitive.
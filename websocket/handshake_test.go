@@ -1,9 +1,12 @@
 package websocket
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -197,6 +200,66 @@ func TestUpgrade_MissingSecKey(t *testing.T) {
 	}
 }
 
+// TestUpgrade_InvalidSecKey verifies that the key must be base64 of
+// exactly 16 bytes.
+func TestUpgrade_InvalidSecKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		// Valid: the handshake gets as far as hijacking, which the
+		// recorder doesn't support
+		{"RFC example", "dGhlIHNhbXBsZSBub25jZQ==", ErrHijackFailed},
+		{"too short", "c2hvcnQ=", ErrInvalidSecKey},                    // "short"
+		{"too long", "dGhpcyBrZXkgaXMgdG9vIGxvbmc=", ErrInvalidSecKey}, // 20 bytes
+		{"not base64", "not a base64 key!", ErrInvalidSecKey},
+		{"unpadded", "dGhlIHNhbXBsZSBub25jZQ", ErrInvalidSecKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ws", http.NoBody)
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", tt.key)
+
+			_, err := Upgrade(httptest.NewRecorder(), req, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Upgrade() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestUpgrade_HandshakeTooLarge verifies that Upgrade rejects a request
+// whose headers exceed UpgradeOptions.MaxHeaderBytes.
+func TestUpgrade_HandshakeTooLarge(t *testing.T) {
+	newRequest := func(padding int) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/ws", http.NoBody)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("X-Pad", strings.Repeat("a", padding))
+		return req
+	}
+	opts := &UpgradeOptions{MaxHeaderBytes: 1024}
+
+	_, err := Upgrade(httptest.NewRecorder(), newRequest(2048), opts)
+	if !errors.Is(err, ErrHandshakeTooLarge) {
+		t.Errorf("Upgrade() error = %v, want ErrHandshakeTooLarge", err)
+	}
+
+	// Within the limit, the handshake gets as far as hijacking, which the
+	// recorder doesn't support
+	_, err = Upgrade(httptest.NewRecorder(), newRequest(16), opts)
+	if !errors.Is(err, ErrHijackFailed) {
+		t.Errorf("Upgrade() error = %v, want ErrHijackFailed", err)
+	}
+}
+
 // TestUpgrade_OriginCheck verifies custom origin checking.
 func TestUpgrade_OriginCheck(t *testing.T) {
 	tests := []struct {
@@ -393,6 +456,64 @@ func TestUpgrade_BufferSizes(t *testing.T) {
 	}
 }
 
+// TestUpgradeWithContext_Cancel verifies that canceling the context closes
+// the connection with 1001 Going Away.
+func TestUpgradeWithContext_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upgraded := make(chan *Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWithContext(ctx, w, r, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		upgraded <- conn
+	}))
+	defer server.Close()
+
+	client := dialTestServer(t, server)
+	defer client.Close()
+	serverConn := <-upgraded
+
+	cancel()
+
+	_, _, err := client.Read()
+	if !IsCloseErrorCode(err, CloseGoingAway) {
+		t.Fatalf("Read() error = %v, want close 1001", err)
+	}
+	if err := serverConn.WriteText("late"); !errors.Is(err, ErrClosed) {
+		t.Errorf("WriteText() after cancel error = %v, want ErrClosed", err)
+	}
+}
+
+// TestUpgradeWithContext_CloseFirst verifies that closing the connection
+// before the context is canceled keeps the original close code.
+func TestUpgradeWithContext_CloseFirst(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWithContext(ctx, w, r, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = conn.Close()
+		cancel() // No second close frame
+	}))
+	defer server.Close()
+
+	client := dialTestServer(t, server)
+	defer client.Close()
+
+	_, _, err := client.Read()
+	if !IsCloseErrorCode(err, CloseNormalClosure) {
+		t.Fatalf("Read() error = %v, want close 1000", err)
+	}
+}
+
 // TestComputeAcceptKey verifies Sec-WebSocket-Accept calculation.
 //
 // RFC 6455 Section 1.3: SHA-1(key + GUID) base64 encoded.
@@ -416,9 +537,9 @@ func TestComputeAcceptKey(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := computeAcceptKey(tt.key)
+			got := ComputeAcceptKey(tt.key)
 			if got != tt.want {
-				t.Errorf("computeAcceptKey(%q) = %q, want %q", tt.key, got, tt.want)
+				t.Errorf("ComputeAcceptKey(%q) = %q, want %q", tt.key, got, tt.want)
 			}
 		})
 	}
@@ -483,6 +604,111 @@ func TestNegotiateSubprotocol(t *testing.T) {
 	}
 }
 
+// TestClientIP verifies client address selection from RemoteAddr and
+// the trusted proxy header.
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		xff    string
+		want   string
+	}{
+		{
+			name: "remote addr",
+			xff:  "203.0.113.7",
+			want: "192.0.2.1",
+		},
+		{
+			name:   "forwarded",
+			header: "X-Forwarded-For",
+			xff:    "203.0.113.7",
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "last forwarded address",
+			header: "X-Forwarded-For",
+			xff:    "198.51.100.1, 203.0.113.7",
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "ipv6",
+			header: "X-Forwarded-For",
+			xff:    "2001:db8::1",
+			want:   "2001:db8::1",
+		},
+		{
+			name:   "missing header",
+			header: "X-Forwarded-For",
+			want:   "192.0.2.1",
+		},
+		{
+			name:   "invalid address",
+			header: "X-Forwarded-For",
+			xff:    "unknown",
+			want:   "192.0.2.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+
+			got := clientIP(req, tt.header)
+			if got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUpgrade_ClientIP verifies that ClientIP uses X-Forwarded-For only
+// when TrustedProxyHeader is set.
+func TestUpgrade_ClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "default", want: "127.0.0.1"},
+		{name: "trusted proxy", header: "X-Forwarded-For", want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := Upgrade(w, r, &UpgradeOptions{TrustedProxyHeader: tt.header})
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				defer conn.Close()
+				got <- conn.ClientIP()
+			}))
+			defer server.Close()
+
+			wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+			conn, _, err := Dial(context.Background(), wsURL, &DialOptions{
+				Header: http.Header{"X-Forwarded-For": {"203.0.113.7"}},
+			})
+			if err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			defer conn.Close()
+
+			if ip := <-got; ip != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", ip, tt.want)
+			}
+			if ip := conn.ClientIP(); ip != "" {
+				t.Errorf("client-side ClientIP() = %q, want empty", ip)
+			}
+		})
+	}
+}
+
 // TestHeaderContainsToken verifies case-insensitive token matching.
 func TestHeaderContainsToken(t *testing.T) {
 	tests := []struct {
@@ -618,6 +844,39 @@ func TestCheckSameOrigin(t *testing.T) {
 }
 
 // BenchmarkComputeAcceptKey benchmarks Sec-WebSocket-Accept calculation.
+// TestComputeAcceptKey_ServerResponse tests that a server's
+// Sec-WebSocket-Accept can be verified with ComputeAcceptKey.
+func TestComputeAcceptKey_ServerResponse(t *testing.T) {
+	server := newTestServer(t, func(*Conn) {})
+	defer server.Close()
+
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	req, err := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	if got, want := resp.Header.Get("Sec-WebSocket-Accept"), ComputeAcceptKey(key); got != want {
+		t.Errorf("Sec-WebSocket-Accept = %q, want %q", got, want)
+	}
+	if got := ComputeAcceptKey(key); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("ComputeAcceptKey(%q) = %q, want the RFC 6455 value", key, got)
+	}
+}
+
 func BenchmarkComputeAcceptKey(b *testing.B) {
 	key := "dGhlIHNhbXBsZSBub25jZQ=="
 
@@ -625,7 +884,7 @@ func BenchmarkComputeAcceptKey(b *testing.B) {
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		_ = computeAcceptKey(key)
+		_ = ComputeAcceptKey(key)
 	}
 }
 
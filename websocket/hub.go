@@ -1,8 +1,15 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json/v2"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coregx/stream/metrics"
 )
 
 // Hub manages multiple WebSocket connections for broadcasting.
@@ -39,36 +46,198 @@ type Hub struct {
 	clients map[*Conn]bool // Registered clients
 
 	// Channels for event loop
-	register   chan *Conn  // Register new client
-	unregister chan *Conn  // Unregister client
-	broadcast  chan []byte // Broadcast message to all
+	register   chan hubRegistration // Register new client
+	unregister chan *Conn           // Unregister client
+	broadcast  chan hubBroadcast    // Broadcast message to all
 
 	// Lifecycle management
-	done   chan struct{}  // Shutdown signal
-	closed bool           // Track if hub is closed
-	wg     sync.WaitGroup // Wait for goroutines
+	done    chan struct{}  // Shutdown signal
+	closed  bool           // Track if hub is closed
+	started bool           // Event loop started (by Run or lazily)
+	wg      sync.WaitGroup // Wait for goroutines
 
 	// Thread-safety for clients map and closed flag
 	mu sync.RWMutex
+
+	// metrics receives hub events (metrics.Discard if unset)
+	metrics metrics.MetricsSink
+
+	// logger receives dropped-client events (nopLogger if unset)
+	logger Logger
+
+	// maxClients limits registered clients (0 = unlimited)
+	maxClients int
+
+	// maxPerOrigin limits registered clients per client IP (0 =
+	// unlimited); origins counts them. Protected by mu.
+	maxPerOrigin int
+	origins      map[string]int
+
+	// shards partition clients across broadcast workers (nil = a
+	// goroutine per client per broadcast). Fixed at creation; their
+	// client sets are protected by mu.
+	shards []*hubShard
+
+	// Keep-alive pings (pingInterval 0 = disabled)
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+	pingers      map[*Conn]context.CancelFunc // Stops each client's ping loop
+}
+
+// hubRegistration is a client queued for registration.
+type hubRegistration struct {
+	client *Conn
+	result chan error // Receives nil or ErrTooManyClients
+}
+
+// hubBroadcast is a message queued for broadcast.
+type hubBroadcast struct {
+	msgType MessageType // Frame type (0 = BinaryMessage)
+	message []byte
+	filter  func(*Conn) bool     // Selects recipients (nil = all clients)
+	result  chan BroadcastResult // Receives delivery counts (nil if not wanted)
+}
+
+// hubShard is a broadcast worker and the clients it writes to.
+type hubShard struct {
+	clients map[*Conn]bool     // Protected by Hub.mu
+	jobs    chan *broadcastRun // Broadcasts for this shard, in order
+}
+
+// broadcastRun tracks one broadcast's delivery across clients or shards.
+type broadcastRun struct {
+	msg       hubBroadcast
+	msgType   MessageType
+	prepared  *preparedFrame // Framed once for all server connections
+	wg        sync.WaitGroup
+	delivered atomic.Int64
+	failed    atomic.Int64
+}
+
+// BroadcastResult reports the outcome of a broadcast.
+type BroadcastResult struct {
+	Delivered int // Clients the message was written to
+	Failed    int // Clients whose write failed (now unregistered)
+}
+
+// HubOptions configures a Hub.
+//
+// A nil *HubOptions (or zero fields) uses defaults.
+type HubOptions struct {
+	// Metrics receives connection, broadcast, and write events, reported
+	// with transport metrics.TransportWebSocket (default: none).
+	Metrics metrics.MetricsSink
+
+	// Logger receives events the hub handles on its own: clients dropped
+	// after a failed write or a pong timeout, and rejected registrations
+	// (default: discard).
+	Logger Logger
+
+	// MaxClients limits the number of registered clients (default: 0,
+	// unlimited). Register returns ErrTooManyClients once it's reached.
+	MaxClients int
+
+	// MaxPerOrigin limits the number of registered clients from one
+	// client IP address, as reported by Conn.ClientIP (default: 0,
+	// unlimited). Register returns ErrTooManyClients once it's reached,
+	// so a single host can't take all of MaxClients. Behind a reverse
+	// proxy, set UpgradeOptions.TrustedProxyHeader, or every client
+	// shares the proxy's address.
+	MaxPerOrigin int
+
+	// PingInterval is how often the hub pings each client (default: 0,
+	// no pings). Clients that don't answer within PongTimeout are
+	// unregistered, which detects half-open connections that would
+	// otherwise linger until a write fails.
+	//
+	// Pongs are received by the client's read loop, so every registered
+	// connection must have Read (or Serve) running.
+	PingInterval time.Duration
+
+	// PongTimeout is how long to wait for a pong after each ping
+	// (default: PingInterval).
+	PongTimeout time.Duration
+
+	// Shards partitions clients across this many broadcast workers
+	// (default: 0, unsharded).
+	//
+	// Unsharded, every broadcast starts a goroutine per client, which
+	// isolates slow clients but costs a goroutine per client per message
+	// and doesn't keep consecutive broadcasts in order. Sharded, each
+	// worker writes its clients one after another, so broadcasts reach
+	// each client in the order they were sent and the shards work in
+	// parallel; a slow client delays the others in its shard. Useful for
+	// 10k+ clients, with Shards around runtime.NumCPU().
+	Shards int
 }
 
 // NewHub creates a new WebSocket Hub.
 //
-// The Hub must be started by calling Run() in a goroutine:
+// Start the Hub by calling Run() in a goroutine:
 //
 //	hub := websocket.NewHub()
 //	go hub.Run()
 //	defer hub.Close()
 //
+// If Run isn't called, the first Register or Broadcast starts the event
+// loop.
+//
 // Returns a ready-to-use Hub with initialized channels.
 func NewHub() *Hub {
-	return &Hub{
+	return NewHubWithOptions(nil)
+}
+
+// NewHubWithOptions creates a new WebSocket Hub with custom options.
+//
+// Like NewHub, start the Hub by calling Run() in a goroutine.
+//
+// Example:
+//
+//	collector := metrics.NewCollector()
+//	hub := websocket.NewHubWithOptions(&websocket.HubOptions{
+//	    Metrics: collector,
+//	})
+//	go hub.Run()
+func NewHubWithOptions(opts *HubOptions) *Hub {
+	if opts == nil {
+		opts = &HubOptions{}
+	}
+
+	h := &Hub{
 		clients:    make(map[*Conn]bool),
-		register:   make(chan *Conn),
+		register:   make(chan hubRegistration),
 		unregister: make(chan *Conn),
-		broadcast:  make(chan []byte, 256), // Buffered for performance
+		broadcast:  make(chan hubBroadcast, 256), // Buffered for performance
 		done:       make(chan struct{}),
+		metrics:    opts.Metrics,
+		logger:     opts.Logger,
+		maxClients: opts.MaxClients,
+
+		maxPerOrigin: opts.MaxPerOrigin,
+		origins:      make(map[string]int),
+
+		pingInterval: opts.PingInterval,
+		pongTimeout:  opts.PongTimeout,
+		pingers:      make(map[*Conn]context.CancelFunc),
+	}
+
+	if h.metrics == nil {
+		h.metrics = metrics.Discard
+	}
+	if h.logger == nil {
+		h.logger = nopLogger{}
+	}
+	if h.pongTimeout <= 0 {
+		h.pongTimeout = h.pingInterval
+	}
+	for i := 0; i < opts.Shards; i++ {
+		h.shards = append(h.shards, &hubShard{
+			clients: make(map[*Conn]bool),
+			jobs:    make(chan *broadcastRun, 64),
+		})
 	}
+
+	return h
 }
 
 // Run starts the Hub's event loop.
@@ -83,46 +252,311 @@ func NewHub() *Hub {
 //   - Graceful shutdown
 //
 // Run exits when Close() is called.
+//
+// Calling Run is optional: the first Register or Broadcast starts the event
+// loop if it isn't running, so a hub used before its Run goroutine gets
+// scheduled doesn't block. Only one event loop ever runs; a Run call after
+// the loop has started doesn't start another, and just blocks until Close.
 func (h *Hub) Run() {
-	h.wg.Add(1)
+	if !h.claimLoop() {
+		<-h.done
+		return
+	}
 	defer h.wg.Done()
 
+	h.loop()
+}
+
+// claimLoop marks the event loop as started and reports whether the caller
+// should run it (false if it's already running or the hub is closed).
+func (h *Hub) claimLoop() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed || h.started {
+		return false
+	}
+	h.started = true
+	h.wg.Add(1)
+	return true
+}
+
+// open reports whether the hub accepts operations (false after Close),
+// starting the event loop if Run hasn't been called yet.
+func (h *Hub) open() bool {
+	h.mu.RLock()
+	closed, started := h.closed, h.started
+	h.mu.RUnlock()
+
+	if !closed && !started && h.claimLoop() {
+		go func() {
+			defer h.wg.Done()
+			h.loop()
+		}()
+	}
+	return !closed
+}
+
+// loop is the event loop run by Run.
+func (h *Hub) loop() {
+	for _, shard := range h.shards {
+		h.wg.Add(1)
+		go h.runShard(shard)
+	}
+
 	for {
 		select {
-		case client := <-h.register:
-			// Register new client
+		case reg := <-h.register:
+			// Register new client (re-registration is a no-op)
+			var err error
 			h.mu.Lock()
-			h.clients[client] = true
+			switch {
+			case h.clients[reg.client]:
+			case h.maxClients > 0 && len(h.clients) >= h.maxClients:
+				err = ErrTooManyClients
+			case h.maxPerOrigin > 0 && h.origins[reg.client.ClientIP()] >= h.maxPerOrigin:
+				err = ErrTooManyClients
+			default:
+				h.clients[reg.client] = true
+				h.origins[reg.client.ClientIP()]++
+				h.assignShard(reg.client)
+				h.metrics.ClientConnected(metrics.TransportWebSocket)
+				h.startPinger(reg.client)
+			}
 			h.mu.Unlock()
+			if err != nil {
+				h.logger.Info("websocket: hub rejected client", "client", reg.client.ClientIP(), "err", err)
+			}
+			reg.result <- err
 
 		case client := <-h.unregister:
 			// Unregister client
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
+				h.releaseOrigin(client)
+				for _, shard := range h.shards {
+					delete(shard.clients, client)
+				}
+				h.stopPinger(client)
 				_ = client.Close() // Close connection
+				h.metrics.ClientDisconnected(metrics.TransportWebSocket)
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			// Broadcast to all clients
+		case msg := <-h.broadcast:
+			h.handleBroadcast(msg)
+
+		case <-h.done:
+			// Shutdown
+			return
+		}
+	}
+}
+
+// assignShard adds a new client to the shard with the fewest clients, if
+// the hub is sharded. Caller must hold h.mu.
+func (h *Hub) assignShard(client *Conn) {
+	var target *hubShard
+	for _, shard := range h.shards {
+		if target == nil || len(shard.clients) < len(target.clients) {
+			target = shard
+		}
+	}
+	if target != nil {
+		target.clients[client] = true
+	}
+}
+
+// releaseOrigin uncounts an unregistered client from its IP's total.
+// Caller must hold h.mu.
+func (h *Hub) releaseOrigin(client *Conn) {
+	ip := client.ClientIP()
+	if h.origins[ip] <= 1 {
+		delete(h.origins, ip)
+	} else {
+		h.origins[ip]--
+	}
+}
+
+// startPinger starts the ping loop for a newly registered client, if
+// pings are enabled. Caller must hold h.mu.
+func (h *Hub) startPinger(client *Conn) {
+	if h.pingInterval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.pingers[client] = cancel
+	h.wg.Add(1)
+	go h.pingLoop(ctx, client)
+}
+
+// stopPinger stops a client's ping loop. Caller must hold h.mu.
+func (h *Hub) stopPinger(client *Conn) {
+	if cancel, ok := h.pingers[client]; ok {
+		cancel()
+		delete(h.pingers, client)
+	}
+}
+
+// pingLoop pings client every pingInterval until ctx is canceled or the
+// hub closes. A client that doesn't answer within pongTimeout (or can't
+// be pinged) is unregistered.
+func (h *Hub) pingLoop(ctx context.Context, client *Conn) {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(h.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-h.done:
+			return
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, h.pongTimeout)
+		_, err := client.PingWithTimeout(pingCtx)
+		cancel()
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			// Stopped while waiting (unregistered or hub closing)
+			return
+		}
+
+		// Unresponsive: evict (not via Unregister, which could block
+		// once Run has exited)
+		h.logger.Warn("websocket: hub dropped unresponsive client", "client", client.ClientIP(), "err", err)
+		select {
+		case h.unregister <- client:
+		case <-h.done:
+		}
+		return
+	}
+}
+
+// handleBroadcast writes a message to all clients.
+//
+// Unsharded, each client is written in its own goroutine so a slow client
+// doesn't block the event loop; sharded, the message is queued to every
+// shard's worker. If msg.result is set, delivery counts are sent on it
+// once every write has finished.
+func (h *Hub) handleBroadcast(msg hubBroadcast) {
+	h.metrics.Broadcast(metrics.TransportWebSocket)
+
+	run := &broadcastRun{msg: msg, msgType: msg.msgType}
+	if run.msgType == 0 {
+		run.msgType = BinaryMessage
+	}
+	run.prepared = newPreparedFrame(byte(run.msgType), msg.message)
+
+	if len(h.shards) > 0 {
+		for _, shard := range h.shards {
+			run.wg.Add(1)
+			select {
+			case shard.jobs <- run:
+			case <-h.done:
+				run.wg.Done()
+			}
+		}
+	} else {
+		h.mu.RLock()
+		for client := range h.clients {
+			if msg.filter != nil && !msg.filter(client) {
+				continue
+			}
+			run.wg.Add(1)
+			// Send in goroutine to avoid blocking on slow clients
+			go func(c *Conn) {
+				defer run.wg.Done()
+				if !h.deliver(run, c) {
+					// Auto-unregister on write failure
+					h.Unregister(c)
+				}
+			}(client)
+		}
+		h.mu.RUnlock()
+	}
+
+	if msg.result != nil {
+		go func() {
+			run.wg.Wait()
+			msg.result <- BroadcastResult{
+				Delivered: int(run.delivered.Load()),
+				Failed:    int(run.failed.Load()),
+			}
+		}()
+	}
+}
+
+// runShard writes the broadcasts queued for shard to its clients, one
+// client after another, until the hub closes.
+func (h *Hub) runShard(shard *hubShard) {
+	defer h.wg.Done()
+
+	var clients []*Conn
+	for {
+		select {
+		case run := <-shard.jobs:
+			clients = clients[:0]
 			h.mu.RLock()
-			for client := range h.clients {
-				// Send in goroutine to avoid blocking on slow clients
-				go func(c *Conn, msg []byte) {
-					if err := c.Write(BinaryMessage, msg); err != nil {
-						// Auto-unregister on write failure
-						h.Unregister(c)
-					}
-				}(client, message)
+			for client := range shard.clients {
+				if run.msg.filter == nil || run.msg.filter(client) {
+					clients = append(clients, client)
+				}
 			}
 			h.mu.RUnlock()
 
+			for _, client := range clients {
+				if !h.deliver(run, client) {
+					// Asynchronously: the event loop may be waiting to
+					// queue the next broadcast to this shard
+					go h.Unregister(client)
+				}
+			}
+			run.wg.Done()
+
 		case <-h.done:
-			// Shutdown
-			return
+			// Release BroadcastWithResult waits for queued broadcasts
+			for {
+				select {
+				case run := <-shard.jobs:
+					run.wg.Done()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver writes run's message to client and records the outcome,
+// reporting whether the write succeeded.
+//
+// Server connections all get the same prepared frame; client connections
+// (from Dial) mask theirs, so each frame is encoded separately.
+func (h *Hub) deliver(run *broadcastRun, client *Conn) bool {
+	var err error
+	if client.isServer {
+		err = client.writePrepared(run.prepared)
+	} else {
+		err = client.Write(run.msgType, run.msg.message)
+	}
+	if err != nil {
+		run.failed.Add(1)
+		if !errors.Is(err, ErrClosed) { // Not already closed elsewhere
+			h.logger.Warn("websocket: hub dropped client after failed write", "client", client.ClientIP(), "err", err)
 		}
+		return false
 	}
+	run.delivered.Add(1)
+	h.metrics.BytesWritten(metrics.TransportWebSocket, len(run.msg.message))
+	return true
 }
 
 // Register adds a client to the Hub.
@@ -132,18 +566,27 @@ func (h *Hub) Run() {
 // Typically called after successful WebSocket upgrade:
 //
 //	conn, _ := websocket.Upgrade(w, r, nil)
-//	hub.Register(conn)
+//	if err := hub.Register(conn); err != nil {
+//	    _ = conn.CloseWithCode(websocket.CloseTryAgainLater, "server full")
+//	    return
+//	}
+//
+// Returns ErrTooManyClients if HubOptions.MaxClients, or MaxPerOrigin for
+// the client's IP, is reached; the limits are checked in the event loop,
+// so they hold under concurrent Registers.
+// A rejected client is left open for the caller to close. Registering an
+// already registered client is a no-op. After Close, Register is a no-op
+// and returns nil.
 //
 // Thread-safe: can be called from multiple goroutines.
-func (h *Hub) Register(client *Conn) {
-	h.mu.RLock()
-	if h.closed {
-		h.mu.RUnlock()
-		return
+func (h *Hub) Register(client *Conn) error {
+	if !h.open() {
+		return nil
 	}
-	h.mu.RUnlock()
 
-	h.register <- client
+	result := make(chan error, 1)
+	h.register <- hubRegistration{client: client, result: result}
+	return <-result
 }
 
 // Unregister removes a client from the Hub.
@@ -157,12 +600,9 @@ func (h *Hub) Register(client *Conn) {
 // Thread-safe: can be called from multiple goroutines.
 // Safe to call multiple times for the same client (no-op after first call).
 func (h *Hub) Unregister(client *Conn) {
-	h.mu.RLock()
-	if h.closed {
-		h.mu.RUnlock()
+	if !h.open() {
 		return
 	}
-	h.mu.RUnlock()
 
 	h.unregister <- client
 }
@@ -181,14 +621,111 @@ func (h *Hub) Unregister(client *Conn) {
 // Thread-safe: can be called from multiple goroutines.
 // Non-blocking: queues message and returns immediately.
 func (h *Hub) Broadcast(message []byte) {
-	h.mu.RLock()
-	if h.closed {
-		h.mu.RUnlock()
+	if !h.open() {
+		return
+	}
+
+	h.broadcast <- hubBroadcast{message: message}
+}
+
+// BroadcastMessage sends a message of the given type to all connected
+// clients.
+//
+// Broadcast always sends binary frames; BroadcastMessage keeps the type, so
+// a relay can forward what it read with Conn.Read as text or binary, as
+// the sender sent it.
+//
+// Like Broadcast, the message is queued and delivered asynchronously.
+//
+// Example:
+//
+//	for {
+//	    msgType, data, err := conn.Read()
+//	    if err != nil {
+//	        break
+//	    }
+//	    _ = hub.BroadcastMessage(msgType, data)
+//	}
+//
+// Returns ErrInvalidMessageType if msgType isn't TextMessage or
+// BinaryMessage, or ErrInvalidUTF8 for a text message that isn't valid
+// UTF-8; nothing is sent in either case.
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub) BroadcastMessage(msgType MessageType, data []byte) error {
+	// Validate up front: a write error would unregister every client
+	if _, err := messageOpcode(msgType, data); err != nil {
+		return err
+	}
+
+	if !h.open() {
+		return nil
+	}
+
+	h.broadcast <- hubBroadcast{msgType: msgType, message: data}
+	return nil
+}
+
+// BroadcastFunc sends a message to the connected clients for which pred
+// returns true.
+//
+// It's a lightweight alternative to keeping separate hubs per audience:
+// tag connections with Conn.SetMetadata and select them at broadcast time.
+// pred runs in the hub's event loop once per client (in the shard workers,
+// concurrently, if HubOptions.Shards is set), so it should be fast and
+// must not call Hub methods; reading Conn.Metadata is safe.
+//
+// Like Broadcast, the message is queued and delivered asynchronously.
+//
+// Example:
+//
+//	conn.SetMetadata("locale", "de")
+//
+//	hub.BroadcastFunc(greetingDE, func(c *websocket.Conn) bool {
+//	    return c.Metadata("locale") == "de"
+//	})
+//
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub) BroadcastFunc(message []byte, pred func(*Conn) bool) {
+	if !h.open() {
 		return
 	}
-	h.mu.RUnlock()
 
-	h.broadcast <- message
+	h.broadcast <- hubBroadcast{message: message, filter: pred}
+}
+
+// BroadcastWithResult sends a message to all connected clients and waits
+// for delivery.
+//
+// Unlike Broadcast, it blocks until the message has been written to every
+// client (or the write failed), and reports how many clients received it.
+// Clients whose write failed are unregistered, as with Broadcast.
+//
+// Returns a zero BroadcastResult if the hub is closed.
+//
+// Example:
+//
+//	result := hub.BroadcastWithResult([]byte("Deploy finished"))
+//	if result.Failed > 0 {
+//	    log.Printf("broadcast failed for %d of %d clients",
+//	        result.Failed, result.Delivered+result.Failed)
+//	}
+//
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub) BroadcastWithResult(message []byte) BroadcastResult {
+	if !h.open() {
+		return BroadcastResult{}
+	}
+
+	result := make(chan BroadcastResult, 1)
+	h.broadcast <- hubBroadcast{message: message, result: result}
+
+	select {
+	case r := <-result:
+		return r
+	case <-h.done:
+		// Closed before the broadcast was processed
+		return BroadcastResult{}
+	}
 }
 
 // BroadcastText sends a text message to all connected clients.
@@ -206,7 +743,8 @@ func (h *Hub) BroadcastText(text string) {
 
 // BroadcastJSON sends a JSON message to all connected clients.
 //
-// Marshals the value to JSON and broadcasts as text message.
+// Marshals the value to JSON once and broadcasts it as a text message.
+// The frame is built once too, so every client receives the same bytes.
 //
 // Example:
 //
@@ -224,7 +762,45 @@ func (h *Hub) BroadcastJSON(v any) error {
 		return err
 	}
 
-	h.Broadcast(data)
+	if h.open() {
+		h.broadcast <- hubBroadcast{msgType: TextMessage, message: data}
+	}
+	return nil
+}
+
+// BroadcastTextFunc sends a text message to the clients for which pred
+// returns true.
+//
+// Convenience wrapper around BroadcastFunc() for text messages.
+//
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub) BroadcastTextFunc(text string, pred func(*Conn) bool) {
+	h.BroadcastFunc([]byte(text), pred)
+}
+
+// BroadcastJSONFunc sends a JSON message to the clients for which pred
+// returns true.
+//
+// Marshals the value to JSON once and broadcasts it as a text message,
+// like BroadcastJSON, to the clients pred selects.
+//
+// Example:
+//
+//	err := hub.BroadcastJSONFunc(alert, func(c *websocket.Conn) bool {
+//	    return c.Metadata("role") == "admin"
+//	})
+//
+// Returns error if JSON marshaling fails.
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub) BroadcastJSONFunc(v any, pred func(*Conn) bool) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if h.open() {
+		h.broadcast <- hubBroadcast{msgType: TextMessage, message: data, filter: pred}
+	}
 	return nil
 }
 
@@ -237,12 +813,50 @@ func (h *Hub) ClientCount() int {
 	return len(h.clients)
 }
 
+// Clients returns the number of currently connected clients.
+//
+// Same as ClientCount; it lets Hub satisfy the stream.Broadcaster interface.
+//
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub) Clients() int {
+	return h.ClientCount()
+}
+
+// Publish sends msg to all connected clients.
+//
+// Unlike Broadcast, msg may be of any type, so the hub can be used through
+// the transport-independent stream.Broadcaster interface:
+//   - string and []byte: sent as-is
+//   - fmt.Stringer: String() method called
+//   - other types: JSON-encoded (see BroadcastJSON)
+//
+// Returns ErrHubClosed if the hub is closed, or an error if JSON
+// marshaling fails.
+// Thread-safe: can be called from multiple goroutines.
+func (h *Hub) Publish(msg any) error {
+	if !h.open() {
+		return ErrHubClosed
+	}
+
+	switch v := msg.(type) {
+	case string:
+		h.BroadcastText(v)
+	case []byte:
+		h.Broadcast(v)
+	case fmt.Stringer:
+		h.BroadcastText(v.String())
+	default:
+		return h.BroadcastJSON(v)
+	}
+	return nil
+}
+
 // Close stops the Hub and disconnects all clients.
 //
 // Performs graceful shutdown:
 //  1. Sets closed flag to prevent new operations
-//  2. Stops the event loop
-//  3. Waits for Run() to exit
+//  2. Stops the event loop and client ping loops
+//  3. Waits for Run() and the ping loops to exit
 //  4. Closes all client connections
 //  5. Closes all channels
 //
@@ -252,6 +866,26 @@ func (h *Hub) ClientCount() int {
 //
 //	defer hub.Close()
 func (h *Hub) Close() error {
+	return h.shutdown(func(client *Conn) { _ = client.Close() })
+}
+
+// Shutdown stops the Hub like Close, but closes client connections with
+// CloseGoingAway (1001) and reason, telling clients the server is going
+// away (e.g. to reconnect elsewhere) rather than ending the session.
+//
+// Safe to call multiple times, and after Close (no-op after first call).
+//
+// Example:
+//
+//	<-ctx.Done() // SIGTERM
+//	_ = hub.Shutdown("server restarting")
+func (h *Hub) Shutdown(reason string) error {
+	return h.shutdown(func(client *Conn) { _ = client.CloseGoingAway(reason) })
+}
+
+// shutdown implements Close and Shutdown, closing each client with
+// closeClient.
+func (h *Hub) shutdown(closeClient func(*Conn)) error {
 	// Set closed flag first (prevents new Register/Unregister/Broadcast)
 	h.mu.Lock()
 	if h.closed {
@@ -264,15 +898,26 @@ func (h *Hub) Close() error {
 	// Signal shutdown to event loop
 	close(h.done)
 
-	// Wait for event loop to exit
+	// Stop ping loops waiting for pongs, then wait for them and the
+	// event loop to exit
+	h.mu.Lock()
+	for client := range h.pingers {
+		h.stopPinger(client)
+	}
+	h.mu.Unlock()
 	h.wg.Wait()
 
 	// Close all client connections
 	h.mu.Lock()
 	for client := range h.clients {
-		_ = client.Close()
+		closeClient(client)
+		h.metrics.ClientDisconnected(metrics.TransportWebSocket)
 	}
 	h.clients = make(map[*Conn]bool) // Clear map
+	h.origins = make(map[string]int)
+	for _, shard := range h.shards {
+		shard.clients = make(map[*Conn]bool)
+	}
 	h.mu.Unlock()
 
 	// Close channels (safe now that event loop exited and no new sends)
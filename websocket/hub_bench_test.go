@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"runtime"
 	"testing"
@@ -63,6 +64,38 @@ func BenchmarkHub_Broadcast_100Clients(b *testing.B) {
 	}
 }
 
+// BenchmarkHub_Broadcast_10kClients compares unsharded and sharded
+// broadcasts to 10,000 clients, measured until every client is written.
+func BenchmarkHub_Broadcast_10kClients(b *testing.B) {
+	for _, shards := range []int{0, runtime.NumCPU()} {
+		name := "single"
+		if shards > 0 {
+			name = fmt.Sprintf("%dShards", shards)
+		}
+		b.Run(name, func(b *testing.B) {
+			hub := NewHubWithOptions(&HubOptions{Shards: shards})
+			go hub.Run()
+			defer hub.Close()
+
+			const numClients = 10000
+			for i := 0; i < numClients; i++ {
+				hub.Register(mockConnForHub(b))
+			}
+
+			message := []byte("Benchmark message")
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if result := hub.BroadcastWithResult(message); result.Delivered != numClients {
+					b.Fatalf("BroadcastWithResult() = %+v, want %d delivered", result, numClients)
+				}
+			}
+		})
+	}
+}
+
 // BenchmarkHub_Register benchmarks client registration.
 func BenchmarkHub_Register(b *testing.B) {
 	hub := NewHub()
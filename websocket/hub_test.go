@@ -4,9 +4,16 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json/v2"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/coregx/stream/metrics"
 )
 
 // TestHub_RegisterUnregister tests client registration and unregistration.
@@ -308,6 +315,101 @@ func TestHub_Close(t *testing.T) {
 	}
 }
 
+// TestHub_Shutdown tests that Shutdown closes clients with CloseGoingAway.
+func TestHub_Shutdown(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	server := newTestServer(t, func(conn *Conn) {
+		if err := hub.Register(conn); err != nil {
+			return
+		}
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	client := dialTestServer(t, server)
+	defer client.Close()
+	waitForClients(t, hub, 1, time.Second)
+
+	if err := hub.Shutdown("restarting"); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	_, _, err := client.Read()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("Read() error = %v, want *CloseError", err)
+	}
+	if closeErr.Code != CloseGoingAway || closeErr.Reason != "restarting" {
+		t.Errorf("close = %d %q, want %d %q", closeErr.Code, closeErr.Reason, CloseGoingAway, "restarting")
+	}
+
+	// Close after Shutdown is a no-op
+	if err := hub.Close(); err != nil {
+		t.Errorf("Close() after Shutdown error = %v", err)
+	}
+}
+
+// TestHub_LazyStart tests that a hub used before Run starts its event loop
+// instead of blocking, and that a later Run doesn't start a second loop.
+func TestHub_LazyStart(t *testing.T) {
+	hub := NewHub()
+
+	client := newMockHubClient(t)
+	defer client.Stop()
+
+	done := make(chan error, 1)
+	go func() {
+		// Fills the broadcast buffer, then needs the event loop
+		for i := 0; i < 300; i++ {
+			hub.Broadcast([]byte("early"))
+		}
+		done <- hub.Register(client.conn)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Broadcast/Register blocked on a hub without Run")
+	}
+	if count := hub.ClientCount(); count != 1 {
+		t.Errorf("ClientCount() = %d, want 1", count)
+	}
+
+	// Run doesn't start another loop; it returns once the hub is closed
+	runDone := make(chan struct{})
+	go func() {
+		hub.Run()
+		close(runDone)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-runDone:
+		t.Fatal("Run() returned before Close")
+	default:
+	}
+
+	if err := hub.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	select {
+	case <-runDone:
+	case <-time.After(time.Second):
+		t.Error("Run() didn't return after Close")
+	}
+
+	// Run after Close returns immediately
+	hub.Run()
+}
+
 // TestHub_BroadcastAfterClose tests that broadcasting after close is safe.
 func TestHub_BroadcastAfterClose(t *testing.T) {
 	hub := NewHub()
@@ -345,6 +447,444 @@ func TestHub_BroadcastAfterClose(t *testing.T) {
 	// Should not panic - operations are safely ignored
 }
 
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+// captureLogger records logged messages as "LEVEL msg".
+type captureLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *captureLogger) log(level, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, level+" "+msg)
+}
+
+func (l *captureLogger) Debug(msg string, _ ...any) { l.log("DEBUG", msg) }
+func (l *captureLogger) Info(msg string, _ ...any)  { l.log("INFO", msg) }
+func (l *captureLogger) Warn(msg string, _ ...any)  { l.log("WARN", msg) }
+func (l *captureLogger) Error(msg string, _ ...any) { l.log("ERROR", msg) }
+
+// Messages returns a copy of the logged messages.
+func (l *captureLogger) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.msgs)
+}
+
+// TestHub_Logger tests that the hub logs a client dropped after a failed
+// write.
+func TestHub_Logger(t *testing.T) {
+	logger := &captureLogger{}
+	hub := NewHubWithOptions(&HubOptions{Logger: logger})
+	go hub.Run()
+	defer hub.Close()
+
+	bad := newConn(nil, nil, bufio.NewWriter(failingWriter{}), true)
+	if err := hub.Register(bad); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if result := hub.BroadcastWithResult([]byte("hello")); result.Failed != 1 {
+		t.Fatalf("BroadcastWithResult() = %+v, want 1 failed", result)
+	}
+
+	want := "WARN websocket: hub dropped client after failed write"
+	if got := logger.Messages(); !slices.Contains(got, want) {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
+
+// TestHub_BroadcastWithResult tests that delivery failures are counted.
+func TestHub_BroadcastWithResult(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Close()
+
+	good1 := newMockHubClient(t)
+	good2 := newMockHubClient(t)
+	bad := newConn(nil, nil, bufio.NewWriter(failingWriter{}), true)
+
+	hub.Register(good1.conn)
+	hub.Register(good2.conn)
+	hub.Register(bad)
+	time.Sleep(20 * time.Millisecond)
+
+	result := hub.BroadcastWithResult([]byte("hello"))
+	want := BroadcastResult{Delivered: 2, Failed: 1}
+	if result != want {
+		t.Errorf("BroadcastWithResult() = %+v, want %+v", result, want)
+	}
+
+	// The failed client is unregistered
+	time.Sleep(20 * time.Millisecond)
+	if count := hub.ClientCount(); count != 2 {
+		t.Errorf("ClientCount() = %d, want 2", count)
+	}
+
+	hub.Close()
+	if result := hub.BroadcastWithResult([]byte("late")); result != (BroadcastResult{}) {
+		t.Errorf("BroadcastWithResult() after Close = %+v, want zero", result)
+	}
+}
+
+// TestHub_BroadcastMessage tests that broadcasts keep the message type.
+func TestHub_BroadcastMessage(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Close()
+
+	var buf lockedBuffer
+	conn := newConn(nil, nil, bufio.NewWriter(&buf), true)
+	hub.Register(conn)
+
+	// Each broadcast is written by its own goroutine, so wait between them
+	// to keep the frames in order
+	if err := hub.BroadcastMessage(BinaryMessage, []byte{0x00, 0xFF}); err != nil {
+		t.Fatalf("BroadcastMessage(binary) error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := hub.BroadcastMessage(TextMessage, []byte("hello")); err != nil {
+		t.Fatalf("BroadcastMessage(text) error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Invalid messages are rejected before reaching any client
+	if err := hub.BroadcastMessage(MessageType(opcodePing), nil); !errors.Is(err, ErrInvalidMessageType) {
+		t.Errorf("BroadcastMessage(ping) error = %v, want ErrInvalidMessageType", err)
+	}
+	if err := hub.BroadcastMessage(TextMessage, []byte{0xFF}); !errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("BroadcastMessage(invalid UTF-8) error = %v, want ErrInvalidUTF8", err)
+	}
+	if count := hub.ClientCount(); count != 1 {
+		t.Errorf("ClientCount() = %d, want 1", count)
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+	want := []struct {
+		opcode  byte
+		payload string
+	}{
+		{opcodeBinary, "\x00\xff"},
+		{opcodeText, "hello"},
+	}
+	for i, w := range want {
+		f, err := readFrame(reader)
+		if err != nil {
+			t.Fatalf("frame %d: readFrame() error = %v", i, err)
+		}
+		if f.opcode != w.opcode || string(f.payload) != w.payload {
+			t.Errorf("frame %d = opcode 0x%X %q, want opcode 0x%X %q", i, f.opcode, f.payload, w.opcode, w.payload)
+		}
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Bytes returns a copy of the buffered data.
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// countedJSON marshals to a fixed object, counting MarshalJSON calls.
+type countedJSON struct {
+	calls *atomic.Int32
+}
+
+func (c countedJSON) MarshalJSON() ([]byte, error) {
+	c.calls.Add(1)
+	return []byte(`{"n":1}`), nil
+}
+
+// TestHub_BroadcastJSON_MarshalOnce tests that BroadcastJSON marshals once
+// and writes the same text frame to every client.
+func TestHub_BroadcastJSON_MarshalOnce(t *testing.T) {
+	const numClients = 100
+
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Close()
+
+	bufs := make([]*lockedBuffer, numClients)
+	for i := range bufs {
+		bufs[i] = &lockedBuffer{}
+		conn := &Conn{writer: bufio.NewWriter(bufs[i]), isServer: true}
+		if err := hub.Register(conn); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	waitForClients(t, hub, numClients, time.Second)
+
+	var calls atomic.Int32
+	if err := hub.BroadcastJSON(countedJSON{calls: &calls}); err != nil {
+		t.Fatalf("BroadcastJSON() error = %v", err)
+	}
+
+	// Wait for every client's write
+	deadline := time.Now().Add(time.Second)
+	for _, buf := range bufs {
+		for len(buf.Bytes()) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("broadcast didn't reach every client")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("MarshalJSON called %d times, want 1", n)
+	}
+
+	want := bufs[0].Bytes()
+	for i, buf := range bufs[1:] {
+		if got := buf.Bytes(); !bytes.Equal(got, want) {
+			t.Fatalf("client %d received %q, want %q", i+1, got, want)
+		}
+	}
+	f, err := readFrame(bufio.NewReader(bytes.NewReader(want)))
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if f.opcode != opcodeText || string(f.payload) != `{"n":1}` {
+		t.Errorf("frame = opcode %#x payload %q, want text %q", f.opcode, f.payload, `{"n":1}`)
+	}
+}
+
+// TestHub_BroadcastFunc tests broadcasting to the clients selected by a
+// metadata predicate.
+func TestHub_BroadcastFunc(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Close()
+
+	admin, user, guest := newMockHubClient(t), newMockHubClient(t), newMockHubClient(t)
+	admin.conn.SetMetadata("role", "admin")
+	user.conn.SetMetadata("role", "user")
+	for _, client := range []*mockHubClient{admin, user, guest} {
+		hub.Register(client.conn)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	isAdmin := func(c *Conn) bool { return c.Metadata("role") == "admin" }
+	hasRole := func(c *Conn) bool { return c.Metadata("role") != nil }
+
+	// The mock client decodes one frame per tick, so pace the broadcasts
+	hub.BroadcastFunc([]byte("admins"), isAdmin)
+	time.Sleep(20 * time.Millisecond)
+	hub.BroadcastTextFunc("staff", hasRole)
+	time.Sleep(20 * time.Millisecond)
+	if err := hub.BroadcastJSONFunc(map[string]int{"n": 1}, isAdmin); err != nil {
+		t.Fatalf("BroadcastJSONFunc() error = %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	tests := []struct {
+		name   string
+		client *mockHubClient
+		want   []string
+	}{
+		{"admin", admin, []string{"admins", "staff", `{"n":1}`}},
+		{"user", user, []string{"staff"}},
+		{"guest", guest, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, msg := range tt.client.Messages() {
+			got = append(got, string(msg))
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s received %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Removing the tag excludes the client from later broadcasts
+	user.conn.SetMetadata("role", nil)
+	hub.BroadcastTextFunc("staff again", hasRole)
+	time.Sleep(50 * time.Millisecond)
+	if n := len(user.Messages()); n != 1 {
+		t.Errorf("untagged user has %d messages, want 1", n)
+	}
+}
+
+// TestHub_Shards tests that a sharded hub delivers every broadcast to
+// every client, in order, and drops failed clients.
+func TestHub_Shards(t *testing.T) {
+	hub := NewHubWithOptions(&HubOptions{Shards: 3})
+	go hub.Run()
+	defer hub.Close()
+
+	bufs := make([]*lockedBuffer, 7)
+	conns := make([]*Conn, len(bufs))
+	for i := range bufs {
+		bufs[i] = new(lockedBuffer)
+		conns[i] = newConn(nil, nil, bufio.NewWriter(bufs[i]), true)
+		if err := hub.Register(conns[i]); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+	bad := newConn(nil, nil, bufio.NewWriter(failingWriter{}), true)
+	if err := hub.Register(bad); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// No pauses: each shard keeps its clients' broadcasts in order
+	const numMessages = 50
+	for i := 0; i < numMessages-1; i++ {
+		hub.BroadcastText(strconv.Itoa(i))
+	}
+	result := hub.BroadcastWithResult([]byte(strconv.Itoa(numMessages - 1)))
+	if result.Delivered != len(bufs) {
+		t.Errorf("BroadcastWithResult() = %+v, want %d delivered", result, len(bufs))
+	}
+
+	for i, buf := range bufs {
+		r := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+		for want := 0; want < numMessages; want++ {
+			f, err := readFrame(r)
+			if err != nil {
+				t.Fatalf("client %d: readFrame() error = %v after %d messages", i, err, want)
+			}
+			if got := string(f.payload); got != strconv.Itoa(want) {
+				t.Fatalf("client %d: message %d = %q, want %q", i, want, got, strconv.Itoa(want))
+			}
+		}
+	}
+
+	// The failing client was unregistered; so is one removed by the caller
+	hub.Unregister(conns[0])
+	waitForClients(t, hub, len(bufs)-1, time.Second)
+	if result := hub.BroadcastWithResult([]byte("after")); result != (BroadcastResult{Delivered: len(bufs) - 1}) {
+		t.Errorf("BroadcastWithResult() = %+v, want %d delivered", result, len(bufs)-1)
+	}
+}
+
+// TestHub_MaxClients tests that registrations past the limit are rejected
+// while existing clients stay connected.
+func TestHub_MaxClients(t *testing.T) {
+	hub := NewHubWithOptions(&HubOptions{MaxClients: 2})
+	go hub.Run()
+	defer hub.Close()
+
+	a, b, c := newMockHubClient(t), newMockHubClient(t), newMockHubClient(t)
+	for _, client := range []*mockHubClient{a, b} {
+		if err := hub.Register(client.conn); err != nil {
+			t.Fatalf("Register() error = %v, want nil", err)
+		}
+	}
+
+	if err := hub.Register(c.conn); !errors.Is(err, ErrTooManyClients) {
+		t.Errorf("Register() past limit error = %v, want ErrTooManyClients", err)
+	}
+	if err := hub.Register(a.conn); err != nil {
+		t.Errorf("re-Register() error = %v, want nil", err)
+	}
+	if count := hub.ClientCount(); count != 2 {
+		t.Errorf("ClientCount() = %d, want 2", count)
+	}
+
+	// Existing clients still receive broadcasts
+	if result := hub.BroadcastWithResult([]byte("hi")); result.Delivered != 2 {
+		t.Errorf("BroadcastWithResult() = %+v, want 2 delivered", result)
+	}
+
+	// Unregistering frees a slot
+	hub.Unregister(a.conn)
+	time.Sleep(10 * time.Millisecond)
+	if err := hub.Register(c.conn); err != nil {
+		t.Errorf("Register() after Unregister error = %v, want nil", err)
+	}
+}
+
+// TestHub_MaxPerOrigin tests that clients from one IP are capped while
+// other IPs can still register.
+func TestHub_MaxPerOrigin(t *testing.T) {
+	hub := NewHubWithOptions(&HubOptions{MaxPerOrigin: 2})
+	go hub.Run()
+	defer hub.Close()
+
+	newClient := func(ip string) *mockHubClient {
+		client := newMockHubClient(t)
+		client.conn.clientIP = ip
+		return client
+	}
+
+	a, b, c := newClient("203.0.113.7"), newClient("203.0.113.7"), newClient("203.0.113.7")
+	for _, client := range []*mockHubClient{a, b} {
+		if err := hub.Register(client.conn); err != nil {
+			t.Fatalf("Register() error = %v, want nil", err)
+		}
+	}
+	if err := hub.Register(c.conn); !errors.Is(err, ErrTooManyClients) {
+		t.Errorf("Register() past per-origin limit error = %v, want ErrTooManyClients", err)
+	}
+
+	// Another IP isn't affected
+	if err := hub.Register(newClient("198.51.100.1").conn); err != nil {
+		t.Errorf("Register() from another IP error = %v, want nil", err)
+	}
+
+	// Unregistering frees a slot for that IP
+	hub.Unregister(a.conn)
+	time.Sleep(10 * time.Millisecond)
+	if err := hub.Register(c.conn); err != nil {
+		t.Errorf("Register() after Unregister error = %v, want nil", err)
+	}
+	if count := hub.ClientCount(); count != 3 {
+		t.Errorf("ClientCount() = %d, want 3", count)
+	}
+}
+
+// TestHub_Metrics tests that the hub reports to its metrics sink.
+func TestHub_Metrics(t *testing.T) {
+	collector := metrics.NewCollector()
+	hub := NewHubWithOptions(&HubOptions{Metrics: collector})
+	go hub.Run()
+	defer hub.Close()
+
+	client1 := newMockHubClient(t)
+	client2 := newMockHubClient(t)
+	hub.Register(client1.conn)
+	hub.Register(client2.conn)
+	hub.Register(client2.conn) // Duplicate registration counts once
+	time.Sleep(20 * time.Millisecond)
+
+	hub.BroadcastText("hello")
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Unregister(client1.conn)
+	time.Sleep(20 * time.Millisecond)
+
+	got := collector.Snapshot(metrics.TransportWebSocket)
+	want := metrics.Snapshot{
+		Connected:    2,
+		Disconnected: 1,
+		Active:       1,
+		Broadcasts:   1,
+		BytesWritten: 2 * uint64(len("hello")),
+	}
+	if got != want {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
+
 // mockHubClient is a test helper that captures messages sent to it.
 type mockHubClient struct {
 	conn             *Conn
@@ -445,3 +985,67 @@ func (c *mockHubClient) Messages() [][]byte {
 	copy(result, c.receivedMessages)
 	return result
 }
+
+// TestHub_PingEvictsUnresponsive tests that a client that stops answering
+// pings is unregistered within the pong timeout, while a responsive client
+// stays registered.
+func TestHub_PingEvictsUnresponsive(t *testing.T) {
+	hub := NewHubWithOptions(&HubOptions{
+		PingInterval: 20 * time.Millisecond,
+		PongTimeout:  50 * time.Millisecond,
+	})
+	go hub.Run()
+	defer hub.Close()
+
+	server := newTestServer(t, func(conn *Conn) {
+		if err := hub.Register(conn); err != nil {
+			return
+		}
+		defer hub.Unregister(conn)
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	// Responsive peer: its read loop answers pings
+	responsive := dialTestServer(t, server)
+	defer responsive.Close()
+	go func() {
+		for {
+			if _, _, err := responsive.Read(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Unresponsive peer: never reads, so never answers pings
+	unresponsive := dialTestServer(t, server)
+	defer unresponsive.Close()
+
+	waitForClients(t, hub, 2, time.Second)
+
+	// Evicted within PingInterval + PongTimeout (plus scheduling slack)
+	waitForClients(t, hub, 1, 500*time.Millisecond)
+
+	// The responsive client survives several more ping rounds
+	time.Sleep(150 * time.Millisecond)
+	if count := hub.ClientCount(); count != 1 {
+		t.Errorf("ClientCount() = %d, want 1", count)
+	}
+}
+
+// waitForClients waits until hub has want clients or timeout elapses.
+func waitForClients(t *testing.T, hub *Hub, want int, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for hub.ClientCount() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("ClientCount() = %d after %v, want %d", hub.ClientCount(), timeout, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,81 @@
+package websocket
+
+import "time"
+
+// SetIdleTimeout closes the connection with 1001 (Going Away) once no
+// message has been read or written for timeout, e.g. to reap idle chat
+// clients.
+//
+// Every data message returned by Read (or fully read from NextReader) and
+// every successful Write resets the window. Control frames don't, so
+// keep-alive pings don't hold an otherwise idle connection open. Closing
+// the connection unblocks a pending Read, which returns ErrClosed or a
+// network error.
+//
+// A timeout of 0 or less disables it (default). The window restarts when
+// the timeout is set.
+//
+// Example:
+//
+//	conn.SetIdleTimeout(5 * time.Minute)
+//
+// Thread-Safety: Safe to call concurrently with Read and Write.
+func (c *Conn) SetIdleTimeout(timeout time.Duration) {
+	timeout = max(timeout, 0)
+	c.idleTimeout.Store(int64(timeout))
+	c.touch()
+
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+	if timeout > 0 {
+		c.idleTimer = time.AfterFunc(timeout, c.checkIdle)
+	}
+}
+
+// touch records message activity for the idle timeout.
+func (c *Conn) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// checkIdle runs when the idle timer fires. It closes the connection if
+// it has been idle for the whole timeout, or waits out the rest of the
+// window since the last activity.
+func (c *Conn) checkIdle() {
+	c.closeMu.RLock()
+	closed := c.closed
+	c.closeMu.RUnlock()
+
+	timeout := time.Duration(c.idleTimeout.Load())
+	if closed || timeout <= 0 {
+		return
+	}
+
+	idle := time.Since(time.Unix(0, c.lastActivity.Load()))
+	if idle < timeout {
+		c.idleMu.Lock()
+		if c.idleTimer != nil {
+			c.idleTimer.Reset(timeout - idle)
+		}
+		c.idleMu.Unlock()
+		return
+	}
+
+	c.log().Info("websocket: closing idle connection", "idle", idle)
+	_ = c.CloseWithCode(CloseGoingAway, "idle timeout")
+}
+
+// stopIdleTimer stops the idle timer, if any, once the connection closes.
+func (c *Conn) stopIdleTimer() {
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+}
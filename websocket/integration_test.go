@@ -426,12 +426,13 @@ func TestIntegration_HubEchoServer(t *testing.T) {
 		go func() {
 			defer hub.Unregister(conn)
 			for {
-				_, data, err := conn.Read()
+				msgType, data, err := conn.Read()
 				if err != nil {
 					break
 				}
-				// Broadcast to all clients (including sender)
-				hub.Broadcast(data)
+				// Broadcast to all clients (including sender), keeping
+				// the message type
+				_ = hub.BroadcastMessage(msgType, data)
 			}
 		}()
 	}))
@@ -459,15 +460,18 @@ func TestIntegration_HubEchoServer(t *testing.T) {
 		t.Fatalf("Client 0 send failed: %v", err)
 	}
 
-	// All clients should receive the broadcast
+	// All clients should receive the broadcast as a text frame
 	for i := 0; i < numClients; i++ {
-		received, err := clients[i].receiveFrame()
+		frame, err := websocket.ReadFrameForTest(clients[i].reader)
 		if err != nil {
 			t.Fatalf("Client %d receive failed: %v", i, err)
 		}
 
-		if !bytes.Equal(received, testMessage) {
-			t.Errorf("Client %d received %q, want %q", i, received, testMessage)
+		if frame.Opcode != websocket.OpcodeTextForTest {
+			t.Errorf("Client %d received opcode 0x%X, want text", i, frame.Opcode)
+		}
+		if !bytes.Equal(frame.Payload, testMessage) {
+			t.Errorf("Client %d received %q, want %q", i, frame.Payload, testMessage)
 		}
 	}
 
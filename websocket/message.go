@@ -1,6 +1,10 @@
 package websocket
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
 
 // MessageType represents WebSocket message type.
 //
@@ -19,18 +23,30 @@ const (
 	BinaryMessage MessageType = 2
 )
 
-// String returns string representation of message type.
+// String returns string representation of message type
+// ("text", "binary" or "unknown").
 func (mt MessageType) String() string {
 	switch mt {
 	case TextMessage:
-		return "Text"
+		return "text"
 	case BinaryMessage:
-		return "Binary"
+		return "binary"
 	default:
-		return "Unknown"
+		return "unknown"
 	}
 }
 
+// IsValid reports whether mt is TextMessage or BinaryMessage.
+func (mt MessageType) IsValid() bool {
+	return mt == TextMessage || mt == BinaryMessage
+}
+
+// Message is a single data message, as written by Conn.WriteMessages.
+type Message struct {
+	Type MessageType // TextMessage or BinaryMessage
+	Data []byte      // Payload (valid UTF-8 for TextMessage)
+}
+
 // CloseCode represents WebSocket close status codes (RFC 6455 Section 7.4).
 //
 // Close frames MAY contain a status code indicating the reason for closure.
@@ -94,7 +110,9 @@ const (
 	// Server is temporarily unable to process request (e.g. overloaded).
 	CloseTryAgainLater CloseCode = 1013
 
-	// 1014 is reserved and MUST NOT be used.
+	// CloseBadGateway indicates bad gateway (1014).
+	// Gateway or proxy received an invalid response from the upstream server.
+	CloseBadGateway CloseCode = 1014
 
 	// CloseTLSHandshake indicates TLS handshake failure (1015).
 	// This is a reserved value and MUST NOT be set in close frame.
@@ -102,9 +120,22 @@ const (
 	CloseTLSHandshake CloseCode = 1015
 )
 
+// isValidWire reports whether the code may be sent in a close frame (RFC
+// 6455 Section 7.4): 1000-1003 and 1007-1014 are registered with IANA,
+// 3000-4999 are for libraries and applications. 1004-1006 and 1015 are
+// reserved, the rest unassigned.
+func (cc CloseCode) isValidWire() bool {
+	switch {
+	case cc >= 1000 && cc <= 1003, cc >= 1007 && cc <= 1014:
+		return true
+	default:
+		return cc >= 3000 && cc <= 4999
+	}
+}
+
 // String returns string representation of close code.
 //
-//nolint:cyclop // 15 close codes per RFC 6455
+//nolint:cyclop // 15 close codes per RFC 6455 and the IANA registry
 func (cc CloseCode) String() string {
 	switch cc {
 	case CloseNormalClosure:
@@ -133,6 +164,8 @@ func (cc CloseCode) String() string {
 		return "Service Restart"
 	case CloseTryAgainLater:
 		return "Try Again Later"
+	case CloseBadGateway:
+		return "Bad Gateway"
 	case CloseTLSHandshake:
 		return "TLS Handshake"
 	default:
@@ -140,16 +173,86 @@ func (cc CloseCode) String() string {
 	}
 }
 
-// IsCloseError checks if error represents a WebSocket close frame.
+// CloseError is returned by Read when the connection is closed by the peer.
+//
+// Code and Reason come from the received close frame (RFC 6455 Section
+// 5.5.1). A close frame without a status code reports CloseNoStatusReceived
+// (1005). A connection dropped without a close frame reports
+// CloseAbnormalClosure (1006) and wraps the underlying network error.
+//
+// A CloseError from a close frame matches ErrClosed (errors.Is), so
+// existing checks keep working.
+//
+// Example:
+//
+//	_, _, err := conn.Read()
+//	var ce *websocket.CloseError
+//	if errors.As(err, &ce) {
+//	    log.Printf("closed: %d %s", ce.Code, ce.Reason)
+//	}
+type CloseError struct {
+	// Code is the close status code.
+	Code CloseCode
+
+	// Reason is the optional UTF-8 reason sent by the peer.
+	Reason string
+
+	// Err is the network error behind CloseAbnormalClosure, or
+	// ErrInvalidUTF8 if the peer's close reason wasn't valid UTF-8 (the
+	// reason is then dropped), if any.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *CloseError) Error() string {
+	msg := fmt.Sprintf("websocket: close %d (%s)", int(e.Code), e.Code)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is reports whether target is ErrClosed and a close frame was received.
+func (e *CloseError) Is(target error) bool {
+	return target == ErrClosed && e.Code != CloseAbnormalClosure
+}
+
+// Unwrap returns the underlying network error, if any.
+func (e *CloseError) Unwrap() error {
+	return e.Err
+}
+
+// IsCloseError checks if error means the WebSocket connection was closed.
 //
-// Returns true if the error is a clean close (close frame received).
-// Returns false for other errors (network errors, protocol errors, etc.).
+// Returns true whether the peer closed cleanly (close frame received) or
+// abruptly (the connection dropped without one, reported by Read as a
+// *CloseError with CloseAbnormalClosure), and for ErrClosed. Returns false
+// for other errors (protocol errors, timeouts, etc.). Use
+// IsCloseErrorCode to tell the cases apart.
 func IsCloseError(err error) bool {
 	if err == nil {
 		return false
 	}
-	// Check if error is ErrClosed (close frame sent/received)
-	return errors.Is(err, ErrClosed)
+	var ce *CloseError
+	return errors.Is(err, ErrClosed) || errors.As(err, &ce)
+}
+
+// IsCloseErrorCode reports whether err is a *CloseError with one of codes.
+//
+// Example:
+//
+//	if websocket.IsCloseErrorCode(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+//	    return // Client left
+//	}
+func IsCloseErrorCode(err error, codes ...CloseCode) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	return slices.Contains(codes, ce.Code)
 }
 
 // IsTemporaryError checks if error is temporary and operation can be retried.
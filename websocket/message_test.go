@@ -0,0 +1,62 @@
+package websocket
+
+import "testing"
+
+// TestMessageType_String tests message type names for known and unknown values.
+func TestMessageType_String(t *testing.T) {
+	tests := []struct {
+		mt        MessageType
+		want      string
+		wantValid bool
+	}{
+		{TextMessage, "text", true},
+		{BinaryMessage, "binary", true},
+		{MessageType(0), "unknown", false},
+		{MessageType(8), "unknown", false}, // Close opcode, not a message type
+		{MessageType(-1), "unknown", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mt.String(); got != tt.want {
+			t.Errorf("MessageType(%d).String(): expected %q, got %q", int(tt.mt), tt.want, got)
+		}
+		if got := tt.mt.IsValid(); got != tt.wantValid {
+			t.Errorf("MessageType(%d).IsValid(): expected %v, got %v", int(tt.mt), tt.wantValid, got)
+		}
+	}
+}
+
+// TestCloseCode_Values tests the close code constants against the RFC 6455
+// and IANA registry values.
+func TestCloseCode_Values(t *testing.T) {
+	tests := []struct {
+		code CloseCode
+		want int
+		name string
+	}{
+		{CloseNormalClosure, 1000, "Normal Closure"},
+		{CloseGoingAway, 1001, "Going Away"},
+		{CloseProtocolError, 1002, "Protocol Error"},
+		{CloseUnsupportedData, 1003, "Unsupported Data"},
+		{CloseNoStatusReceived, 1005, "No Status Received"},
+		{CloseAbnormalClosure, 1006, "Abnormal Closure"},
+		{CloseInvalidFramePayloadData, 1007, "Invalid Frame Payload Data"},
+		{ClosePolicyViolation, 1008, "Policy Violation"},
+		{CloseMessageTooBig, 1009, "Message Too Big"},
+		{CloseMandatoryExtension, 1010, "Mandatory Extension"},
+		{CloseInternalServerErr, 1011, "Internal Server Error"},
+		{CloseServiceRestart, 1012, "Service Restart"},
+		{CloseTryAgainLater, 1013, "Try Again Later"},
+		{CloseBadGateway, 1014, "Bad Gateway"},
+		{CloseTLSHandshake, 1015, "TLS Handshake"},
+	}
+
+	for _, tt := range tests {
+		if int(tt.code) != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, int(tt.code), tt.want)
+		}
+		if got := tt.code.String(); got != tt.name {
+			t.Errorf("CloseCode(%d).String() = %q, want %q", tt.want, got, tt.name)
+		}
+	}
+}
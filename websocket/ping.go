@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+)
+
+// PingWithTimeout sends a ping and waits for the matching pong, returning
+// the round-trip time.
+//
+// Each call sends a unique payload, so concurrent calls and unsolicited
+// pongs don't get mixed up. Pongs are received by the read loop: Read (or
+// ReadContext, Serve) must be running in another goroutine, or the pong is
+// never seen and PingWithTimeout waits until ctx is done.
+//
+// Returns ctx.Err() (e.g. context.DeadlineExceeded) if no pong arrives
+// before ctx is done, or the error from sending the ping.
+//
+// Example:
+//
+//	go conn.Serve(handler)
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	rtt, err := conn.PingWithTimeout(ctx)
+//	if err != nil {
+//	    return err // Peer unresponsive
+//	}
+//	log.Printf("RTT: %v", rtt)
+//
+// Thread-safe: can be called concurrently with Read and Write.
+func (c *Conn) PingWithTimeout(ctx context.Context) (time.Duration, error) {
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint64(payload, c.pingSeq.Add(1))
+	key := string(payload)
+
+	pong := make(chan struct{})
+	c.pingMu.Lock()
+	if c.pings == nil {
+		c.pings = make(map[string]chan struct{})
+	}
+	c.pings[key] = pong
+	c.pingMu.Unlock()
+
+	defer func() {
+		c.pingMu.Lock()
+		delete(c.pings, key)
+		c.pingMu.Unlock()
+	}()
+
+	start := time.Now()
+	if err := c.Ping(payload); err != nil {
+		return 0, err
+	}
+
+	select {
+	case <-pong:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// pongReceived wakes the PingWithTimeout call waiting for payload, if any.
+func (c *Conn) pongReceived(payload []byte) {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+
+	if pong, ok := c.pings[string(payload)]; ok {
+		close(pong)
+		delete(c.pings, string(payload))
+	}
+}
@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestConn_PingWithTimeout tests RTT measurement against a peer that
+// answers pings from its read loop.
+func TestConn_PingWithTimeout(t *testing.T) {
+	server := newTestServer(t, func(conn *Conn) {
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	conn := dialTestServer(t, server)
+	defer conn.Close()
+
+	go func() {
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		rtt, err := conn.PingWithTimeout(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("PingWithTimeout() error = %v", err)
+		}
+		if rtt <= 0 {
+			t.Errorf("PingWithTimeout() rtt = %v, want > 0", rtt)
+		}
+	}
+
+	if n := pendingPingsForTest(conn); n != 0 {
+		t.Errorf("pending pings = %d, want 0", n)
+	}
+}
+
+// TestConn_PingWithTimeout_Timeout tests that an unanswered ping returns
+// the context error.
+func TestConn_PingWithTimeout_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	server := newTestServer(t, func(*Conn) {
+		<-done // Never read, so never pong
+	})
+	defer server.Close()
+	defer close(done)
+
+	conn := dialTestServer(t, server)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := conn.PingWithTimeout(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PingWithTimeout() error = %v, want context.DeadlineExceeded", err)
+	}
+	if n := pendingPingsForTest(conn); n != 0 {
+		t.Errorf("pending pings = %d, want 0", n)
+	}
+}
+
+// TestConn_PingWithTimeout_Closed tests that pinging a closed connection fails.
+func TestConn_PingWithTimeout_Closed(t *testing.T) {
+	conn, _ := mockConnWriter(t)
+	_ = conn.Close()
+
+	if _, err := conn.PingWithTimeout(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("PingWithTimeout() error = %v, want ErrClosed", err)
+	}
+}
+
+// pendingPingsForTest returns the number of PingWithTimeout calls still waiting.
+func pendingPingsForTest(c *Conn) int {
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	return len(c.pings)
+}
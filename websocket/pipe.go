@@ -0,0 +1,37 @@
+package websocket
+
+import (
+	"bufio"
+	"net"
+)
+
+// Pipe returns a connected pair of WebSocket connections backed by an
+// in-memory net.Pipe, with no handshake and no TCP.
+//
+// The client end masks its frames and the server end doesn't, as over a
+// real connection, so each end enforces the masking rules on the other.
+// Pipe is meant for fast unit tests of code that speaks WebSocket.
+//
+// Like net.Pipe, the pipe is synchronous and unbuffered: a write blocks
+// until the other end reads it, so read and write from different
+// goroutines. Closing either end closes the pipe.
+//
+// Example:
+//
+//	client, server := websocket.Pipe()
+//	defer client.Close()
+//	defer server.Close()
+//
+//	go client.WriteText("hello")
+//	text, err := server.ReadText()
+func Pipe() (client, server *Conn) {
+	clientSide, serverSide := net.Pipe()
+
+	client = newConn(clientSide,
+		bufio.NewReaderSize(clientSide, defaultReadBufferSize),
+		bufio.NewWriterSize(clientSide, defaultWriteBufferSize), false)
+	server = newConn(serverSide,
+		bufio.NewReaderSize(serverSide, defaultReadBufferSize),
+		bufio.NewWriterSize(serverSide, defaultWriteBufferSize), true)
+	return client, server
+}
@@ -0,0 +1,49 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPipe tests that the two ends of a Pipe exchange messages in both
+// directions with the right masking.
+func TestPipe(t *testing.T) {
+	client, server := Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- client.WriteText("hello") }()
+
+	text, err := server.ReadText()
+	if err != nil {
+		t.Fatalf("server ReadText() error = %v", err)
+	}
+	if text != "hello" {
+		t.Errorf("server ReadText() = %q, want %q", text, "hello")
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("client WriteText() error = %v", err)
+	}
+
+	go func() { errc <- server.WriteText("world") }()
+
+	text, err = client.ReadText()
+	if err != nil {
+		t.Fatalf("client ReadText() error = %v", err)
+	}
+	if text != "world" {
+		t.Errorf("client ReadText() = %q, want %q", text, "world")
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("server WriteText() error = %v", err)
+	}
+
+	// A close from one end reaches the other
+	go func() { errc <- client.Close() }()
+
+	if _, _, err := server.Read(); !IsCloseError(err) || !errors.Is(err, ErrClosed) {
+		t.Fatalf("server Read() after client close error = %v, want *CloseError", err)
+	}
+	<-errc
+}
@@ -0,0 +1,296 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ClientPool defaults.
+const (
+	defaultPoolMaxIdle     = 2
+	defaultPoolIdleTimeout = 90 * time.Second
+	defaultPoolPingTimeout = 5 * time.Second
+)
+
+// ClientPoolOptions configures a ClientPool.
+//
+// All fields are optional. Zero values use sensible defaults.
+type ClientPoolOptions struct {
+	// Dial is used for every new connection, e.g. to send an
+	// Authorization header (default: nil, Dial defaults).
+	Dial *DialOptions
+
+	// MaxIdle is the number of idle connections kept per URL (default: 2).
+	// Connections returned beyond it are closed.
+	MaxIdle int
+
+	// IdleTimeout closes connections that stay idle longer
+	// (default: 90s).
+	IdleTimeout time.Duration
+
+	// PingTimeout bounds the health check ping before an idle connection
+	// is reused (default: 5s).
+	PingTimeout time.Duration
+}
+
+// ClientPool keeps idle client connections for reuse, so services that
+// make many short-lived WebSocket calls don't pay for a handshake each time.
+//
+// Connections are pooled per URL. Get hands out an idle connection after
+// checking that it still answers a ping, or dials a new one; Put returns it
+// to the pool when the caller is done.
+//
+// A pooled connection is reused as is: the server must treat it as a fresh
+// session and must not send messages while it's idle (a message arriving
+// during the health check fails it, and the connection is discarded).
+//
+// Thread-safe: Get, Put, and Close can be called concurrently.
+//
+// Example:
+//
+//	pool := websocket.NewClientPool(&websocket.ClientPoolOptions{
+//	    Dial: &websocket.DialOptions{
+//	        Header: http.Header{"Authorization": {"Bearer " + token}},
+//	    },
+//	})
+//	defer pool.Close()
+//
+//	conn, err := pool.Get(ctx, "wss://example.com/rpc")
+//	if err != nil {
+//	    return err
+//	}
+//	_ = conn.WriteText(request)
+//	reply, err := conn.ReadText()
+//	if err != nil {
+//	    conn.Close() // Don't return a broken connection
+//	    return err
+//	}
+//	pool.Put(conn)
+type ClientPool struct {
+	opts ClientPoolOptions
+
+	mu     sync.Mutex
+	idle   map[string][]idleConn
+	active map[*Conn]string // Connections handed out by Get, by URL
+	closed bool
+}
+
+// idleConn is a pooled connection and when it was returned.
+type idleConn struct {
+	conn  *Conn
+	since time.Time
+}
+
+// NewClientPool creates a ClientPool.
+//
+// If opts is nil, defaults are used.
+func NewClientPool(opts *ClientPoolOptions) *ClientPool {
+	p := &ClientPool{
+		idle:   make(map[string][]idleConn),
+		active: make(map[*Conn]string),
+	}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.MaxIdle <= 0 {
+		p.opts.MaxIdle = defaultPoolMaxIdle
+	}
+	if p.opts.IdleTimeout <= 0 {
+		p.opts.IdleTimeout = defaultPoolIdleTimeout
+	}
+	if p.opts.PingTimeout <= 0 {
+		p.opts.PingTimeout = defaultPoolPingTimeout
+	}
+	return p
+}
+
+// Get returns a connection to url, reusing a healthy idle one if possible.
+//
+// Idle connections are tried most recently used first; any that fail the
+// health check are closed. If none is usable, Get dials a new connection
+// with ClientPoolOptions.Dial, bounded by ctx.
+//
+// Returns ErrPoolClosed after Close, or the Dial error.
+func (p *ClientPool) Get(ctx context.Context, url string) (*Conn, error) {
+	for {
+		ic, ok, err := p.popIdle(url)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if p.healthy(ctx, ic.conn) {
+			return p.checkout(url, ic.conn)
+		}
+		_ = ic.conn.Close()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, resp, err := Dial(ctx, url, p.opts.Dial)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.checkout(url, conn)
+}
+
+// Put returns a connection obtained from Get to the pool.
+//
+// Connections that are closed, weren't obtained from this pool, or exceed
+// ClientPoolOptions.MaxIdle are closed instead. Put must not be called
+// while the connection is still in use.
+func (p *ClientPool) Put(conn *Conn) {
+	p.mu.Lock()
+	url, ok := p.active[conn]
+	delete(p.active, conn)
+	if !ok || p.closed || conn.isClosed() || conn.inFragment || len(p.idle[url]) >= p.opts.MaxIdle {
+		p.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	p.idle[url] = append(p.idle[url], idleConn{conn: conn, since: time.Now()})
+	expired := p.evictLocked(time.Now())
+	p.mu.Unlock()
+
+	closeAll(expired)
+}
+
+// Close closes all idle connections. Connections handed out by Get are left
+// to their callers; Put closes them from now on.
+//
+// It's safe to call Close multiple times.
+func (p *ClientPool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var conns []*Conn
+	for _, list := range p.idle {
+		for _, ic := range list {
+			conns = append(conns, ic.conn)
+		}
+	}
+	p.idle = make(map[string][]idleConn)
+	p.mu.Unlock()
+
+	closeAll(conns)
+	return nil
+}
+
+// Idle returns the number of idle connections in the pool.
+func (p *ClientPool) Idle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for _, list := range p.idle {
+		n += len(list)
+	}
+	return n
+}
+
+// popIdle removes the most recently returned idle connection to url, after
+// evicting expired ones.
+func (p *ClientPool) popIdle(url string) (idleConn, bool, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return idleConn{}, false, ErrPoolClosed
+	}
+	expired := p.evictLocked(time.Now())
+
+	list := p.idle[url]
+	var ic idleConn
+	ok := len(list) > 0
+	if ok {
+		ic = list[len(list)-1]
+		p.idle[url] = list[:len(list)-1]
+	}
+	p.mu.Unlock()
+
+	closeAll(expired)
+	return ic, ok, nil
+}
+
+// evictLocked removes connections idle longer than IdleTimeout and returns
+// them for closing outside the lock. Caller must hold mu.
+func (p *ClientPool) evictLocked(now time.Time) []*Conn {
+	var expired []*Conn
+	for url, list := range p.idle {
+		kept := list[:0]
+		for _, ic := range list {
+			if now.Sub(ic.since) > p.opts.IdleTimeout {
+				expired = append(expired, ic.conn)
+			} else {
+				kept = append(kept, ic)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.idle, url)
+		} else {
+			p.idle[url] = kept
+		}
+	}
+	return expired
+}
+
+// checkout records conn as handed out for url.
+func (p *ClientPool) checkout(url string, conn *Conn) (*Conn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		_ = conn.Close()
+		return nil, ErrPoolClosed
+	}
+	p.active[conn] = url
+	return conn, nil
+}
+
+// healthy pings an idle connection and reads until the pong arrives.
+//
+// Nothing else reads an idle connection, so healthy runs the read loop
+// itself and stops it once PingWithTimeout returns. Any message or error
+// read meanwhile means the connection can't be reused.
+func (p *ClientPool) healthy(ctx context.Context, conn *Conn) bool {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.PingTimeout)
+	defer cancel()
+
+	readCtx, stopRead := context.WithCancel(ctx)
+	defer stopRead()
+
+	pingErr := make(chan error, 1)
+	go func() {
+		_, err := conn.PingWithTimeout(ctx)
+		stopRead()
+		pingErr <- err
+	}()
+
+	_, _, readErr := conn.ReadContext(readCtx)
+	if !errors.Is(readErr, context.Canceled) {
+		// No pong is coming; don't wait out the timeout
+		cancel()
+	}
+	err := <-pingErr
+
+	return err == nil && errors.Is(readErr, context.Canceled)
+}
+
+// isClosed reports whether the connection has been closed.
+func (c *Conn) isClosed() bool {
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+	return c.closed
+}
+
+// closeAll closes conns, ignoring errors.
+func closeAll(conns []*Conn) {
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
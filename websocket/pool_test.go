@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newPoolTestServer starts an echo server that counts handshakes.
+func newPoolTestServer(t *testing.T, handshakes *atomic.Int32) (*httptest.Server, string) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handshakes.Add(1)
+
+		for {
+			msgType, data, err := conn.Read()
+			if err != nil {
+				return
+			}
+			if string(data) == "bye" {
+				return
+			}
+			_ = conn.Write(msgType, data)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// TestClientPool_Reuse tests that a healthy connection returned with Put is
+// handed out again by the next Get.
+func TestClientPool_Reuse(t *testing.T) {
+	var handshakes atomic.Int32
+	_, url := newPoolTestServer(t, &handshakes)
+
+	pool := NewClientPool(nil)
+	defer pool.Close()
+
+	ctx := context.Background()
+	first, err := pool.Get(ctx, url)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if err := first.WriteText("one"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if reply, err := first.ReadText(); err != nil || reply != "one" {
+		t.Fatalf("ReadText() = %q, %v, want %q", reply, err, "one")
+	}
+	pool.Put(first)
+
+	if got := pool.Idle(); got != 1 {
+		t.Fatalf("Idle() = %d after Put, want 1", got)
+	}
+
+	second, err := pool.Get(ctx, url)
+	if err != nil {
+		t.Fatalf("second Get() error = %v", err)
+	}
+	defer second.Close()
+
+	if second != first {
+		t.Error("second Get() dialed a new connection, want the pooled one")
+	}
+	if got := handshakes.Load(); got != 1 {
+		t.Errorf("server saw %d handshakes, want 1", got)
+	}
+
+	// The reused connection still works
+	if err := second.WriteText("two"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if reply, err := second.ReadText(); err != nil || reply != "two" {
+		t.Errorf("ReadText() = %q, %v, want %q", reply, err, "two")
+	}
+}
+
+// TestClientPool_DiscardsDead tests that an idle connection the server
+// closed fails the health check and is replaced.
+func TestClientPool_DiscardsDead(t *testing.T) {
+	var handshakes atomic.Int32
+	_, url := newPoolTestServer(t, &handshakes)
+
+	pool := NewClientPool(&ClientPoolOptions{PingTimeout: time.Second})
+	defer pool.Close()
+
+	ctx := context.Background()
+	first, err := pool.Get(ctx, url)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	// Server hangs up while the connection sits in the pool
+	_ = first.WriteText("bye")
+	pool.Put(first)
+
+	second, err := pool.Get(ctx, url)
+	if err != nil {
+		t.Fatalf("second Get() error = %v", err)
+	}
+	defer second.Close()
+
+	if second == first {
+		t.Error("Get() reused a dead connection")
+	}
+	if got := handshakes.Load(); got != 2 {
+		t.Errorf("server saw %d handshakes, want 2", got)
+	}
+}
+
+// TestClientPool_Limits tests MaxIdle, IdleTimeout, and Close.
+func TestClientPool_Limits(t *testing.T) {
+	var handshakes atomic.Int32
+	_, url := newPoolTestServer(t, &handshakes)
+
+	pool := NewClientPool(&ClientPoolOptions{MaxIdle: 1, IdleTimeout: 50 * time.Millisecond})
+
+	ctx := context.Background()
+	a, err := pool.Get(ctx, url)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	b, err := pool.Get(ctx, url)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	pool.Put(a)
+	pool.Put(b)
+	if got := pool.Idle(); got != 1 {
+		t.Errorf("Idle() = %d, want MaxIdle 1", got)
+	}
+	if _, _, err := b.Read(); !errors.Is(err, ErrClosed) {
+		t.Errorf("connection over MaxIdle: Read() error = %v, want ErrClosed", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	c, err := pool.Get(ctx, url)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if c == a {
+		t.Error("Get() reused a connection past IdleTimeout")
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := pool.Get(ctx, url); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Get() after Close error = %v, want ErrPoolClosed", err)
+	}
+	pool.Put(c)
+	if _, _, err := c.Read(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Put after Close: Read() error = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,262 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// NextReader returns the type of the next data message and a reader that
+// streams its payload frame by frame, without assembling the message in
+// memory.
+//
+// Control frames interleaved with the message's fragments are handled
+// transparently, as with Read: pings are answered and pongs are delivered
+// to PingWithTimeout. The reader returns io.EOF only after the final
+// fragment. If the message can't be completed (the peer sends a malformed
+// control frame, a close frame, an out-of-sequence data frame, or the
+// connection drops), the reader fails with an error wrapping
+// ErrMessageInterrupted and the underlying cause, so a truncated message
+// is never mistaken for a complete one. Text messages are validated as
+// UTF-8 as they stream; invalid data fails the reader with ErrInvalidUTF8.
+//
+// The reader is valid until the next call to NextReader, Read, or one of
+// their wrappers, which discards any unread remainder of the message.
+//
+// Example:
+//
+//	msgType, r, err := conn.NextReader()
+//	if err != nil {
+//	    return err
+//	}
+//	if _, err := io.Copy(dst, r); err != nil {
+//	    return err // Includes ErrMessageInterrupted
+//	}
+//
+// Thread-Safety: like Read, one goroutine should read at a time.
+func (c *Conn) NextReader() (MessageType, io.Reader, error) {
+	if c.isClosed() {
+		return 0, nil, ErrClosed
+	}
+	if err := c.discardMessage(); err != nil {
+		return 0, nil, err
+	}
+
+	r := &messageReader{c: c}
+
+	if c.inFragment {
+		// Resume a message left by a canceled ReadContext
+		r.msgType = MessageType(c.fragmentType)
+		r.payload = append([]byte(nil), c.fragmentBuf.Bytes()...)
+		c.inFragment = false
+		c.fragmentBuf.Reset()
+	} else {
+		f, err := c.nextDataFrame(context.Background())
+		if err != nil {
+			return 0, nil, err
+		}
+		if f.opcode == opcodeContinuation {
+			_ = c.CloseWithCode(CloseProtocolError, "unexpected continuation")
+			return 0, nil, ErrUnexpectedContinuation
+		}
+		if err := c.checkMessageSize(len(f.payload)); err != nil {
+			return 0, nil, err
+		}
+		r.msgType = MessageType(f.opcode)
+		r.payload = f.payload
+		r.fin = f.fin
+		if !f.fin {
+			c.startFragments()
+		}
+	}
+
+	r.size = len(r.payload)
+	if err := r.validate(r.payload); err != nil {
+		return 0, nil, err
+	}
+	c.activeReader = r
+	return r.msgType, r, nil
+}
+
+// ReadInto reads the next complete message into dst's storage, an
+// alternative to Read for high-throughput readers.
+//
+// Read allocates a new slice for every message. ReadInto instead returns
+// dst[:0] with the message appended, growing it only if the message
+// doesn't fit, so a reader that passes the result back in reuses one
+// buffer and cuts GC churn. Fragmented messages are streamed into dst
+// (see NextReader) rather than assembled in an internal buffer first.
+//
+// The returned slice is only valid until the next ReadInto with the same
+// buffer. Errors and limits are as for Read and NextReader; on error the
+// returned slice is empty.
+//
+// Example:
+//
+//	buf := make([]byte, 0, 64<<10)
+//	for {
+//	    var msgType websocket.MessageType
+//	    var err error
+//	    msgType, buf, err = conn.ReadInto(buf)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    process(msgType, buf)
+//	}
+//
+// Thread-Safety: like Read, one goroutine should read at a time.
+func (c *Conn) ReadInto(dst []byte) (MessageType, []byte, error) {
+	buf := dst[:0]
+
+	msgType, r, err := c.NextReader()
+	if err != nil {
+		return 0, buf, err
+	}
+
+	for {
+		if len(buf) == cap(buf) {
+			// Let append pick the new capacity
+			buf = append(buf, 0)[:len(buf)]
+		}
+
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			return msgType, buf, nil
+		}
+		if err != nil {
+			return 0, buf[:0], err
+		}
+	}
+}
+
+// discardMessage reads and drops the rest of the message returned by the
+// last NextReader, if it wasn't read to the end.
+func (c *Conn) discardMessage() error {
+	r := c.activeReader
+	if r == nil {
+		return nil
+	}
+
+	_, err := io.Copy(io.Discard, r)
+	c.activeReader = nil
+	return err
+}
+
+// messageReader streams the payload of one data message (see NextReader).
+type messageReader struct {
+	c       *Conn
+	msgType MessageType
+
+	payload []byte // Unread part of the current frame
+	fin     bool   // Current frame is the last one
+	size    int    // Payload bytes received so far
+
+	// utf8Tail holds the bytes of a UTF-8 sequence split across frames,
+	// for validating text messages.
+	utf8Tail []byte
+
+	err error // Sticky error (io.EOF once the message is complete)
+}
+
+// Read implements io.Reader.
+func (r *messageReader) Read(p []byte) (int, error) {
+	for len(r.payload) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.fin {
+			r.finish()
+			continue
+		}
+		r.next()
+	}
+
+	n := copy(p, r.payload)
+	r.payload = r.payload[n:]
+	return n, nil
+}
+
+// next reads the message's next fragment, recording an error if the
+// message can't continue.
+func (r *messageReader) next() {
+	f, err := r.c.nextFragment(context.Background())
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			// Mid-message, the end of the stream is unexpected
+			err = &CloseError{Code: CloseAbnormalClosure, Err: io.ErrUnexpectedEOF}
+		}
+		r.err = fmt.Errorf("%w: %w", ErrMessageInterrupted, err)
+		return
+	}
+
+	// RFC 6455 Section 5.4: Fragments of one message can't be interleaved
+	// with another data message
+	if f.opcode != opcodeContinuation {
+		_ = r.c.CloseWithCode(CloseProtocolError, "expected continuation")
+		r.err = fmt.Errorf("%w: %w", ErrMessageInterrupted, ErrProtocolError)
+		return
+	}
+
+	if err := r.c.checkMessageSize(r.size + len(f.payload)); err != nil {
+		r.err = err
+		return
+	}
+	if err := r.validate(f.payload); err != nil {
+		r.err = err
+		return
+	}
+	r.payload = f.payload
+	r.fin = f.fin
+	r.size += len(f.payload)
+}
+
+// finish completes the message after its final fragment was read.
+func (r *messageReader) finish() {
+	r.c.fragmentDeadline = time.Time{}
+
+	if r.msgType == TextMessage && len(r.utf8Tail) > 0 {
+		// Message ended in the middle of a UTF-8 sequence
+		_ = r.c.CloseWithCode(CloseInvalidFramePayloadData, "invalid UTF-8")
+		r.err = ErrInvalidUTF8
+		return
+	}
+
+	r.c.stats.recordRead(r.size)
+	r.c.touch()
+	r.err = io.EOF
+}
+
+// validate checks the UTF-8 of a text message fragment, carrying an
+// incomplete trailing sequence over to the next fragment.
+func (r *messageReader) validate(payload []byte) error {
+	if r.msgType != TextMessage {
+		return nil
+	}
+
+	data := payload
+	if len(r.utf8Tail) > 0 {
+		data = append(r.utf8Tail, payload...)
+	}
+
+	// Hold back a trailing partial sequence (at most 3 bytes)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	if !utf8.Valid(data[:cut]) {
+		_ = r.c.CloseWithCode(CloseInvalidFramePayloadData, "invalid UTF-8")
+		return ErrInvalidUTF8
+	}
+	r.utf8Tail = append(r.utf8Tail[:0:0], data[cut:]...)
+	return nil
+}
@@ -0,0 +1,292 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// newStreamConn creates a client-side connection that reads raw from the
+// peer and captures what it writes.
+func newStreamConn(t *testing.T, frames []*frame, raw []byte) (*Conn, *bytes.Buffer) {
+	t.Helper()
+
+	var in bytes.Buffer
+	w := bufio.NewWriter(&in)
+	for _, f := range frames {
+		if err := writeFrameNoValidation(w, f); err != nil {
+			t.Fatalf("writeFrame error: %v", err)
+		}
+	}
+	_ = w.Flush()
+	in.Write(raw)
+
+	var out bytes.Buffer
+	return newConn(nil, bufio.NewReader(&in), bufio.NewWriter(&out), false), &out
+}
+
+// TestConn_NextReader tests streaming a fragmented message with a ping
+// between its fragments.
+func TestConn_NextReader(t *testing.T) {
+	conn, out := newStreamConn(t, []*frame{
+		{fin: false, opcode: opcodeText, payload: []byte("Part1")},
+		{fin: true, opcode: opcodePing, payload: []byte("ping")},
+		{fin: false, opcode: opcodeContinuation, payload: []byte("Part2")},
+		{fin: true, opcode: opcodeContinuation, payload: []byte("Part3")},
+		{fin: true, opcode: opcodeBinary, payload: []byte{1, 2, 3}},
+	}, nil)
+
+	msgType, r, err := conn.NextReader()
+	if err != nil {
+		t.Fatalf("NextReader() error = %v", err)
+	}
+	if msgType != TextMessage {
+		t.Errorf("msgType = %v, want TextMessage", msgType)
+	}
+
+	// Small reads span frame boundaries
+	var got []byte
+	buf := make([]byte, 3)
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+	if string(got) != "Part1Part2Part3" {
+		t.Errorf("payload = %q, want %q", got, "Part1Part2Part3")
+	}
+
+	// The ping was answered
+	pong, err := readFrame(bufio.NewReader(out))
+	if err != nil {
+		t.Fatalf("readFrame() error = %v", err)
+	}
+	if pong.opcode != opcodePong || string(pong.payload) != "ping" {
+		t.Errorf("wrote opcode 0x%X %q, want pong %q", pong.opcode, pong.payload, "ping")
+	}
+
+	msgType, data, err := conn.Read()
+	if err != nil || msgType != BinaryMessage || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Errorf("Read() = %v, %v, %v, want the next binary message", msgType, data, err)
+	}
+	if stats := conn.Stats(); stats.MessagesRead != 2 {
+		t.Errorf("MessagesRead = %d, want 2", stats.MessagesRead)
+	}
+}
+
+// TestConn_NextReader_MalformedControl tests that a malformed control
+// frame mid-message fails the reader instead of ending it with io.EOF.
+func TestConn_NextReader_MalformedControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     []byte // Control frame injected after the first fragment
+		wantErr error
+	}{
+		{"fragmented ping", []byte{0x09, 0x00}, ErrControlFragmented},
+		{"oversized ping", append([]byte{0x89, 0x7E, 0x00, 0x7E}, make([]byte, 126)...), ErrControlTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, out := newStreamConn(t, []*frame{
+				{fin: false, opcode: opcodeBinary, payload: []byte("Part1")},
+			}, tt.raw)
+
+			_, r, err := conn.NextReader()
+			if err != nil {
+				t.Fatalf("NextReader() error = %v", err)
+			}
+
+			data, err := io.ReadAll(r)
+			if err == nil {
+				t.Fatalf("ReadAll() = %q, nil, want error", data)
+			}
+			if !errors.Is(err, ErrMessageInterrupted) || !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadAll() error = %v, want ErrMessageInterrupted wrapping %v", err, tt.wantErr)
+			}
+			if string(data) != "Part1" {
+				t.Errorf("data before error = %q, want %q", data, "Part1")
+			}
+
+			// The error is sticky
+			if _, err2 := r.Read(make([]byte, 1)); !errors.Is(err2, ErrMessageInterrupted) {
+				t.Errorf("second Read() error = %v, want ErrMessageInterrupted", err2)
+			}
+
+			closeFrame, err := readFrame(bufio.NewReader(out))
+			if err != nil {
+				t.Fatalf("readFrame() error = %v", err)
+			}
+			if code := CloseCode(binary.BigEndian.Uint16(closeFrame.payload)); code != CloseProtocolError {
+				t.Errorf("close code = %d, want %d", code, CloseProtocolError)
+			}
+		})
+	}
+}
+
+// TestConn_NextReader_Interrupted tests messages cut short by the peer.
+func TestConn_NextReader_Interrupted(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  []*frame
+		wantErr error
+	}{
+		{
+			name:    "connection dropped",
+			frames:  []*frame{{fin: false, opcode: opcodeText, payload: []byte("Part1")}},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name: "close frame",
+			frames: []*frame{
+				{fin: false, opcode: opcodeText, payload: []byte("Part1")},
+				{fin: true, opcode: opcodeClose, payload: []byte{0x03, 0xE8}},
+			},
+			wantErr: ErrClosed,
+		},
+		{
+			name: "new message before final fragment",
+			frames: []*frame{
+				{fin: false, opcode: opcodeText, payload: []byte("Part1")},
+				{fin: true, opcode: opcodeText, payload: []byte("other")},
+			},
+			wantErr: ErrProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _ := newStreamConn(t, tt.frames, nil)
+
+			_, r, err := conn.NextReader()
+			if err != nil {
+				t.Fatalf("NextReader() error = %v", err)
+			}
+			if _, err := io.ReadAll(r); !errors.Is(err, ErrMessageInterrupted) || !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadAll() error = %v, want ErrMessageInterrupted wrapping %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestConn_NextReader_UTF8 tests UTF-8 validation across fragments.
+func TestConn_NextReader_UTF8(t *testing.T) {
+	euro := []byte("€") // 3 bytes
+
+	t.Run("split sequence", func(t *testing.T) {
+		conn, _ := newStreamConn(t, []*frame{
+			{fin: false, opcode: opcodeText, payload: []byte("ok")},
+			{fin: false, opcode: opcodeContinuation, payload: euro[:1]},
+			{fin: false, opcode: opcodeContinuation, payload: euro[1:2]},
+			{fin: true, opcode: opcodeContinuation, payload: euro[2:]},
+		}, nil)
+
+		_, r, err := conn.NextReader()
+		if err != nil {
+			t.Fatalf("NextReader() error = %v", err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil || string(data) != "ok€" {
+			t.Errorf("ReadAll() = %q, %v, want %q", data, err, "ok€")
+		}
+	})
+
+	t.Run("truncated sequence", func(t *testing.T) {
+		conn, _ := newStreamConn(t, []*frame{
+			{fin: false, opcode: opcodeText, payload: []byte("ok")},
+			{fin: true, opcode: opcodeContinuation, payload: euro[:2]},
+		}, nil)
+
+		_, r, err := conn.NextReader()
+		if err != nil {
+			t.Fatalf("NextReader() error = %v", err)
+		}
+		if _, err := io.ReadAll(r); !errors.Is(err, ErrInvalidUTF8) {
+			t.Errorf("ReadAll() error = %v, want ErrInvalidUTF8", err)
+		}
+	})
+}
+
+// TestConn_NextReader_Discard tests that an unread remainder is skipped
+// by the next read.
+func TestConn_NextReader_Discard(t *testing.T) {
+	conn, _ := newStreamConn(t, []*frame{
+		{fin: false, opcode: opcodeBinary, payload: []byte("skip")},
+		{fin: true, opcode: opcodeContinuation, payload: []byte("ped")},
+		{fin: true, opcode: opcodeText, payload: []byte("next")},
+	}, nil)
+
+	if _, _, err := conn.NextReader(); err != nil {
+		t.Fatalf("NextReader() error = %v", err)
+	}
+
+	msgType, r, err := conn.NextReader()
+	if err != nil {
+		t.Fatalf("second NextReader() error = %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if msgType != TextMessage || string(data) != "next" || err != nil {
+		t.Errorf("second message = %v, %q, %v, want text %q", msgType, data, err, "next")
+	}
+}
+
+// TestConn_ReadInto tests reading several messages, fragmented and not,
+// into one reused buffer.
+func TestConn_ReadInto(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), 100)
+	conn, _ := newStreamConn(t, []*frame{
+		{fin: true, opcode: opcodeText, payload: []byte("hello")},
+		{fin: false, opcode: opcodeBinary, payload: []byte{1, 2}},
+		{fin: true, opcode: opcodePing, payload: []byte("ping")},
+		{fin: true, opcode: opcodeContinuation, payload: []byte{3}},
+		{fin: true, opcode: opcodeText, payload: []byte("")},
+		{fin: true, opcode: opcodeBinary, payload: large},
+	}, nil)
+
+	buf := make([]byte, 0, 16)
+	backing := &buf[:1][0]
+
+	tests := []struct {
+		wantType MessageType
+		want     []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, []byte{1, 2, 3}},
+		{TextMessage, []byte{}},
+	}
+	for i, tt := range tests {
+		var msgType MessageType
+		var err error
+		msgType, buf, err = conn.ReadInto(buf)
+		if err != nil {
+			t.Fatalf("message %d: ReadInto() error = %v", i, err)
+		}
+		if msgType != tt.wantType || !bytes.Equal(buf, tt.want) {
+			t.Errorf("message %d: ReadInto() = %v %q, want %v %q", i, msgType, buf, tt.wantType, tt.want)
+		}
+		if &buf[:1][0] != backing {
+			t.Errorf("message %d: buffer reallocated although the message fit", i)
+		}
+	}
+
+	// A message larger than the buffer grows it
+	msgType, buf, err := conn.ReadInto(buf)
+	if err != nil {
+		t.Fatalf("ReadInto() error = %v", err)
+	}
+	if msgType != BinaryMessage || !bytes.Equal(buf, large) {
+		t.Errorf("ReadInto() = %v %q, want the %d-byte binary message", msgType, buf, len(large))
+	}
+
+	if _, buf, err = conn.ReadInto(buf); err == nil || len(buf) != 0 {
+		t.Errorf("ReadInto() at end of stream = %q, %v, want an empty slice and an error", buf, err)
+	}
+}
@@ -0,0 +1,338 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReconnectingConn defaults.
+const (
+	defaultReconnectMinBackoff = time.Second
+	defaultReconnectMaxBackoff = 30 * time.Second
+)
+
+// ReconnectOptions configures a ReconnectingConn.
+//
+// All fields are optional. Zero values use sensible defaults.
+type ReconnectOptions struct {
+	// Dial is used for every connection attempt (default: nil, Dial
+	// defaults).
+	Dial *DialOptions
+
+	// MinBackoff is the delay before the first reconnect attempt
+	// (default: 1s). It doubles after each failed attempt.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts (default: 30s).
+	MaxBackoff time.Duration
+
+	// MaxAttempts is the number of consecutive failed attempts before
+	// giving up with ErrReconnectFailed (default: 0, unlimited).
+	MaxAttempts int
+
+	// Resume is called with each new connection after a reconnect, before
+	// Read and Write use it, e.g. to resubscribe or send the ID of the
+	// last message received. An error fails the attempt. Not called for
+	// the initial connection.
+	Resume func(conn *Conn) error
+}
+
+// ReconnectingConn is a client connection that re-dials when it drops, for
+// long-running clients such as IoT devices.
+//
+// Read and Write work like the Conn methods. When the connection fails,
+// the call reconnects with backoff (see ReconnectOptions), runs Resume, and
+// carries on with the new connection: Read returns the next message from
+// it, and Write writes the message again. Messages the server sends while
+// the client is disconnected are lost unless Resume asks for them.
+//
+// Transient failures (network errors, a dropped connection, or a close
+// frame with 1001 or 1011-1014) are retried. Failures that a new
+// connection wouldn't fix end the ReconnectingConn, and every later call
+// returns the same error:
+//   - ErrPermanentFailure: the server closed the connection with any other
+//     code (e.g. 1000 or 1008), or rejected the handshake with a 4xx status
+//     other than 408 or 429.
+//   - ErrReconnectFailed: ReconnectOptions.MaxAttempts attempts in a row
+//     failed.
+//
+// Only Read sees close codes: a Write racing the server's close may
+// reconnect once before Read reports ErrPermanentFailure.
+//
+// Thread-safe: One goroutine may call Read while others call Write and
+// Close.
+//
+// Example:
+//
+//	conn, err := websocket.NewReconnectingConn(ctx, "wss://example.com/telemetry",
+//	    &websocket.ReconnectOptions{
+//	        MaxBackoff: time.Minute,
+//	        Resume: func(c *websocket.Conn) error {
+//	            return c.WriteText(`{"resume":"` + lastID + `"}`)
+//	        },
+//	    })
+//	if err != nil {
+//	    return err
+//	}
+//	defer conn.Close()
+//
+//	for {
+//	    _, data, err := conn.Read()
+//	    if errors.Is(err, websocket.ErrPermanentFailure) {
+//	        return err // Don't come back
+//	    }
+//	    ...
+//	}
+type ReconnectingConn struct {
+	url    string
+	opts   ReconnectOptions
+	ctx    context.Context // Canceled by Close
+	cancel context.CancelFunc
+
+	// conn is the current connection.
+	conn atomic.Pointer[Conn]
+
+	// mu serializes reconnects and protects err, the failure that ended
+	// the ReconnectingConn (nil while it's usable).
+	mu  sync.Mutex
+	err error
+}
+
+// NewReconnectingConn dials url and returns a ReconnectingConn for it.
+//
+// The initial connection is retried like a reconnect (without Resume),
+// and ctx bounds the ReconnectingConn's whole lifetime: canceling it
+// closes the connection. If opts is nil, defaults are used.
+//
+// Returns ErrPermanentFailure or ErrReconnectFailed if no connection could
+// be established, or ErrClosed if ctx was canceled first.
+func NewReconnectingConn(ctx context.Context, url string, opts *ReconnectOptions) (*ReconnectingConn, error) {
+	r := &ReconnectingConn{url: url}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.MinBackoff <= 0 {
+		r.opts.MinBackoff = defaultReconnectMinBackoff
+	}
+	if r.opts.MaxBackoff < r.opts.MinBackoff {
+		r.opts.MaxBackoff = max(defaultReconnectMaxBackoff, r.opts.MinBackoff)
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	conn, err := r.connect(false)
+	if err != nil {
+		r.cancel()
+		return nil, err
+	}
+	r.conn.Store(conn)
+
+	context.AfterFunc(r.ctx, func() { _ = r.conn.Load().Close() })
+	return r, nil
+}
+
+// Read reads the next message, reconnecting if the connection fails.
+func (r *ReconnectingConn) Read() (MessageType, []byte, error) {
+	for {
+		conn, err := r.current()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		msgType, data, err := conn.Read()
+		if err == nil {
+			return msgType, data, nil
+		}
+		if err := r.reconnectAfter(conn, err); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// Write writes a message, reconnecting and writing it again if the
+// connection fails.
+//
+// Errors that don't fail the connection, such as ErrInvalidUTF8, are
+// returned as is.
+func (r *ReconnectingConn) Write(messageType MessageType, data []byte) error {
+	for {
+		conn, err := r.current()
+		if err != nil {
+			return err
+		}
+
+		err = conn.Write(messageType, data)
+		if err == nil || !conn.isClosed() {
+			return err
+		}
+		if err := r.reconnectAfter(conn, err); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteText writes a text message (see Write).
+func (r *ReconnectingConn) WriteText(text string) error {
+	return r.Write(TextMessage, []byte(text))
+}
+
+// Close closes the connection and stops reconnecting. Calls blocked in
+// Read, Write, or a reconnect return ErrClosed.
+//
+// It's safe to call Close multiple times.
+func (r *ReconnectingConn) Close() error {
+	r.cancel()
+	return r.conn.Load().Close()
+}
+
+// current returns the connection to use, or the error that ended the
+// ReconnectingConn.
+func (r *ReconnectingConn) current() (*Conn, error) {
+	if r.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.conn.Load(), nil
+}
+
+// reconnectAfter replaces failed, which failed with cause, by a new
+// connection. It's a no-op if another call already replaced it. Returns the
+// error that ends the ReconnectingConn, if any.
+func (r *ReconnectingConn) reconnectAfter(failed *Conn, cause error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.ctx.Err() != nil:
+		return ErrClosed
+	case r.err != nil:
+		return r.err
+	case r.conn.Load() != failed:
+		return nil
+	}
+	_ = failed.Close()
+
+	if isPermanentClose(cause) {
+		r.err = fmt.Errorf("%w: %w", ErrPermanentFailure, cause)
+		return r.err
+	}
+
+	r.log().Info("websocket: connection lost, reconnecting", "err", cause)
+	conn, err := r.connect(true)
+	if err != nil {
+		if !errors.Is(err, ErrClosed) {
+			r.err = err
+		}
+		return err
+	}
+	r.conn.Store(conn)
+
+	// Close may have run during the reconnect, closing the old connection
+	if r.ctx.Err() != nil {
+		_ = conn.Close()
+		return ErrClosed
+	}
+	return nil
+}
+
+// connect dials until a connection is established or the failure is
+// permanent. For a reconnect, every attempt waits out the backoff first and
+// runs Resume on the new connection.
+func (r *ReconnectingConn) connect(reconnect bool) (*Conn, error) {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if r.opts.MaxAttempts > 0 && attempt > r.opts.MaxAttempts {
+			return nil, fmt.Errorf("%w after %d attempts: %w", ErrReconnectFailed, r.opts.MaxAttempts, lastErr)
+		}
+
+		waits := attempt - 1
+		if reconnect {
+			waits = attempt
+		}
+		if waits > 0 {
+			timer := time.NewTimer(r.backoff(waits))
+			select {
+			case <-timer.C:
+			case <-r.ctx.Done():
+				timer.Stop()
+				return nil, ErrClosed
+			}
+		}
+
+		conn, resp, err := Dial(r.ctx, r.url, r.opts.Dial)
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		if err == nil && reconnect && r.opts.Resume != nil {
+			if err = r.opts.Resume(conn); err != nil {
+				_ = conn.Close()
+			}
+		}
+
+		switch {
+		case err == nil:
+			return conn, nil
+		case r.ctx.Err() != nil:
+			return nil, ErrClosed
+		case isPermanentHandshake(err, resp):
+			return nil, fmt.Errorf("%w: %w", ErrPermanentFailure, err)
+		}
+		r.log().Info("websocket: connection attempt failed", "attempt", attempt, "err", err)
+		lastErr = err
+	}
+}
+
+// backoff returns the delay before the nth wait (from 1).
+func (r *ReconnectingConn) backoff(n int) time.Duration {
+	delay := r.opts.MinBackoff
+	for i := 1; i < n && delay < r.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.opts.MaxBackoff)
+}
+
+// log returns the logger from the dial options (discarding if none is set).
+func (r *ReconnectingConn) log() Logger {
+	if r.opts.Dial == nil || r.opts.Dial.Logger == nil {
+		return nopLogger{}
+	}
+	return r.opts.Dial.Logger
+}
+
+// isPermanentClose reports whether err is a close frame from the peer that
+// ends the session on purpose. Going away, server errors, restarts,
+// overload, and gateway errors invite the client back; a dropped
+// connection has no close frame.
+func isPermanentClose(err error) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) || !errors.Is(err, ErrClosed) {
+		return false
+	}
+	switch ce.Code {
+	case CloseGoingAway, CloseInternalServerErr, CloseServiceRestart, CloseTryAgainLater, CloseBadGateway:
+		return false
+	}
+	return true
+}
+
+// isPermanentHandshake reports whether the server rejected the handshake
+// with a client error that retrying won't fix.
+func isPermanentHandshake(err error, resp *http.Response) bool {
+	if !errors.Is(err, ErrBadHandshake) || resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return resp.StatusCode >= 400 && resp.StatusCode < 500
+}
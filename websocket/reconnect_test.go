@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestReconnectingConn tests that the connection is re-established with a
+// resume message after the server drops it, and that a deliberate close
+// from the server is a permanent failure.
+func TestReconnectingConn(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if connections.Add(1) == 1 {
+			// Echo one message, then drop the connection without a close
+			// frame
+			if _, data, err := conn.Read(); err == nil {
+				_ = conn.Write(TextMessage, data)
+			}
+			_ = conn.conn.Close()
+			return
+		}
+
+		if text, err := conn.ReadText(); err != nil || text != "resume" {
+			return
+		}
+		_ = conn.WriteText("resumed")
+		for {
+			_, data, err := conn.Read()
+			if err != nil {
+				return
+			}
+			if string(data) == "bye" {
+				_ = conn.CloseWithCode(ClosePolicyViolation, "go away")
+				return
+			}
+			_ = conn.Write(TextMessage, data)
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	var resumes atomic.Int32
+	conn, err := NewReconnectingConn(context.Background(), wsURL, &ReconnectOptions{
+		MinBackoff: 10 * time.Millisecond,
+		Resume: func(c *Conn) error {
+			resumes.Add(1)
+			return c.WriteText("resume")
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewReconnectingConn() error = %v", err)
+	}
+	defer conn.Close()
+
+	for _, want := range []string{"one", "resumed", "two"} {
+		if want != "resumed" {
+			if err := conn.WriteText(want); err != nil {
+				t.Fatalf("WriteText(%q) error = %v", want, err)
+			}
+		}
+		_, data, err := conn.Read()
+		if err != nil || string(data) != want {
+			t.Fatalf("Read() = %q, %v, want %q", data, err, want)
+		}
+	}
+	if got := connections.Load(); got != 2 {
+		t.Errorf("server saw %d connections, want 2", got)
+	}
+	if got := resumes.Load(); got != 1 {
+		t.Errorf("Resume called %d times, want 1", got)
+	}
+
+	if err := conn.WriteText("bye"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	_, _, err = conn.Read()
+	if !errors.Is(err, ErrPermanentFailure) || !IsCloseErrorCode(err, ClosePolicyViolation) {
+		t.Fatalf("Read() error = %v, want ErrPermanentFailure with 1008", err)
+	}
+	if err := conn.WriteText("again"); !errors.Is(err, ErrPermanentFailure) {
+		t.Errorf("WriteText() after permanent failure error = %v, want ErrPermanentFailure", err)
+	}
+}
+
+// TestReconnectingConn_Dial tests that a rejected handshake is permanent and
+// an unreachable server is retried up to MaxAttempts.
+func TestReconnectingConn_Dial(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		if r.URL.Path == "/forbidden" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	opts := &ReconnectOptions{MinBackoff: time.Millisecond, MaxAttempts: 3}
+
+	_, err := NewReconnectingConn(context.Background(), wsURL+"/forbidden", opts)
+	if !errors.Is(err, ErrPermanentFailure) || !errors.Is(err, ErrBadHandshake) {
+		t.Errorf("NewReconnectingConn(403) error = %v, want ErrPermanentFailure", err)
+	}
+	if got := attempts.Swap(0); got != 1 {
+		t.Errorf("403: %d attempts, want 1", got)
+	}
+
+	_, err = NewReconnectingConn(context.Background(), wsURL+"/unavailable", opts)
+	if !errors.Is(err, ErrReconnectFailed) || errors.Is(err, ErrPermanentFailure) {
+		t.Errorf("NewReconnectingConn(503) error = %v, want ErrReconnectFailed", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("503: %d attempts, want 3", got)
+	}
+}
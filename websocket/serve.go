@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"errors"
+	"iter"
+)
+
+// MessageHandler holds the callbacks used by Conn.Serve.
+//
+// All fields are optional. Messages without a callback are discarded.
+type MessageHandler struct {
+	// OnText is called for each text message. Returning an error stops
+	// Serve, which returns it.
+	OnText func(text string) error
+
+	// OnBinary is called for each binary message. Returning an error stops
+	// Serve, which returns it.
+	OnBinary func(data []byte) error
+
+	// OnClose is called once when the peer closes the connection, with the
+	// code and reason from its close frame.
+	OnClose func(code CloseCode, reason string)
+}
+
+// Serve runs the read loop, dispatching each message to handler until the
+// connection closes or an error occurs.
+//
+// Control frames are handled as by Read: pings are answered automatically
+// and a close frame from the peer is echoed.
+//
+// Returns nil when the peer closes the connection with a close frame
+// (after calling OnClose). Otherwise returns the read error or the error
+// returned by a callback. Serve doesn't close the connection on return.
+//
+// Example:
+//
+//	conn, err := websocket.Upgrade(w, r, nil)
+//	if err != nil {
+//	    return
+//	}
+//	defer conn.Close()
+//
+//	err = conn.Serve(websocket.MessageHandler{
+//	    OnText: func(text string) error {
+//	        return conn.WriteText(text) // Echo
+//	    },
+//	    OnClose: func(code websocket.CloseCode, reason string) {
+//	        log.Printf("client closed: %d %s", code, reason)
+//	    },
+//	})
+func (c *Conn) Serve(handler MessageHandler) error {
+	for {
+		msgType, data, err := c.Read()
+		if err != nil {
+			var ce *CloseError
+			if errors.As(err, &ce) && errors.Is(err, ErrClosed) {
+				if handler.OnClose != nil {
+					handler.OnClose(ce.Code, ce.Reason)
+				}
+				return nil
+			}
+			return err
+		}
+
+		switch msgType {
+		case TextMessage:
+			if handler.OnText != nil {
+				err = handler.OnText(string(data))
+			}
+		case BinaryMessage:
+			if handler.OnBinary != nil {
+				err = handler.OnBinary(data)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Messages returns an iterator over the messages read from the connection,
+// for use with range:
+//
+//	for msgType, data := range conn.Messages() {
+//	    log.Printf("received %s: %s", msgType, data)
+//	}
+//	if err := conn.Err(); err != nil {
+//	    log.Printf("read error: %v", err)
+//	}
+//
+// Each message is read as by Read, so control frames are handled
+// automatically. The iteration stops when Read fails; Err then reports why.
+// Breaking out of the loop leaves the connection open.
+func (c *Conn) Messages() iter.Seq2[MessageType, []byte] {
+	return func(yield func(MessageType, []byte) bool) {
+		c.messagesErr = nil
+		for {
+			msgType, data, err := c.Read()
+			if err != nil {
+				c.messagesErr = err
+				return
+			}
+			if !yield(msgType, data) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error that ended the last Messages loop.
+//
+// Like Serve, it returns nil when the peer closed the connection with a
+// close frame, or when the loop was ended by break. Otherwise it returns
+// the read error, e.g. a *CloseError with CloseAbnormalClosure if the
+// connection dropped.
+//
+// Call Err after the loop, from the goroutine that ran it.
+func (c *Conn) Err() error {
+	var ce *CloseError
+	if errors.As(c.messagesErr, &ce) && errors.Is(c.messagesErr, ErrClosed) {
+		return nil
+	}
+	return c.messagesErr
+}
@@ -0,0 +1,151 @@
+package websocket
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestConn_Serve tests that messages are dispatched by type and a clean
+// close ends the loop.
+func TestConn_Serve(t *testing.T) {
+	frames := []*frame{
+		{fin: true, opcode: opcodeText, payload: []byte("hello")},
+		{fin: true, opcode: opcodePing, payload: nil},
+		{fin: true, opcode: opcodeBinary, payload: []byte{1, 2, 3}},
+		{fin: true, opcode: opcodeText, payload: []byte("bye")},
+		{fin: true, opcode: opcodeClose, payload: []byte{0x03, 0xE9, 'g', 'o', 'n', 'e'}}, // 1001
+	}
+	conn := mockConn(t, frames, true)
+
+	var texts []string
+	var binaries [][]byte
+	var closeCode CloseCode
+	var closeReason string
+	closeCalls := 0
+
+	err := conn.Serve(MessageHandler{
+		OnText: func(text string) error {
+			texts = append(texts, text)
+			return nil
+		},
+		OnBinary: func(data []byte) error {
+			binaries = append(binaries, data)
+			return nil
+		},
+		OnClose: func(code CloseCode, reason string) {
+			closeCalls++
+			closeCode, closeReason = code, reason
+		},
+	})
+	if err != nil {
+		t.Fatalf("Serve() error = %v, want nil on clean close", err)
+	}
+
+	if !slices.Equal(texts, []string{"hello", "bye"}) {
+		t.Errorf("texts = %q, want [hello bye]", texts)
+	}
+	if len(binaries) != 1 || !slices.Equal(binaries[0], []byte{1, 2, 3}) {
+		t.Errorf("binaries = %v, want [[1 2 3]]", binaries)
+	}
+	if closeCalls != 1 || closeCode != CloseGoingAway || closeReason != "gone" {
+		t.Errorf("OnClose called %d times with (%d, %q), want once with (1001, \"gone\")",
+			closeCalls, closeCode, closeReason)
+	}
+}
+
+// TestConn_ServeHandlerError tests that a callback error stops Serve.
+func TestConn_ServeHandlerError(t *testing.T) {
+	frames := []*frame{
+		{fin: true, opcode: opcodeText, payload: []byte("one")},
+		{fin: true, opcode: opcodeText, payload: []byte("two")},
+	}
+	conn := mockConn(t, frames, true)
+
+	errStop := errors.New("stop")
+	calls := 0
+	err := conn.Serve(MessageHandler{
+		OnText: func(string) error {
+			calls++
+			return errStop
+		},
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Serve() error = %v, want %v", err, errStop)
+	}
+	if calls != 1 {
+		t.Errorf("OnText called %d times, want 1", calls)
+	}
+}
+
+// TestConn_ServeAbnormalClosure tests that a dropped connection is reported
+// as an error without calling OnClose.
+func TestConn_ServeAbnormalClosure(t *testing.T) {
+	conn := mockConn(t, nil, true)
+
+	err := conn.Serve(MessageHandler{
+		OnClose: func(CloseCode, string) {
+			t.Error("OnClose called for abnormal closure")
+		},
+	})
+	if !IsCloseErrorCode(err, CloseAbnormalClosure) {
+		t.Errorf("Serve() error = %v, want CloseAbnormalClosure", err)
+	}
+}
+
+// TestConn_Messages tests ranging over messages until a clean close.
+func TestConn_Messages(t *testing.T) {
+	frames := []*frame{
+		{fin: true, opcode: opcodeText, payload: []byte("hello")},
+		{fin: true, opcode: opcodePing, payload: nil},
+		{fin: true, opcode: opcodeBinary, payload: []byte{1, 2, 3}},
+		{fin: true, opcode: opcodeClose, payload: []byte{0x03, 0xE8}}, // 1000
+	}
+	conn := mockConn(t, frames, true)
+
+	var types []MessageType
+	var payloads []string
+	for msgType, data := range conn.Messages() {
+		types = append(types, msgType)
+		payloads = append(payloads, string(data))
+	}
+
+	if !slices.Equal(types, []MessageType{TextMessage, BinaryMessage}) {
+		t.Errorf("types = %v, want [text binary]", types)
+	}
+	if !slices.Equal(payloads, []string{"hello", "\x01\x02\x03"}) {
+		t.Errorf("payloads = %q, want [hello \\x01\\x02\\x03]", payloads)
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil after clean close", err)
+	}
+}
+
+// TestConn_MessagesError tests that Err reports a dropped connection and
+// that breaking out of the loop isn't an error.
+func TestConn_MessagesError(t *testing.T) {
+	frames := []*frame{
+		{fin: true, opcode: opcodeText, payload: []byte("one")},
+		{fin: true, opcode: opcodeText, payload: []byte("two")},
+	}
+	conn := mockConn(t, frames, true)
+
+	for range conn.Messages() {
+		break
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() after break = %v, want nil", err)
+	}
+
+	// One message left, then the connection drops
+	count := 0
+	for range conn.Messages() {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("got %d messages, want 1", count)
+	}
+	if err := conn.Err(); !IsCloseErrorCode(err, CloseAbnormalClosure) {
+		t.Errorf("Err() = %v, want CloseAbnormalClosure", err)
+	}
+}
@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConnStats is a snapshot of a connection's counters.
+//
+// Message and byte counts cover data messages (text and binary) and their
+// payloads; frame headers and control frames are not included. Ping and
+// pong counts cover control frames in each direction, including pongs sent
+// automatically in reply to pings.
+type ConnStats struct {
+	MessagesRead    uint64 // Data messages received
+	BytesRead       uint64 // Payload bytes received
+	MessagesWritten uint64 // Data messages sent
+	BytesWritten    uint64 // Payload bytes sent
+
+	PingsSent     uint64 // Ping frames sent
+	PingsReceived uint64 // Ping frames received
+	PongsSent     uint64 // Pong frames sent
+	PongsReceived uint64 // Pong frames received
+
+	ConnectedAt time.Time // When the connection was established
+}
+
+// connStats holds a connection's counters.
+//
+// Counters are atomics, so updates on the read and write paths don't take
+// a lock.
+type connStats struct {
+	messagesRead    atomic.Uint64
+	bytesRead       atomic.Uint64
+	messagesWritten atomic.Uint64
+	bytesWritten    atomic.Uint64
+
+	pingsSent     atomic.Uint64
+	pingsReceived atomic.Uint64
+	pongsSent     atomic.Uint64
+	pongsReceived atomic.Uint64
+
+	connectedAt time.Time // Set once by newConn
+}
+
+// Stats returns a snapshot of the connection's counters.
+//
+// Counters are read individually, so a snapshot taken while messages are
+// in flight may be slightly inconsistent (e.g. a message counted without
+// its bytes).
+//
+// Example:
+//
+//	stats := conn.Stats()
+//	log.Printf("%d messages in, %d out, connected %v",
+//	    stats.MessagesRead, stats.MessagesWritten, time.Since(stats.ConnectedAt))
+//
+// Thread-safe: can be called from multiple goroutines.
+func (c *Conn) Stats() ConnStats {
+	return ConnStats{
+		MessagesRead:    c.stats.messagesRead.Load(),
+		BytesRead:       c.stats.bytesRead.Load(),
+		MessagesWritten: c.stats.messagesWritten.Load(),
+		BytesWritten:    c.stats.bytesWritten.Load(),
+		PingsSent:       c.stats.pingsSent.Load(),
+		PingsReceived:   c.stats.pingsReceived.Load(),
+		PongsSent:       c.stats.pongsSent.Load(),
+		PongsReceived:   c.stats.pongsReceived.Load(),
+		ConnectedAt:     c.stats.connectedAt,
+	}
+}
+
+// recordRead counts a received data message of n payload bytes.
+func (s *connStats) recordRead(n int) {
+	s.messagesRead.Add(1)
+	s.bytesRead.Add(uint64(n))
+}
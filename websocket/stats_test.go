@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+// TestConn_Stats tests that reads, writes, and control frames are counted.
+func TestConn_Stats(t *testing.T) {
+	frames := []*frame{
+		{fin: true, opcode: opcodeText, payload: []byte("hello")},
+		{fin: true, opcode: opcodePing, payload: []byte("p")},
+		{fin: true, opcode: opcodePong, payload: nil},
+		{fin: false, opcode: opcodeBinary, payload: []byte{1, 2}},
+		{fin: true, opcode: opcodeContinuation, payload: []byte{3}},
+	}
+
+	before := time.Now()
+	conn := mockConn(t, frames, true)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := conn.Read(); err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+
+	for _, msg := range []string{"a", "bb", "ccc"} {
+		if err := conn.WriteText(msg); err != nil {
+			t.Fatalf("WriteText() error = %v", err)
+		}
+	}
+	if err := conn.Ping(nil); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	got := conn.Stats()
+	want := ConnStats{
+		MessagesRead:    2,
+		BytesRead:       8, // "hello" + 3 fragmented bytes
+		MessagesWritten: 3,
+		BytesWritten:    6,
+		PingsSent:       1,
+		PingsReceived:   1,
+		PongsSent:       1, // Automatic reply to the ping
+		PongsReceived:   1,
+		ConnectedAt:     got.ConnectedAt,
+	}
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if got.ConnectedAt.Before(before) || got.ConnectedAt.After(time.Now()) {
+		t.Errorf("ConnectedAt = %v, want time of newConn", got.ConnectedAt)
+	}
+}
@@ -489,9 +489,63 @@ func TestStress_MemoryPressure(t *testing.T) {
 }
 
 // TestStress_PingPongStorm tests handling of many ping/pong control frames.
-// NOTE: Skipped - requires SetPongHandler() and WritePing() methods not yet implemented.
 func TestStress_PingPongStorm(t *testing.T) {
-	t.Skip("Requires SetPongHandler() and WritePing() methods - TODO")
+	if testing.Short() {
+		t.Skip("Skipping stress test in short mode")
+	}
+
+	// Server's read loop answers every ping
+	server := newTestServer(t, func(conn *Conn) {
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+
+	conn := dialTestServer(t, server)
+	defer conn.Close()
+
+	// Client's read loop receives the pongs
+	go func() {
+		for {
+			if _, _, err := conn.Read(); err != nil {
+				return
+			}
+		}
+	}()
+
+	const numPings = 10000
+	const numSenders = 10
+
+	startTime := time.Now()
+	var wg sync.WaitGroup
+	for s := 0; s < numSenders; s++ {
+		wg.Add(1)
+		go func(sender int) {
+			defer wg.Done()
+			for i := 0; i < numPings/numSenders; i++ {
+				if err := conn.WritePing([]byte(fmt.Sprintf("%d-%d", sender, i))); err != nil {
+					t.Errorf("WritePing error: %v", err)
+					return
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for conn.Stats().PongsReceived < numPings {
+		if time.Now().After(deadline) {
+			t.Fatalf("Received %d pongs, want %d", conn.Stats().PongsReceived, numPings)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	duration := time.Since(startTime)
+
+	t.Logf("Ping/pong storm: %d round trips in %v (%.0f/sec)",
+		numPings, duration, float64(numPings)/duration.Seconds())
 }
 
 // TestStress_ConnectionTimeout tests handling of connection timeouts and deadlines.
@@ -0,0 +1,74 @@
+package websocket
+
+import "strings"
+
+// ParseSubprotocols parses a Sec-WebSocket-Protocol header value into its
+// list of subprotocols, in order.
+//
+// Elements are separated by commas with optional spaces or tabs around
+// them (RFC 6455 Section 4.1, RFC 7230 Section 7). Empty elements and
+// elements that aren't valid tokens (e.g. containing spaces or quotes) are
+// skipped. Returns nil if the header holds no subprotocols.
+//
+// Example:
+//
+//	offered := websocket.ParseSubprotocols(r.Header.Get("Sec-WebSocket-Protocol"))
+//	// "chat.v2, chat.v1" → ["chat.v2", "chat.v1"]
+func ParseSubprotocols(header string) []string {
+	var protocols []string
+	for _, element := range strings.Split(header, ",") {
+		element = strings.Trim(element, " \t")
+		if isToken(element) {
+			protocols = append(protocols, element)
+		}
+	}
+	return protocols
+}
+
+// FormatSubprotocols builds a Sec-WebSocket-Protocol header value from a
+// list of subprotocols, in order.
+//
+// Entries that aren't valid tokens (including empty strings) are skipped,
+// since the peer couldn't parse them. Returns "" if no entry is valid.
+//
+// Example:
+//
+//	req.Header.Set("Sec-WebSocket-Protocol",
+//	    websocket.FormatSubprotocols([]string{"chat.v2", "chat.v1"}))
+//	// "chat.v2, chat.v1"
+func FormatSubprotocols(protocols []string) string {
+	var b strings.Builder
+	for _, protocol := range protocols {
+		if !isToken(protocol) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(protocol)
+	}
+	return b.String()
+}
+
+// isToken reports whether s is a non-empty HTTP token (RFC 7230 Section
+// 3.2.6), the syntax of subprotocol names.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTokenChar reports whether c is a tchar: a visible ASCII character
+// other than a delimiter.
+func isTokenChar(c byte) bool {
+	if c <= ' ' || c >= 0x7F {
+		return false
+	}
+	return !strings.ContainsRune(`"(),/:;<=>?@[\]{}`, rune(c))
+}
@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// TestParseSubprotocols tests parsing Sec-WebSocket-Protocol values.
+func TestParseSubprotocols(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"only separators", " , ,\t", nil},
+		{"single", "chat", []string{"chat"}},
+		{"multiple", "chat.v2,chat.v1,superchat", []string{"chat.v2", "chat.v1", "superchat"}},
+		{"extra whitespace", "  chat.v2 ,\tchat.v1\t,  superchat  ", []string{"chat.v2", "chat.v1", "superchat"}},
+		{"empty elements", "chat,,  ,json", []string{"chat", "json"}},
+		{"invalid tokens skipped", `chat v2, "quoted", mqtt, a/b`, []string{"mqtt"}},
+		{"token characters", "v1.0+json_x~!#$%&'*^`|", []string{"v1.0+json_x~!#$%&'*^`|"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSubprotocols(tt.header); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseSubprotocols(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatSubprotocols tests building Sec-WebSocket-Protocol values.
+func TestFormatSubprotocols(t *testing.T) {
+	tests := []struct {
+		name      string
+		protocols []string
+		want      string
+	}{
+		{"nil", nil, ""},
+		{"single", []string{"chat"}, "chat"},
+		{"multiple", []string{"chat.v2", "chat.v1"}, "chat.v2, chat.v1"},
+		{"invalid skipped", []string{"", "chat", "bad name", "json"}, "chat, json"},
+		{"none valid", []string{"", "a,b"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatSubprotocols(tt.protocols)
+			if got != tt.want {
+				t.Errorf("FormatSubprotocols(%q) = %q, want %q", tt.protocols, got, tt.want)
+			}
+
+			// Round trip keeps the valid entries
+			var valid []string
+			for _, p := range tt.protocols {
+				if isToken(p) {
+					valid = append(valid, p)
+				}
+			}
+			if back := ParseSubprotocols(got); !slices.Equal(back, valid) {
+				t.Errorf("ParseSubprotocols(%q) = %q, want %q", got, back, valid)
+			}
+		})
+	}
+}
+
+// TestNegotiateSubprotocol_MultipleHeaders tests an offer split across
+// several header lines.
+func TestNegotiateSubprotocol_MultipleHeaders(t *testing.T) {
+	r, _ := http.NewRequest(http.MethodGet, "/", http.NoBody)
+	r.Header.Add("Sec-WebSocket-Protocol", "chat.v3")
+	r.Header.Add("Sec-WebSocket-Protocol", " chat.v1 , chat.v2")
+
+	if got := negotiateSubprotocol(r, []string{"chat.v2", "chat.v1"}); got != "chat.v1" {
+		t.Errorf("negotiateSubprotocol() = %q, want %q", got, "chat.v1")
+	}
+}
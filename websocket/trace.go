@@ -0,0 +1,127 @@
+package websocket
+
+import "fmt"
+
+// Direction tells whether a traced frame was received or sent.
+type Direction int
+
+const (
+	// DirectionRead is a frame received from the peer.
+	DirectionRead Direction = iota
+
+	// DirectionWrite is a frame sent to the peer.
+	DirectionWrite
+)
+
+// String returns "read" or "write".
+func (d Direction) String() string {
+	if d == DirectionWrite {
+		return "write"
+	}
+	return "read"
+}
+
+// FrameInfo describes a frame for tracing (RFC 6455 Section 5.2).
+//
+// It carries header fields only; payload bytes are never exposed, so
+// traces are safe to log.
+type FrameInfo struct {
+	Opcode byte // Frame opcode (0x1 text, 0x2 binary, 0x8 close, ...)
+	Fin    bool // Final fragment of a message
+	Length int  // Payload length in bytes
+	Masked bool // Payload masked (client-to-server frames)
+}
+
+// String returns a compact description, e.g. "text fin=true len=5 masked=false".
+func (fi FrameInfo) String() string {
+	return fmt.Sprintf("%s fin=%t len=%d masked=%t", opcodeName(fi.Opcode), fi.Fin, fi.Length, fi.Masked)
+}
+
+// opcodeName returns a readable name for opcode.
+func opcodeName(opcode byte) string {
+	switch opcode {
+	case opcodeContinuation:
+		return "continuation"
+	case opcodeText:
+		return "text"
+	case opcodeBinary:
+		return "binary"
+	case opcodeClose:
+		return "close"
+	case opcodePing:
+		return "ping"
+	case opcodePong:
+		return "pong"
+	default:
+		return fmt.Sprintf("opcode(0x%X)", opcode)
+	}
+}
+
+// Tracer is called for every frame a Conn reads or writes.
+type Tracer func(dir Direction, f FrameInfo)
+
+// SetTracer installs fn to be called for every frame read or written on
+// the connection, including control frames. Pass nil to stop tracing.
+//
+// Read frames are traced once parsed, before validation; written frames
+// once written to the buffer. fn runs on the reading or writing goroutine,
+// so it may be called concurrently and should return quickly.
+//
+// Example:
+//
+//	conn.SetTracer(func(dir websocket.Direction, f websocket.FrameInfo) {
+//	    log.Printf("ws %s: %s", dir, f)
+//	})
+//
+// Thread-safe: can be called while the connection is in use.
+func (c *Conn) SetTracer(fn Tracer) {
+	if fn == nil {
+		c.tracer.Store(nil)
+		return
+	}
+	c.tracer.Store(&fn)
+}
+
+// trace reports f to the tracer, if any.
+func (c *Conn) trace(dir Direction, f *frame) {
+	if fn := c.tracer.Load(); fn != nil {
+		(*fn)(dir, FrameInfo{
+			Opcode: f.opcode,
+			Fin:    f.fin,
+			Length: len(f.payload),
+			Masked: f.masked,
+		})
+	}
+}
+
+// recvFrame reads the next frame and traces it.
+func (c *Conn) recvFrame() (*frame, error) {
+	f, err := readFrame(c.reader)
+	if err != nil {
+		return nil, err
+	}
+	c.trace(DirectionRead, f)
+	return f, nil
+}
+
+// sendFrame writes f and traces it. With flush false the frame stays in
+// the write buffer (manual flush mode). Caller must hold writeMu.
+//
+// An I/O error fails the connection (see writeFailed); an invalid frame
+// is rejected before anything is written and leaves it usable.
+func (c *Conn) sendFrame(f *frame, flush bool) error {
+	write := writeFrame
+	if !flush {
+		write = bufferFrame
+	}
+	if err := write(c.writer, f); err != nil {
+		// bufio.Writer errors are sticky: an empty write reports one
+		// without writing anything
+		if _, ioErr := c.writer.Write(nil); ioErr != nil {
+			return c.writeFailed(err)
+		}
+		return err
+	}
+	c.trace(DirectionWrite, f)
+	return nil
+}
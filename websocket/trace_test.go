@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"slices"
+	"testing"
+)
+
+// tracedFrame is a frame recorded by a test tracer.
+type tracedFrame struct {
+	dir  Direction
+	info FrameInfo
+}
+
+// TestConn_SetTracer tests that frames are traced in both directions across
+// a ping/pong/text exchange.
+func TestConn_SetTracer(t *testing.T) {
+	frames := []*frame{
+		{fin: true, opcode: opcodePing, payload: []byte("p")},
+		{fin: true, opcode: opcodeText, payload: []byte("hello")},
+	}
+	conn := mockConn(t, frames, true)
+
+	var got []tracedFrame
+	conn.SetTracer(func(dir Direction, f FrameInfo) {
+		got = append(got, tracedFrame{dir, f})
+	})
+
+	if _, _, err := conn.Read(); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if err := conn.WriteText("hi"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	want := []tracedFrame{
+		{DirectionRead, FrameInfo{Opcode: opcodePing, Fin: true, Length: 1, Masked: true}},
+		{DirectionWrite, FrameInfo{Opcode: opcodePong, Fin: true, Length: 1}}, // Automatic reply
+		{DirectionRead, FrameInfo{Opcode: opcodeText, Fin: true, Length: 5, Masked: true}},
+		{DirectionWrite, FrameInfo{Opcode: opcodeText, Fin: true, Length: 2}},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("traced frames = %+v, want %+v", got, want)
+	}
+
+	// Removing the tracer stops tracing
+	conn.SetTracer(nil)
+	if err := conn.Ping(nil); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("traced %d frames after SetTracer(nil), want %d", len(got), len(want))
+	}
+}
+
+// TestFrameInfo_String tests the trace description of a frame.
+func TestFrameInfo_String(t *testing.T) {
+	tests := []struct {
+		info FrameInfo
+		want string
+	}{
+		{FrameInfo{Opcode: opcodeText, Fin: true, Length: 5}, "text fin=true len=5 masked=false"},
+		{FrameInfo{Opcode: opcodeClose, Fin: true, Length: 2, Masked: true}, "close fin=true len=2 masked=true"},
+		{FrameInfo{Opcode: 0x3}, "opcode(0x3) fin=false len=0 masked=false"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("FrameInfo.String(): expected %q, got %q", tt.want, got)
+		}
+	}
+}